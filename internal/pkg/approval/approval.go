@@ -0,0 +1,212 @@
+package approval
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"github.com/mattn/go-isatty"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Decision is the answer of the operator when reviewing the changes of a stack
+type Decision int
+
+const (
+	Apply Decision = iota
+	Skip
+	Abort
+)
+
+// ErrAborted is returned for every stack once the operator aborted the deploy
+var ErrAborted = errors.New("deploy aborted by the operator")
+
+// Approver decides whether protected changes may go ahead. Changes are approved up front
+// with Stack/Resource patterns, the remaining ones are asked for when a terminal is attached.
+type Approver struct {
+	approved []string
+	// granted holds the region/stack/resource changes the operator approved at a prompt
+	granted map[string]bool
+
+	prompt  bool
+	aborted bool
+	in      *bufio.Reader
+	out     io.Writer
+	mu      sync.Mutex
+}
+
+// New checks that every approval is a Stack/Resource pattern, * can be used in both parts.
+// The operator is only prompted when prompt is set and a terminal is attached.
+func New(approved []string, prompt bool) (*Approver, error) {
+	for _, a := range approved {
+		parts := strings.Split(a, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errors.Errorf("invalid approval %s, must be Stack/Resource", a)
+		}
+		if _, err := path.Match(a, ""); err != nil {
+			return nil, errors.Errorf("invalid approval %s: %v", a, err)
+		}
+	}
+
+	return &Approver{
+		approved: approved,
+		granted:  map[string]bool{},
+		prompt:   prompt && (isatty.IsTerminal(os.Stdin.Fd()) || isatty.IsCygwinTerminal(os.Stdin.Fd())),
+		in:       bufio.NewReader(os.Stdin),
+		out:      os.Stdout,
+	}, nil
+}
+
+// CanPrompt reports whether the operator can be asked
+func (a *Approver) CanPrompt() bool {
+	return a != nil && a.prompt
+}
+
+// WithoutPrompts returns an approver that never prompts and keeps the approvals given so
+// far, for deployments that run after every change was reviewed up front
+func (a *Approver) WithoutPrompts() *Approver {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	granted := make(map[string]bool, len(a.granted))
+	for k := range a.granted {
+		granted[k] = true
+	}
+	return &Approver{
+		approved: a.approved,
+		granted:  granted,
+		in:       a.in,
+		out:      a.out,
+	}
+}
+
+func grantKey(region string, stackName string, resource string) string {
+	return region + "/" + stackName + "/" + resource
+}
+
+// IsApproved reports whether a change to a resource of a stack was approved up front
+func (a *Approver) IsApproved(stackName string, resource string) bool {
+	if a == nil {
+		return false
+	}
+	for _, pattern := range a.approved {
+		if ok, _ := path.Match(pattern, stackName+"/"+resource); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Approve returns an error unless every change was approved up front or by the operator.
+// Prompts of stacks deployed in parallel are asked one at a time.
+func (a *Approver) Approve(region string, stackName string, changes []cloudformation.ChangeResource) error {
+	var pending []cloudformation.ChangeResource
+	for _, c := range changes {
+		if !a.IsApproved(stackName, c.Name) && !a.isGranted(region, stackName, c.Name) {
+			pending = append(pending, c)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if a != nil && a.prompt {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+
+		fmt.Fprintf(a.out, "    Stack %s in region %s has protected changes:\n", stackName, region)
+		for _, c := range pending {
+			fmt.Fprintf(a.out, "        %s\n", Describe(c))
+		}
+		ok, err := a.Confirm("    Proceed with these changes?")
+		if err != nil {
+			return err
+		}
+		if ok {
+			for _, c := range pending {
+				a.granted[grantKey(region, stackName, c.Name)] = true
+			}
+			return nil
+		}
+	}
+
+	lines := make([]string, 0, len(pending))
+	for _, c := range pending {
+		lines = append(lines, Describe(c))
+	}
+	return errors.Errorf("stack %s in region %s has %d protected change(s) that were not approved:\n    %s\nApprove them with --approve-replacements %s/<Resource>",
+		stackName, region, len(pending), strings.Join(lines, "\n    "), stackName)
+}
+
+func (a *Approver) isGranted(region string, stackName string, resource string) bool {
+	if a == nil {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.granted[grantKey(region, stackName, resource)]
+}
+
+// Decide shows the summary of the changes of a stack and asks whether to apply them, skip
+// the stack or abort the deploy. Once aborted every later call returns Abort.
+func (a *Approver) Decide(summary string, question string) (Decision, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.aborted {
+		return Abort, nil
+	}
+
+	fmt.Fprint(a.out, summary)
+	for {
+		fmt.Fprintf(a.out, "%s [apply/skip/abort]: ", question)
+		answer, err := a.in.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return Abort, err
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "apply", "y", "yes":
+			return Apply, nil
+		case "skip", "s":
+			return Skip, nil
+		case "abort", "q":
+			a.aborted = true
+			return Abort, nil
+		}
+		if err == io.EOF {
+			a.aborted = true
+			return Abort, nil
+		}
+	}
+}
+
+// Confirm asks a yes or no question, anything but y or yes is a no
+func (a *Approver) Confirm(question string) (bool, error) {
+	fmt.Fprintf(a.out, "%s [y/N]: ", question)
+	answer, err := a.in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// Describe is a one line summary of a resource change
+func Describe(c cloudformation.ChangeResource) string {
+	action := c.Action
+	if c.Action == "Modify" && (c.Replacement == "True" || c.Replacement == "Conditional") {
+		action = "Replace"
+		if c.Replacement == "Conditional" {
+			action = "Conditionally replace"
+		}
+	}
+	return fmt.Sprintf("%s %s (%s)", action, c.Name, c.Type)
+}
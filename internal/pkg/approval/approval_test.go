@@ -0,0 +1,140 @@
+package approval
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	testCases := map[string]struct {
+		approvals []string
+		err       string
+	}{
+		"stack and resource":  {approvals: []string{"Data-Tables/Orders", "Web/*"}},
+		"missing resource":    {approvals: []string{"Data-Tables"}, err: "invalid approval Data-Tables, must be Stack/Resource"},
+		"too many parts":      {approvals: []string{"eu-west-1/Data-Tables/Orders"}, err: "invalid approval eu-west-1/Data-Tables/Orders, must be Stack/Resource"},
+		"invalid glob":        {approvals: []string{"Data-Tables/[Orders"}, err: "invalid approval Data-Tables/[Orders: syntax error in pattern"},
+		"empty resource name": {approvals: []string{"Data-Tables/"}, err: "invalid approval Data-Tables/, must be Stack/Resource"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(tc.approvals, false)
+			if tc.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestApprove(t *testing.T) {
+	changes := []cloudformation.ChangeResource{
+		{Name: "Orders", Type: "AWS::DynamoDB::Table", Action: "Remove"},
+		{Name: "Database", Type: "AWS::RDS::DBInstance", Action: "Modify", Replacement: "True"},
+	}
+
+	testCases := map[string]struct {
+		approvals []string
+		prompt    bool
+		answer    string
+		err       string
+	}{
+		"approved up front": {
+			approvals: []string{"Data/Orders", "Data/Database"},
+		},
+		"approved with a glob": {
+			approvals: []string{"Data/*"},
+		},
+		"not approved": {
+			approvals: []string{"Data/Orders", "Web/Database"},
+			err:       "stack Data in region eu-west-1 has 1 protected change(s) that were not approved:\n    Replace Database (AWS::RDS::DBInstance)\nApprove them with --approve-replacements Data/<Resource>",
+		},
+		"approved by the operator": {
+			prompt: true,
+			answer: "yes\n",
+		},
+		"declined by the operator": {
+			prompt: true,
+			answer: "n\n",
+			err:    "stack Data in region eu-west-1 has 2 protected change(s) that were not approved:\n    Remove Orders (AWS::DynamoDB::Table)\n    Replace Database (AWS::RDS::DBInstance)\nApprove them with --approve-replacements Data/<Resource>",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			a, err := New(tc.approvals, false)
+			require.NoError(t, err)
+			out := &bytes.Buffer{}
+			a.prompt = tc.prompt
+			a.in = bufio.NewReader(strings.NewReader(tc.answer))
+			a.out = out
+
+			err = a.Approve("eu-west-1", "Data", changes)
+			if tc.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.err)
+			}
+			if tc.prompt {
+				require.Contains(t, out.String(), "Proceed with these changes? [y/N]: ")
+			}
+		})
+	}
+}
+
+func TestApproveWithoutApprover(t *testing.T) {
+	var a *Approver
+	require.NoError(t, a.Approve("eu-west-1", "Data", nil))
+	require.Error(t, a.Approve("eu-west-1", "Data", []cloudformation.ChangeResource{{Name: "Orders", Action: "Remove"}}))
+}
+
+func TestWithoutPrompts(t *testing.T) {
+	changes := []cloudformation.ChangeResource{{Name: "Orders", Type: "AWS::DynamoDB::Table", Action: "Remove"}}
+
+	a, err := New([]string{"Web/*"}, false)
+	require.NoError(t, err)
+	a.prompt = true
+	a.in = bufio.NewReader(strings.NewReader("yes\n"))
+	a.out = &bytes.Buffer{}
+	require.NoError(t, a.Approve("eu-west-1", "Data", changes))
+
+	// The workers get the approvals given up front and never prompt
+	workers := a.WithoutPrompts()
+	require.False(t, workers.CanPrompt())
+	require.NoError(t, workers.Approve("eu-west-1", "Data", changes))
+	require.NoError(t, workers.Approve("eu-west-1", "Web", changes))
+	require.Error(t, workers.Approve("us-east-1", "Data", changes))
+	require.Error(t, workers.Approve("eu-west-1", "Data", []cloudformation.ChangeResource{{Name: "Users", Action: "Remove"}}))
+}
+
+func TestDecide(t *testing.T) {
+	testCases := map[string]struct {
+		answers  string
+		expected []Decision
+	}{
+		"apply and skip":         {answers: "apply\ns\n", expected: []Decision{Apply, Skip, Abort}},
+		"asks again when unsure": {answers: "maybe\nyes\n", expected: []Decision{Apply, Abort}},
+		"abort is final":         {answers: "abort\napply\n", expected: []Decision{Abort, Abort}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			a, err := New(nil, false)
+			require.NoError(t, err)
+			a.in = bufio.NewReader(strings.NewReader(tc.answers))
+			a.out = &bytes.Buffer{}
+
+			for _, expected := range tc.expected {
+				d, err := a.Decide("", "Apply changes?")
+				require.NoError(t, err)
+				require.Equal(t, expected, d)
+			}
+		})
+	}
+}
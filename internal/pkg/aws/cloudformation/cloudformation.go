@@ -2,8 +2,10 @@ package cloudformation
 
 import (
 	"encoding/json"
+	"github.com/CleverTap/cfstack/internal/pkg/resolver"
+	"github.com/CleverTap/cfstack/internal/pkg/secrets"
 	"github.com/CleverTap/cfstack/internal/pkg/templates"
-	"github.com/Jeffail/gabs"
+	"github.com/CleverTap/cfstack/internal/pkg/values"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -13,7 +15,7 @@ import (
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
 	"os"
-	"strconv"
+	"sort"
 	"strings"
 	"time"
 )
@@ -23,9 +25,10 @@ const (
 )
 
 type CloudFormation struct {
-	client cloudformationiface.CloudFormationAPI
-	region string
-	values *gabs.Container
+	client  cloudformationiface.CloudFormationAPI
+	region  string
+	values  *values.Values
+	secrets *secrets.Store
 }
 
 type GetStackChangesOpts struct {
@@ -37,6 +40,8 @@ type GetStackChangesOpts struct {
 	ChangeSetName string
 	Type          string
 	RoleArn       string
+	// ResourcesToImport is only sent with an IMPORT change set
+	ResourcesToImport []ResourceToImport
 }
 
 type CreateStackOpts struct {
@@ -60,6 +65,7 @@ type Changes struct {
 	Resources         []ChangeResource
 	StackPolicyChange bool
 	ForceStackUpdate  bool
+	Drifted           bool
 }
 
 type ChangeResource struct {
@@ -69,11 +75,12 @@ type ChangeResource struct {
 	Replacement string
 }
 
-func New(sess *session.Session, v *gabs.Container) CloudFormation {
+func New(sess *session.Session, v *values.Values) CloudFormation {
 	return CloudFormation{
-		client: cloudformation.New(sess),
-		region: aws.StringValue(sess.Config.Region),
-		values: v,
+		client:  cloudformation.New(sess),
+		region:  aws.StringValue(sess.Config.Region),
+		values:  v,
+		secrets: secrets.ForSession(sess),
 	}
 }
 
@@ -83,10 +90,15 @@ func NewWithoutValues(sess *session.Session) CloudFormation {
 	}
 }
 
-func (cf CloudFormation) ValidateTemplate(templateUrl string) error {
-	_, err := cf.client.ValidateTemplate(&cloudformation.ValidateTemplateInput{
-		TemplateURL: aws.String(templateUrl),
-	})
+// ValidateTemplate validates the template at templateUrl, or templateBody when the url is empty
+func (cf CloudFormation) ValidateTemplate(templateUrl string, templateBody string) error {
+	input := &cloudformation.ValidateTemplateInput{}
+	if templateUrl == "" {
+		input.TemplateBody = aws.String(templateBody)
+	} else {
+		input.TemplateURL = aws.String(templateUrl)
+	}
+	_, err := cf.client.ValidateTemplate(input)
 
 	return err
 }
@@ -166,42 +178,62 @@ func (cf CloudFormation) StackExists(stackName string) (bool, error) {
 	return true, nil
 }
 
-func (cf *CloudFormation) ResolveParameterValue(stack string, parameter string) (string, error) {
-	if !cf.values.Exists(cf.region, stack, parameter) {
-		return "", errors.Errorf("Value for parameter %s not found for stack %s in region %s", parameter, stack, cf.region)
+// ResolveParameters substitutes the placeholders in the parameters of a stack
+func (cf CloudFormation) ResolveParameters(stackName string, params map[string]string) (map[string]string, error) {
+	var remote resolver.Remote
+	if cf.secrets != nil {
+		remote = cf.secrets
 	}
+	return resolver.New(cf.region, stackName, cf.values, remote).Resolve(params)
+}
 
-	value, err := strconv.Unquote(cf.values.Search(cf.region, stack, parameter).String())
+// resolveParameters returns the resolved parameters of a stack sorted by key
+func (cf CloudFormation) resolveParameters(stackName string, params map[string]string) ([]*cloudformation.Parameter, error) {
+	resolved, err := cf.ResolveParameters(stackName, params)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return value, nil
+	keys := make([]string, 0, len(resolved))
+	for k := range resolved {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parameters := make([]*cloudformation.Parameter, 0, len(keys))
+	for _, k := range keys {
+		parameters = append(parameters, &cloudformation.Parameter{
+			ParameterKey:   aws.String(k),
+			ParameterValue: aws.String(resolved[k]),
+		})
+	}
+	return parameters, nil
 }
 
 func (cf CloudFormation) GetStackChanges(opts *GetStackChangesOpts) (*Changes, error) {
+	changes, err := cf.CreateChangeSet(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = cf.client.DeleteChangeSet(&cloudformation.DeleteChangeSetInput{
+		ChangeSetName: aws.String(opts.ChangeSetName),
+		StackName:     aws.String(opts.StackName),
+	})
+
+	return changes, nil
+}
+
+// CreateChangeSet creates a change set and waits for its changes, the change set is kept
+// so that it can be executed or deleted afterwards
+func (cf CloudFormation) CreateChangeSet(opts *GetStackChangesOpts) (*Changes, error) {
 	var stackPolicyChange bool
 	var forceStackUpdate bool
 	var resources []ChangeResource
 
-	var parameters []*cloudformation.Parameter
-
-	for k, v := range opts.Parameters {
-		if len(v) >= 4 && v[0:2] == "{{" && v[len(v)-2:] == "}}" {
-			valueName := strings.TrimSpace(v[2 : len(v)-2])
-			if !cf.values.Exists(cf.region, opts.StackName, valueName) {
-				return nil, errors.Errorf("Value %s for parameter %s not found in values", valueName, k)
-			}
-			value, err := strconv.Unquote(cf.values.Search(cf.region, opts.StackName, valueName).String())
-			if err != nil {
-				return nil, err
-			}
-			v = value
-		}
-		parameters = append(parameters, &cloudformation.Parameter{
-			ParameterKey:   aws.String(k),
-			ParameterValue: aws.String(v),
-		})
+	parameters, err := cf.resolveParameters(opts.StackName, opts.Parameters)
+	if err != nil {
+		return nil, err
 	}
 
 	createChangeSetInput := &cloudformation.CreateChangeSetInput{
@@ -224,6 +256,10 @@ func (cf CloudFormation) GetStackChanges(opts *GetStackChangesOpts) (*Changes, e
 		createChangeSetInput.RoleARN = aws.String(opts.RoleArn)
 	}
 
+	if opts.Type == cloudformation.ChangeSetTypeImport {
+		createChangeSetInput.ResourcesToImport = resourcesToImport(opts.ResourcesToImport)
+	}
+
 	if opts.Type == "UPDATE" {
 
 		res, err := cf.client.GetStackPolicy(&cloudformation.GetStackPolicyInput{
@@ -248,7 +284,7 @@ func (cf CloudFormation) GetStackChanges(opts *GetStackChangesOpts) (*Changes, e
 		}
 	}
 
-	_, err := cf.client.CreateChangeSet(createChangeSetInput)
+	_, err = cf.client.CreateChangeSet(createChangeSetInput)
 
 	if err != nil {
 		return nil, err
@@ -260,11 +296,6 @@ func (cf CloudFormation) GetStackChanges(opts *GetStackChangesOpts) (*Changes, e
 		return nil, err
 	}
 
-	_, err = cf.client.DeleteChangeSet(&cloudformation.DeleteChangeSetInput{
-		ChangeSetName: aws.String(opts.ChangeSetName),
-		StackName:     aws.String(opts.StackName),
-	})
-
 	return &Changes{
 		Resources:         resources,
 		StackPolicyChange: stackPolicyChange,
@@ -344,24 +375,9 @@ func (cf CloudFormation) SetStackPolicy(stackName string, stackPolicy string) er
 }
 
 func (cf CloudFormation) UpdateExistingStack(opts *CreateStackOpts) error {
-	var parameters []*cloudformation.Parameter
-
-	for k, v := range opts.Parameters {
-		if len(v) >= 4 && v[0:2] == "{{" && v[len(v)-2:] == "}}" {
-			valueName := strings.TrimSpace(v[2 : len(v)-2])
-			if !cf.values.Exists(cf.region, opts.StackName, valueName) {
-				return errors.Errorf("Value %s for parameter %s not found in values", valueName, k)
-			}
-			value, err := strconv.Unquote(cf.values.Search(cf.region, opts.StackName, valueName).String())
-			if err != nil {
-				return err
-			}
-			v = value
-		}
-		parameters = append(parameters, &cloudformation.Parameter{
-			ParameterKey:   aws.String(k),
-			ParameterValue: aws.String(v),
-		})
+	parameters, err := cf.resolveParameters(opts.StackName, opts.Parameters)
+	if err != nil {
+		return err
 	}
 
 	capabilities := []*string{
@@ -420,7 +436,7 @@ func (cf CloudFormation) UpdateExistingStack(opts *CreateStackOpts) error {
 		}
 	}
 
-	err := cf.trackStackCreateUpdateStatus(opts.StackName)
+	err = cf.trackStackCreateUpdateStatus(opts.StackName)
 
 	if err != nil {
 		return err
@@ -429,6 +445,28 @@ func (cf CloudFormation) UpdateExistingStack(opts *CreateStackOpts) error {
 	return nil
 }
 
+// DeleteStackChanges returns the changes deleting a stack makes, every resource of the
+// stack is removed
+func (cf CloudFormation) DeleteStackChanges(stackName string) (*Changes, error) {
+	changes := &Changes{Resources: []ChangeResource{}}
+	err := cf.client.ListStackResourcesPages(&cloudformation.ListStackResourcesInput{
+		StackName: aws.String(stackName),
+	}, func(page *cloudformation.ListStackResourcesOutput, lastPage bool) bool {
+		for _, r := range page.StackResourceSummaries {
+			changes.Resources = append(changes.Resources, ChangeResource{
+				Name:   aws.StringValue(r.LogicalResourceId),
+				Type:   aws.StringValue(r.ResourceType),
+				Action: "Remove",
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
 func (cf CloudFormation) DeleteStack(opts *DeleteStackOpts) error {
 	deleteStackInput := &cloudformation.DeleteStackInput{
 		StackName: aws.String(opts.StackName),
@@ -479,24 +517,9 @@ func (cf CloudFormation) DeleteStack(opts *DeleteStackOpts) error {
 
 func (cf CloudFormation) CreateNewStack(opts *CreateStackOpts) error {
 
-	var parameters []*cloudformation.Parameter
-
-	for k, v := range opts.Parameters {
-		if len(v) >= 4 && v[0:2] == "{{" && v[len(v)-2:] == "}}" {
-			valueName := strings.TrimSpace(v[2 : len(v)-2])
-			if !cf.values.Exists(cf.region, opts.StackName, valueName) {
-				return errors.Errorf("Value %s for parameter %s not found in values", valueName, k)
-			}
-			value, err := strconv.Unquote(cf.values.Search(cf.region, opts.StackName, valueName).String())
-			if err != nil {
-				return err
-			}
-			v = value
-		}
-		parameters = append(parameters, &cloudformation.Parameter{
-			ParameterKey:   aws.String(k),
-			ParameterValue: aws.String(v),
-		})
+	parameters, err := cf.resolveParameters(opts.StackName, opts.Parameters)
+	if err != nil {
+		return err
 	}
 
 	capabilities := []*string{
@@ -559,7 +582,7 @@ func (cf CloudFormation) CreateNewStack(opts *CreateStackOpts) error {
 		}
 	}
 
-	err := cf.trackStackCreateUpdateStatus(opts.StackName)
+	err = cf.trackStackCreateUpdateStatus(opts.StackName)
 
 	if err != nil {
 		res, err1 := cf.client.DescribeStacks(&cloudformation.DescribeStacksInput{
@@ -673,6 +696,21 @@ func (cf CloudFormation) trackStackCreateUpdateStatus(stackName string) error {
 						return errors.Errorf("Failed to update stack %s", stackName)
 					case cloudformation.StackStatusUpdateRollbackFailed:
 						return errors.Errorf("Failed to rollback stack %s\nReason: %s", stackName, aws.StringValue(stack.StackStatusReason))
+					case cloudformation.StackStatusImportInProgress:
+						if currentStatus != cloudformation.StackStatusImportInProgress {
+							glog.Infof("Importing resources into stack %s\n", stackName)
+							currentStatus = cloudformation.StackStatusImportInProgress
+						}
+					case cloudformation.StackStatusImportRollbackInProgress:
+						if currentStatus != cloudformation.StackStatusImportRollbackInProgress {
+							color.New(color.FgRed).Fprintf(os.Stdout, "    Failed to import resources, rolling back\n")
+							color.New(color.FgRed).Fprintf(os.Stdout, "    Rollback reason: %s\n", aws.StringValue(stack.StackStatusReason))
+							currentStatus = cloudformation.StackStatusImportRollbackInProgress
+						}
+					case cloudformation.StackStatusImportRollbackComplete:
+						return errors.Errorf("Failed to import resources into stack %s", stackName)
+					case cloudformation.StackStatusImportRollbackFailed:
+						return errors.Errorf("Failed to rollback resource import of stack %s\nReason: %s", stackName, aws.StringValue(stack.StackStatusReason))
 					case cloudformation.StackStatusDeleteInProgress:
 						if currentStatus != cloudformation.StackStatusDeleteInProgress {
 							currentStatus = cloudformation.StackStatusDeleteInProgress
@@ -685,6 +723,9 @@ func (cf CloudFormation) trackStackCreateUpdateStatus(stackName string) error {
 					case cloudformation.StackStatusUpdateComplete:
 						glog.Infof("Completed updating stack %s", stackName)
 						completed = true
+					case cloudformation.StackStatusImportComplete:
+						glog.Infof("Completed importing resources into stack %s", stackName)
+						completed = true
 					case cloudformation.StackStatusDeleteComplete:
 						glog.Errorf("Completed deleting stack %s", stackName)
 						completed = true
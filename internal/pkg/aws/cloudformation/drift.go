@@ -0,0 +1,148 @@
+package cloudformation
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"time"
+)
+
+const (
+	DriftStatusDrifted    = cloudformation.StackDriftStatusDrifted
+	DriftStatusInSync     = cloudformation.StackDriftStatusInSync
+	DriftStatusNotChecked = cloudformation.StackDriftStatusNotChecked
+)
+
+type StackDrift struct {
+	StackName string
+	Status    string
+	Resources []ResourceDrift
+}
+
+type ResourceDrift struct {
+	Name        string
+	Type        string
+	PhysicalId  string
+	Status      string
+	Differences []PropertyDifference
+}
+
+type PropertyDifference struct {
+	Path     string
+	Type     string
+	Expected string
+	Actual   string
+}
+
+// DetectStackDrift runs a drift detection on the stack, waits for it to finish and
+// returns the resources that were modified or deleted outside of CloudFormation
+func (cf CloudFormation) DetectStackDrift(stackName string) (*StackDrift, error) {
+	res, err := cf.client.DetectStackDrift(&cloudformation.DetectStackDriftInput{
+		StackName: aws.String(stackName),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := cf.trackDriftDetectionStatus(stackName, aws.StringValue(res.StackDriftDetectionId))
+	if err != nil {
+		return nil, err
+	}
+
+	drift := &StackDrift{
+		StackName: stackName,
+		Status:    status,
+	}
+	if status != DriftStatusDrifted {
+		return drift, nil
+	}
+
+	input := &cloudformation.DescribeStackResourceDriftsInput{
+		StackName: aws.String(stackName),
+		StackResourceDriftStatusFilters: []*string{
+			aws.String(cloudformation.StackResourceDriftStatusModified),
+			aws.String(cloudformation.StackResourceDriftStatusDeleted),
+		},
+	}
+
+	err = cf.client.DescribeStackResourceDriftsPages(input, func(page *cloudformation.DescribeStackResourceDriftsOutput, lastPage bool) bool {
+		for _, d := range page.StackResourceDrifts {
+			r := ResourceDrift{
+				Name:       aws.StringValue(d.LogicalResourceId),
+				Type:       aws.StringValue(d.ResourceType),
+				PhysicalId: aws.StringValue(d.PhysicalResourceId),
+				Status:     aws.StringValue(d.StackResourceDriftStatus),
+			}
+			for _, p := range d.PropertyDifferences {
+				r.Differences = append(r.Differences, PropertyDifference{
+					Path:     aws.StringValue(p.PropertyPath),
+					Type:     aws.StringValue(p.DifferenceType),
+					Expected: aws.StringValue(p.ExpectedValue),
+					Actual:   aws.StringValue(p.ActualValue),
+				})
+			}
+			drift.Resources = append(drift.Resources, r)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return drift, nil
+}
+
+func (cf CloudFormation) trackDriftDetectionStatus(stackName string, detectionId string) (string, error) {
+	timeout := time.After(1 * time.Hour)
+	ticker := time.Tick(5 * time.Second)
+
+	for {
+		select {
+		case <-timeout:
+			return "", errors.Errorf("drift detection for stack %s didn't finish within an hour", stackName)
+		case <-ticker:
+			res, err := cf.client.DescribeStackDriftDetectionStatus(&cloudformation.DescribeStackDriftDetectionStatusInput{
+				StackDriftDetectionId: aws.String(detectionId),
+			})
+
+			if err != nil {
+				if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "Throttling" {
+					glog.Warningf("AWS rate limit error while detecting drift for stack %s, retrying..", stackName)
+					continue
+				}
+				return "", err
+			}
+
+			switch aws.StringValue(res.DetectionStatus) {
+			case cloudformation.StackDriftDetectionStatusDetectionComplete:
+				return aws.StringValue(res.StackDriftStatus), nil
+			case cloudformation.StackDriftDetectionStatusDetectionFailed:
+				// Detection fails when some resources can't be checked, the others still have a result
+				if aws.StringValue(res.StackDriftStatus) == DriftStatusDrifted {
+					return DriftStatusDrifted, nil
+				}
+				return "", errors.Errorf("drift detection for stack %s failed: %s", stackName, aws.StringValue(res.DetectionStatusReason))
+			}
+		}
+	}
+}
+
+// LastDriftStatus returns the result of the last drift detection of a stack without
+// starting a new one, NOT_CHECKED when drift was never detected
+func (cf CloudFormation) LastDriftStatus(stackName string) (string, time.Time, error) {
+	res, err := cf.client.DescribeStacks(&cloudformation.DescribeStacksInput{
+		StackName: aws.String(stackName),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	for _, s := range res.Stacks {
+		if aws.StringValue(s.StackName) == stackName && s.DriftInformation != nil {
+			return aws.StringValue(s.DriftInformation.StackDriftStatus), aws.TimeValue(s.DriftInformation.LastCheckTimestamp), nil
+		}
+	}
+	return DriftStatusNotChecked, time.Time{}, nil
+}
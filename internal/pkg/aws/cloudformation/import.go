@@ -0,0 +1,71 @@
+package cloudformation
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+const ChangeSetTypeImport = cloudformation.ChangeSetTypeImport
+
+// ResourceToImport is an existing resource brought under the management of a stack, the
+// identifier holds the properties CloudFormation uses to find it, e.g. BucketName
+type ResourceToImport struct {
+	LogicalResourceId  string            `json:"LogicalResourceId"`
+	ResourceType       string            `json:"ResourceType"`
+	ResourceIdentifier map[string]string `json:"ResourceIdentifier"`
+}
+
+// ExecuteChangeSet executes a change set created with CreateChangeSet and waits for the stack
+// to settle
+func (cf CloudFormation) ExecuteChangeSet(stackName string, changeSetName string) error {
+	_, err := cf.client.ExecuteChangeSet(&cloudformation.ExecuteChangeSetInput{
+		StackName:     aws.String(stackName),
+		ChangeSetName: aws.String(changeSetName),
+	})
+	if err != nil {
+		return err
+	}
+
+	return cf.trackStackCreateUpdateStatus(stackName)
+}
+
+func (cf CloudFormation) DeleteChangeSet(stackName string, changeSetName string) error {
+	_, err := cf.client.DeleteChangeSet(&cloudformation.DeleteChangeSetInput{
+		StackName:     aws.String(stackName),
+		ChangeSetName: aws.String(changeSetName),
+	})
+	return err
+}
+
+// StackResourceIds returns the logical IDs of the resources a stack already manages
+func (cf CloudFormation) StackResourceIds(stackName string) (map[string]bool, error) {
+	ids := map[string]bool{}
+	err := cf.client.ListStackResourcesPages(&cloudformation.ListStackResourcesInput{
+		StackName: aws.String(stackName),
+	}, func(page *cloudformation.ListStackResourcesOutput, lastPage bool) bool {
+		for _, r := range page.StackResourceSummaries {
+			ids[aws.StringValue(r.LogicalResourceId)] = true
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func resourcesToImport(resources []ResourceToImport) []*cloudformation.ResourceToImport {
+	out := make([]*cloudformation.ResourceToImport, 0, len(resources))
+	for _, r := range resources {
+		identifier := make(map[string]*string, len(r.ResourceIdentifier))
+		for k, v := range r.ResourceIdentifier {
+			identifier[k] = aws.String(v)
+		}
+		out = append(out, &cloudformation.ResourceToImport{
+			LogicalResourceId:  aws.String(r.LogicalResourceId),
+			ResourceType:       aws.String(r.ResourceType),
+			ResourceIdentifier: identifier,
+		})
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+package cloudformation
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/pkg/errors"
+	"time"
+)
+
+type StackInfo struct {
+	Status      string
+	LastUpdated time.Time
+	Outputs     map[string]string
+}
+
+// DescribeStack returns the status and outputs of a stack, nil when it doesn't exist
+func (cf CloudFormation) DescribeStack(stackName string) (*StackInfo, error) {
+	stackExists, err := cf.StackExists(stackName)
+	if err != nil || !stackExists {
+		return nil, err
+	}
+
+	res, err := cf.client.DescribeStacks(&cloudformation.DescribeStacksInput{
+		StackName: aws.String(stackName),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range res.Stacks {
+		if aws.StringValue(s.StackName) != stackName {
+			continue
+		}
+		info := &StackInfo{
+			Status:      aws.StringValue(s.StackStatus),
+			LastUpdated: aws.TimeValue(s.CreationTime),
+			Outputs:     map[string]string{},
+		}
+		if s.LastUpdatedTime != nil {
+			info.LastUpdated = aws.TimeValue(s.LastUpdatedTime)
+		}
+		for _, o := range s.Outputs {
+			info.Outputs[aws.StringValue(o.OutputKey)] = aws.StringValue(o.OutputValue)
+		}
+		return info, nil
+	}
+	return nil, errors.Errorf("stack %s not found", stackName)
+}
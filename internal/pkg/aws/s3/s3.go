@@ -1,10 +1,16 @@
 package s3
 
 import (
+	"bytes"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"os"
 )
 
@@ -22,25 +28,200 @@ type Opts struct {
 	Bucket   string
 	Filepath string
 	Key      string
+	// Body is uploaded instead of the file at Filepath when set
+	Body []byte
 }
 
 func (s *S3) UploadToS3(opts *Opts) error {
-	file, err := os.Open(opts.Filepath)
-	if err != nil {
-		return err
-	}
+	_, err := s.Upload(opts)
+	return err
+}
 
-	defer file.Close()
+// Upload uploads a file and returns the version of the object, empty when the bucket
+// isn't versioned
+func (s *S3) Upload(opts *Opts) (string, error) {
+	var body io.ReadSeeker = bytes.NewReader(opts.Body)
+	if opts.Body == nil {
+		file, err := os.Open(opts.Filepath)
+		if err != nil {
+			return "", err
+		}
 
-	_, err = s.client.PutObject(&s3.PutObjectInput{
+		defer file.Close()
+		body = file
+	}
+
+	res, err := s.client.PutObject(&s3.PutObjectInput{
 		Bucket: aws.String(opts.Bucket),
 		Key:    aws.String(opts.Key),
-		Body:   file,
+		Body:   body,
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return aws.StringValue(res.VersionId), nil
+}
+
+func (s *S3) PutObject(bucket string, key string, body []byte) error {
+	_, err := s.client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(body),
+	})
+	return err
+}
+
+// PutObjectIfAbsent writes an object only when the key doesn't exist yet, it returns false
+// when another object is already there
+func (s *S3) PutObjectIfAbsent(bucket string, key string, body []byte) (bool, error) {
+	req, _ := s.client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(body),
 	})
+	req.HTTPRequest.Header.Set("If-None-Match", "*")
 
+	err := req.Send()
 	if err != nil {
-		return err
+		if aerr, ok := err.(awserr.RequestFailure); ok && aerr.StatusCode() == http.StatusPreconditionFailed {
+			return false, nil
+		}
+		return false, err
 	}
+	return true, nil
+}
+
+// PutObjectIfMatch overwrites an object only when its ETag is still etag, it returns false
+// when the object was changed or removed in the meantime
+func (s *S3) PutObjectIfMatch(bucket string, key string, body []byte, etag string) (bool, error) {
+	req, _ := s.client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(body),
+	})
+	req.HTTPRequest.Header.Set("If-Match", etag)
+	return conditionMet(req.Send())
+}
+
+// DeleteObjectIfMatch removes an object only when its ETag is still etag, it returns false
+// when the object was changed or removed in the meantime
+func (s *S3) DeleteObjectIfMatch(bucket string, key string, etag string) (bool, error) {
+	req, _ := s.client.DeleteObjectRequest(&s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	req.HTTPRequest.Header.Set("If-Match", etag)
+	return conditionMet(req.Send())
+}
+
+// conditionMet turns the failures of an If-Match request on a changed or missing object
+// into false
+func conditionMet(err error) (bool, error) {
+	if err != nil {
+		if aerr, ok := err.(awserr.RequestFailure); ok &&
+			(aerr.StatusCode() == http.StatusPreconditionFailed || aerr.StatusCode() == http.StatusNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
 
-	return nil
+func (s *S3) DeleteObject(bucket string, key string) error {
+	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+func (s *S3) GetObject(bucket string, key string) ([]byte, error) {
+	res, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	return ioutil.ReadAll(res.Body)
+}
+
+// GetObjectWithETag returns the content of an object with its ETag, for conditional
+// writes with PutObjectIfMatch and DeleteObjectIfMatch
+func (s *S3) GetObjectWithETag(bucket string, key string) ([]byte, string, error) {
+	res, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	return b, aws.StringValue(res.ETag), err
+}
+
+// ListKeys returns the keys of every object under prefix
+func (s *S3) ListKeys(bucket string, prefix string) ([]string, error) {
+	var keys []string
+	err := s.client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, o := range page.Contents {
+			keys = append(keys, aws.StringValue(o.Key))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// EmptyBucket deletes every object of a bucket including old versions and delete markers,
+// it returns the number of deleted versions
+func (s *S3) EmptyBucket(bucket string) (int, error) {
+	deleted := 0
+	var deleteErr error
+
+	err := s.client.ListObjectVersionsPages(&s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucket),
+	}, func(page *s3.ListObjectVersionsOutput, lastPage bool) bool {
+		var objects []*s3.ObjectIdentifier
+		for _, v := range page.Versions {
+			objects = append(objects, &s3.ObjectIdentifier{Key: v.Key, VersionId: v.VersionId})
+		}
+		for _, m := range page.DeleteMarkers {
+			objects = append(objects, &s3.ObjectIdentifier{Key: m.Key, VersionId: m.VersionId})
+		}
+		if len(objects) == 0 {
+			return true
+		}
+
+		// A page holds at most 1000 versions, as many as a single DeleteObjects call takes
+		res, err := s.client.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &s3.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			deleteErr = err
+			return false
+		}
+		if len(res.Errors) > 0 {
+			deleteErr = errors.Errorf("%s could not be deleted from %s: %s", aws.StringValue(res.Errors[0].Key), bucket, aws.StringValue(res.Errors[0].Message))
+			return false
+		}
+		deleted += len(objects)
+		return true
+	})
+	if err != nil {
+		return deleted, err
+	}
+	return deleted, deleteErr
 }
@@ -0,0 +1,64 @@
+package s3
+
+import (
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/pkg/errors"
+	"net/url"
+	"regexp"
+)
+
+// URLStyle is how the bucket is addressed in the URL of an object
+type URLStyle string
+
+const (
+	// AutoStyle addresses buckets whose name is a valid host label in the host name and
+	// the others, like names with dots, in the path
+	AutoStyle          URLStyle = ""
+	VirtualHostedStyle URLStyle = "virtual"
+	PathStyle          URLStyle = "path"
+)
+
+var hostLabelBucket = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,61}[a-z0-9]$`)
+
+func (style URLStyle) Validate() error {
+	switch style {
+	case AutoStyle, VirtualHostedStyle, PathStyle:
+		return nil
+	}
+	return errors.Errorf("unknown S3 URL style %q, use %s or %s", style, VirtualHostedStyle, PathStyle)
+}
+
+// ObjectURL returns the https URL of an object. The host comes from the endpoint resolver
+// of the SDK, so the URL has the domain of the partition of the region, and regions the SDK
+// doesn't know yet get the s3.<region> host of their partition.
+func ObjectURL(region string, bucket string, key string, style URLStyle) (string, error) {
+	err := style.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	endpoint, err := endpoints.DefaultResolver().EndpointFor(endpoints.S3ServiceID, region, func(o *endpoints.Options) {
+		o.S3UsEast1RegionalEndpoint = endpoints.RegionalS3UsEast1Endpoint
+	})
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(endpoint.URL)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid S3 endpoint for region %s", region)
+	}
+
+	virtualHosted := hostLabelBucket.MatchString(bucket)
+	if style == VirtualHostedStyle && !virtualHosted {
+		return "", errors.Errorf("bucket %s can't be addressed in the host name, use the %s style", bucket, PathStyle)
+	}
+
+	if virtualHosted && style != PathStyle {
+		u.Host = bucket + "." + u.Host
+		u.Path = "/" + key
+	} else {
+		u.Path = "/" + bucket + "/" + key
+	}
+	return u.String(), nil
+}
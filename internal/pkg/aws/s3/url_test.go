@@ -0,0 +1,120 @@
+package s3
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestObjectURL(t *testing.T) {
+	testCases := map[string]struct {
+		region   string
+		bucket   string
+		key      string
+		style    URLStyle
+		expected string
+		err      string
+	}{
+		"aws us-east-1": {
+			region:   "us-east-1",
+			bucket:   "templates",
+			key:      "uid/vpc.json",
+			expected: "https://templates.s3.us-east-1.amazonaws.com/uid/vpc.json",
+		},
+		"aws": {
+			region:   "eu-west-1",
+			bucket:   "templates",
+			key:      "uid/vpc.json",
+			expected: "https://templates.s3.eu-west-1.amazonaws.com/uid/vpc.json",
+		},
+		"aws path style": {
+			region:   "eu-west-1",
+			bucket:   "templates",
+			key:      "uid/vpc.json",
+			style:    PathStyle,
+			expected: "https://s3.eu-west-1.amazonaws.com/templates/uid/vpc.json",
+		},
+		"aws region unknown to the sdk": {
+			region:   "eu-south-9",
+			bucket:   "templates",
+			key:      "uid/vpc.json",
+			expected: "https://templates.s3.eu-south-9.amazonaws.com/uid/vpc.json",
+		},
+		"china": {
+			region:   "cn-north-1",
+			bucket:   "templates",
+			key:      "uid/vpc.json",
+			expected: "https://templates.s3.cn-north-1.amazonaws.com.cn/uid/vpc.json",
+		},
+		"china path style": {
+			region:   "cn-northwest-1",
+			bucket:   "templates",
+			key:      "uid/vpc.json",
+			style:    PathStyle,
+			expected: "https://s3.cn-northwest-1.amazonaws.com.cn/templates/uid/vpc.json",
+		},
+		"govcloud": {
+			region:   "us-gov-west-1",
+			bucket:   "templates",
+			key:      "uid/vpc.json",
+			expected: "https://templates.s3.us-gov-west-1.amazonaws.com/uid/vpc.json",
+		},
+		"govcloud path style": {
+			region:   "us-gov-east-1",
+			bucket:   "templates",
+			key:      "uid/vpc.json",
+			style:    PathStyle,
+			expected: "https://s3.us-gov-east-1.amazonaws.com/templates/uid/vpc.json",
+		},
+		"iso": {
+			region:   "us-iso-east-1",
+			bucket:   "templates",
+			key:      "uid/vpc.json",
+			expected: "https://templates.s3.us-iso-east-1.c2s.ic.gov/uid/vpc.json",
+		},
+		"iso-b": {
+			region:   "us-isob-east-1",
+			bucket:   "templates",
+			key:      "uid/vpc.json",
+			style:    PathStyle,
+			expected: "https://s3.us-isob-east-1.sc2s.sgov.gov/templates/uid/vpc.json",
+		},
+		"bucket with dots": {
+			region:   "eu-west-1",
+			bucket:   "cfn.templates",
+			key:      "uid/vpc.json",
+			expected: "https://s3.eu-west-1.amazonaws.com/cfn.templates/uid/vpc.json",
+		},
+		"bucket with dots virtual hosted": {
+			region: "eu-west-1",
+			bucket: "cfn.templates",
+			key:    "uid/vpc.json",
+			style:  VirtualHostedStyle,
+			err:    "bucket cfn.templates can't be addressed in the host name, use the path style",
+		},
+		"escaped key": {
+			region:   "eu-west-1",
+			bucket:   "templates",
+			key:      "uid/my templates/vpc.json",
+			expected: "https://templates.s3.eu-west-1.amazonaws.com/uid/my%20templates/vpc.json",
+		},
+		"unknown style": {
+			region: "eu-west-1",
+			bucket: "templates",
+			key:    "uid/vpc.json",
+			style:  "dualstack",
+			err:    `unknown S3 URL style "dualstack", use virtual or path`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			u, err := ObjectURL(tc.region, tc.bucket, tc.key, tc.style)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, u)
+		})
+	}
+}
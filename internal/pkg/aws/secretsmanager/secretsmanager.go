@@ -0,0 +1,36 @@
+package secretsmanager
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/pkg/errors"
+)
+
+type SecretsManager struct {
+	client secretsmanageriface.SecretsManagerAPI
+}
+
+func New(sess *session.Session) SecretsManager {
+	return SecretsManager{
+		client: secretsmanager.New(sess),
+	}
+}
+
+// GetSecretString returns the current string value of a secret given its name or ARN
+func (s SecretsManager) GetSecretString(id string) (string, error) {
+	res, err := s.client.GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(id),
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	if res.SecretString == nil {
+		return "", errors.Errorf("secret %s has no string value", id)
+	}
+
+	return aws.StringValue(res.SecretString), nil
+}
@@ -0,0 +1,32 @@
+package ssm
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+type SSM struct {
+	client ssmiface.SSMAPI
+}
+
+func New(sess *session.Session) SSM {
+	return SSM{
+		client: ssm.New(sess),
+	}
+}
+
+// GetParameter returns the value of a parameter, SecureString parameters are decrypted
+func (s SSM) GetParameter(name string) (string, error) {
+	res, err := s.client.GetParameter(&ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return aws.StringValue(res.Parameter.Value), nil
+}
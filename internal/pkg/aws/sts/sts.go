@@ -0,0 +1,27 @@
+package sts
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+)
+
+type STS struct {
+	client stsiface.STSAPI
+}
+
+func New(sess *session.Session) STS {
+	return STS{
+		client: sts.New(sess),
+	}
+}
+
+// GetAccountId returns the account of the credentials in use
+func (s STS) GetAccountId() (string, error) {
+	res, err := s.client.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(res.Account), nil
+}
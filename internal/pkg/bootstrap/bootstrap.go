@@ -0,0 +1,128 @@
+package bootstrap
+
+import (
+	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"github.com/CleverTap/cfstack/internal/pkg/templates"
+	"github.com/pkg/errors"
+	"regexp"
+)
+
+// DefaultStackName is the stack cfstack init deploys when no other name is configured
+const DefaultStackName = "cfstack-Init"
+
+var (
+	stackNamePattern = regexp.MustCompile(`^[a-zA-Z][-a-zA-Z0-9]{0,127}$`)
+	qualifierPattern = regexp.MustCompile(`^[a-zA-Z0-9]+(-[a-zA-Z0-9]+)*$`)
+)
+
+// Config selects the bootstrap of a region: the stack created by cfstack init, named StackName
+// followed by an optional Qualifier, or buckets managed outside of cfstack
+type Config struct {
+	StackName string `json:"StackName,omitempty"`
+	Qualifier string `json:"Qualifier,omitempty"`
+
+	// TemplatesBucket and SourceBucket are used as they are, no bootstrap stack is needed
+	TemplatesBucket string `json:"TemplatesBucket,omitempty"`
+	SourceBucket    string `json:"SourceBucket,omitempty"`
+}
+
+// Name is the name of the bootstrap stack
+func (c Config) Name() string {
+	name := c.StackName
+	if name == "" {
+		name = DefaultStackName
+	}
+	if c.Qualifier != "" {
+		name += "-" + c.Qualifier
+	}
+	return name
+}
+
+// External reports whether the buckets are given directly instead of by a bootstrap stack
+func (c Config) External() bool {
+	return c.TemplatesBucket != "" || c.SourceBucket != ""
+}
+
+func (c Config) Validate() error {
+	if c.External() {
+		if c.TemplatesBucket == "" || c.SourceBucket == "" {
+			return errors.New("TemplatesBucket and SourceBucket must be given together")
+		}
+		if c.StackName != "" || c.Qualifier != "" {
+			return errors.New("a bootstrap with its own buckets can't set StackName or Qualifier")
+		}
+		return nil
+	}
+	if c.Qualifier != "" && !qualifierPattern.MatchString(c.Qualifier) {
+		return errors.Errorf("invalid bootstrap qualifier %q, use letters, digits and hyphens", c.Qualifier)
+	}
+	if !stackNamePattern.MatchString(c.Name()) {
+		return errors.Errorf("invalid bootstrap stack name %q", c.Name())
+	}
+	return nil
+}
+
+// Merge returns c with the fields set in o replacing its own. Buckets in o replace the
+// bootstrap stack of c and a stack name or qualifier in o replaces the buckets of c.
+func (c Config) Merge(o Config) Config {
+	if o.External() {
+		if !c.External() {
+			c = Config{}
+		}
+		if o.TemplatesBucket != "" {
+			c.TemplatesBucket = o.TemplatesBucket
+		}
+		if o.SourceBucket != "" {
+			c.SourceBucket = o.SourceBucket
+		}
+		return c
+	}
+
+	if o.StackName != "" || o.Qualifier != "" {
+		if c.External() {
+			c = Config{}
+		}
+		if o.StackName != "" {
+			c.StackName = o.StackName
+		}
+		if o.Qualifier != "" {
+			c.Qualifier = o.Qualifier
+		}
+	}
+	return c
+}
+
+// Resources are the parts of a bootstrap commands work with
+type Resources struct {
+	TemplatesBucket string
+	SourceBucket    string
+	// RoleArn is the service role exported by the bootstrap stack, empty for external buckets
+	RoleArn string
+}
+
+// Resolve looks up the buckets and the service role of the bootstrap stack, external
+// buckets are returned as they are
+func (c Config) Resolve(deployer cloudformation.CloudFormation) (*Resources, error) {
+	if c.External() {
+		return &Resources{TemplatesBucket: c.TemplatesBucket, SourceBucket: c.SourceBucket}, nil
+	}
+
+	info, err := deployer.DescribeStack(c.Name())
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return nil, errors.Errorf("bootstrap stack %s not found, run cfstack init", c.Name())
+	}
+
+	r := &Resources{RoleArn: info.Outputs[templates.ServiceRoleOutput]}
+	r.TemplatesBucket, err = deployer.GetStackResourcePhysicalId(c.Name(), "TemplatesS3Bucket")
+	if err != nil {
+		return nil, err
+	}
+	r.SourceBucket, err = deployer.GetStackResourcePhysicalId(c.Name(), "SourceS3Bucket")
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
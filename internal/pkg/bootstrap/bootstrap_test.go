@@ -0,0 +1,103 @@
+package bootstrap
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestName(t *testing.T) {
+	testCases := map[string]struct {
+		config   Config
+		expected string
+	}{
+		"default":        {config: Config{}, expected: "cfstack-Init"},
+		"qualifier":      {config: Config{Qualifier: "payments"}, expected: "cfstack-Init-payments"},
+		"stack name":     {config: Config{StackName: "payments-bootstrap"}, expected: "payments-bootstrap"},
+		"both qualified": {config: Config{StackName: "bootstrap", Qualifier: "dev"}, expected: "bootstrap-dev"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.config.Name())
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := map[string]struct {
+		config Config
+		err    string
+	}{
+		"default": {config: Config{}},
+		"external": {
+			config: Config{TemplatesBucket: "templates", SourceBucket: "source"},
+		},
+		"one bucket": {
+			config: Config{TemplatesBucket: "templates"},
+			err:    "TemplatesBucket and SourceBucket must be given together",
+		},
+		"buckets and stack": {
+			config: Config{TemplatesBucket: "templates", SourceBucket: "source", Qualifier: "dev"},
+			err:    "a bootstrap with its own buckets can't set StackName or Qualifier",
+		},
+		"invalid qualifier": {
+			config: Config{Qualifier: "dev_1"},
+			err:    `invalid bootstrap qualifier "dev_1", use letters, digits and hyphens`,
+		},
+		"invalid stack name": {
+			config: Config{StackName: "1bootstrap"},
+			err:    `invalid bootstrap stack name "1bootstrap"`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := tc.config.Validate()
+			if tc.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	testCases := map[string]struct {
+		config   Config
+		override Config
+		expected Config
+	}{
+		"empty override": {
+			config:   Config{StackName: "bootstrap", Qualifier: "dev"},
+			override: Config{},
+			expected: Config{StackName: "bootstrap", Qualifier: "dev"},
+		},
+		"qualifier only": {
+			config:   Config{StackName: "bootstrap", Qualifier: "dev"},
+			override: Config{Qualifier: "prod"},
+			expected: Config{StackName: "bootstrap", Qualifier: "prod"},
+		},
+		"buckets replace stack": {
+			config:   Config{StackName: "bootstrap", Qualifier: "dev"},
+			override: Config{TemplatesBucket: "templates", SourceBucket: "source"},
+			expected: Config{TemplatesBucket: "templates", SourceBucket: "source"},
+		},
+		"stack replaces buckets": {
+			config:   Config{TemplatesBucket: "templates", SourceBucket: "source"},
+			override: Config{Qualifier: "dev"},
+			expected: Config{Qualifier: "dev"},
+		},
+		"one bucket": {
+			config:   Config{TemplatesBucket: "templates", SourceBucket: "source"},
+			override: Config{SourceBucket: "other-source"},
+			expected: Config{TemplatesBucket: "templates", SourceBucket: "other-source"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.config.Merge(tc.override))
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package changes
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/git"
+	"github.com/CleverTap/cfstack/internal/pkg/manifest"
+	"github.com/CleverTap/cfstack/internal/pkg/stack"
+	"github.com/CleverTap/cfstack/internal/pkg/templates"
+	"github.com/CleverTap/cfstack/internal/pkg/util"
+	"github.com/CleverTap/cfstack/internal/pkg/values"
+	"github.com/Jeffail/gabs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+type Opts struct {
+	Ref           string
+	ManifestFile  string
+	TemplatesRoot string
+	ValuesFiles   []string
+	Repo          *git.Repo
+}
+
+// Decision tells whether a stack has to be processed and why
+type Decision struct {
+	Region    string
+	StackName string
+	Changed   bool
+	Reason    string
+}
+
+type detector struct {
+	opts        *Opts
+	changed     map[string]bool
+	refManifest *manifest.Manifest
+	values      map[string]valuesPair
+}
+
+type valuesPair struct {
+	current *gabs.Container
+	ref     *gabs.Container
+	reason  string
+}
+
+// Since drops every stack of m whose template, nested templates, code, manifest entry,
+// stack policy or values did not change since opts.Ref
+func Since(m *manifest.Manifest, opts *Opts) ([]Decision, error) {
+	files, err := opts.Repo.ChangedFiles(opts.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &detector{
+		opts:    opts,
+		changed: map[string]bool{},
+		values:  map[string]valuesPair{},
+	}
+
+	for _, f := range files {
+		d.changed[f] = true
+	}
+
+	err = d.loadRefManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.loadValues()
+	if err != nil {
+		return nil, err
+	}
+
+	var decisions []Decision
+	regions := make([]manifest.Region, 0)
+
+	for _, region := range m.Regions {
+		stacks := make([]stack.Stack, 0)
+		for _, s := range region.Stacks {
+			reason, changed := d.stackChanged(region.Name, s)
+			decisions = append(decisions, Decision{
+				Region:    region.Name,
+				StackName: s.StackName,
+				Changed:   changed,
+				Reason:    reason,
+			})
+			if changed {
+				stacks = append(stacks, s)
+			}
+		}
+		if len(stacks) > 0 {
+			region.Stacks = stacks
+			regions = append(regions, region)
+		}
+	}
+
+	m.Regions = regions
+	return decisions, nil
+}
+
+func (d *detector) loadRefManifest() error {
+	b, found, err := d.opts.Repo.Show(d.opts.Ref, d.opts.ManifestFile)
+	if err != nil || !found {
+		return err
+	}
+
+	d.refManifest = &manifest.Manifest{}
+	return d.refManifest.Load(b)
+}
+
+func (d *detector) loadValues() error {
+	for _, file := range d.opts.ValuesFiles {
+		pair := valuesPair{}
+
+		if !d.opts.Repo.Contains(file) {
+			pair.reason = fmt.Sprintf("values file %s is outside of the git repository", filepath.Base(file))
+			d.values[file] = pair
+			continue
+		}
+
+		if util.FileExists(file) {
+			current, err := util.ParseJsonFile(file)
+			if err != nil {
+				return err
+			}
+			pair.current = current
+		}
+
+		b, found, err := d.opts.Repo.Show(d.opts.Ref, file)
+		if err != nil {
+			return err
+		}
+		if found {
+			pair.ref, err = gabs.ParseJSON(b)
+			if err != nil {
+				return err
+			}
+		}
+
+		d.values[file] = pair
+	}
+	return nil
+}
+
+func (d *detector) stackChanged(region string, s stack.Stack) (string, bool) {
+	ref := d.opts.Ref
+
+	if d.refManifest == nil {
+		return fmt.Sprintf("manifest did not exist at %s", ref), true
+	}
+
+	refStack, found := d.refStack(region, s.StackName)
+	if !found {
+		return fmt.Sprintf("stack was added to the manifest since %s", ref), true
+	}
+
+	if !reflect.DeepEqual(s.StackPolicy, refStack.StackPolicy) {
+		return "stack policy changed", true
+	}
+
+	if !sameEntry(s, refStack) {
+		return "manifest entry changed", true
+	}
+
+	templatePath := util.ResolvePath(d.opts.TemplatesRoot, s.TemplatePath)
+	if reason, changed := d.templateChanged(templatePath, map[string]bool{}); changed {
+		return reason, true
+	}
+
+	if usesValues(s) {
+		for _, file := range d.opts.ValuesFiles {
+			pair := d.values[file]
+			if pair.reason != "" {
+				return pair.reason, true
+			}
+			if !reflect.DeepEqual(stackValues(pair.current, region, s.StackName), stackValues(pair.ref, region, s.StackName)) {
+				return fmt.Sprintf("values in %s changed", filepath.Base(file)), true
+			}
+		}
+	}
+
+	return fmt.Sprintf("no inputs changed since %s", ref), false
+}
+
+// templateChanged checks a template and everything it references locally
+func (d *detector) templateChanged(path string, seen map[string]bool) (string, bool) {
+	canonical, err := git.Canonical(path)
+	if err != nil {
+		return err.Error(), true
+	}
+	if seen[canonical] {
+		return "", false
+	}
+	seen[canonical] = true
+
+	name := filepath.Base(path)
+	if len(seen) > 1 {
+		name = "nested template " + name
+	} else {
+		name = "template " + name
+	}
+
+	if d.changed[canonical] {
+		return name + " changed", true
+	}
+
+	if !d.opts.Repo.Contains(canonical) {
+		return name + " is outside of the git repository", true
+	}
+
+	t, err := templates.LoadTemplate(canonical)
+	if err != nil {
+		// Templates that can't be parsed are left for the diff to report on
+		return fmt.Sprintf("%s could not be parsed", name), true
+	}
+
+	base := filepath.Dir(canonical)
+
+	for _, nested := range t.LocalPaths("AWS::CloudFormation::Stack", "TemplateURL") {
+		if reason, changed := d.templateChanged(util.ResolvePath(base, nested), seen); changed {
+			return reason, true
+		}
+	}
+	for _, nested := range t.LocalPaths("AWS::Serverless::Application", "Location") {
+		if reason, changed := d.templateChanged(util.ResolvePath(base, nested), seen); changed {
+			return reason, true
+		}
+	}
+
+	code := append(t.LocalPaths("AWS::Serverless::Function", "CodeUri"), t.LocalPaths("AWS::Serverless::LayerVersion", "ContentUri")...)
+	for _, uri := range code {
+		dir, err := git.Canonical(util.ResolvePath(base, uri))
+		if err != nil {
+			return err.Error(), true
+		}
+		for f := range d.changed {
+			if f == dir || strings.HasPrefix(f, dir+string(os.PathSeparator)) {
+				return fmt.Sprintf("code in %s changed", uri), true
+			}
+		}
+	}
+
+	return "", false
+}
+
+func (d *detector) refStack(region string, name string) (stack.Stack, bool) {
+	for _, r := range d.refManifest.Regions {
+		if r.Name != region {
+			continue
+		}
+		for _, s := range r.Stacks {
+			if s.StackName == name {
+				return s, true
+			}
+		}
+	}
+	return stack.Stack{}, false
+}
+
+func sameEntry(a stack.Stack, b stack.Stack) bool {
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(ja) == string(jb)
+}
+
+func usesValues(s stack.Stack) bool {
+	for _, v := range s.Parameters {
+		if strings.Contains(v, "{{") {
+			return true
+		}
+	}
+	return false
+}
+
+// stackValues resolves every value a stack sees from a single values file
+func stackValues(data *gabs.Container, region string, stackName string) map[string]interface{} {
+	return values.New(values.File{Data: data}).Resolved(region, stackName)
+}
@@ -0,0 +1,156 @@
+package changes
+
+import (
+	"github.com/CleverTap/cfstack/internal/pkg/bootstrap"
+	"github.com/CleverTap/cfstack/internal/pkg/git"
+	"github.com/CleverTap/cfstack/internal/pkg/manifest"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testManifest = `{
+  "Regions": [
+    {
+      "Name": "eu-west-1",
+      "Stacks": [
+        {"StackName": "Users", "Action": "CREATE", "StackPolicy": {}, "Parameters": {}, "TemplatePath": "users.json"},
+        {"StackName": "Bucket", "Action": "CREATE", "StackPolicy": {}, "Parameters": {"Days": "{{ Days }}"}, "TemplatePath": "bucket.yaml"},
+        {"StackName": "Api", "Action": "CREATE", "StackPolicy": {}, "Parameters": {}, "TemplatePath": "api.json"}
+      ]
+    }
+  ]
+}`
+
+func TestSince(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	testCases := map[string]struct {
+		manifest string
+		change   func(t *testing.T, dir string)
+		expected map[string]string
+	}{
+		"nothing changed": {
+			change: func(t *testing.T, dir string) {},
+			expected: map[string]string{
+				"Users":  "no inputs changed since HEAD",
+				"Bucket": "no inputs changed since HEAD",
+				"Api":    "no inputs changed since HEAD",
+			},
+		},
+		"manifest wide defaults": {
+			manifest: strings.Replace(testManifest, `"Regions"`, `"AllowReplacement": false, "ProtectedResourceTypes": ["AWS::RDS::DBInstance"], "Regions"`, 1),
+			change:   func(t *testing.T, dir string) {},
+			expected: map[string]string{
+				"Users":  "no inputs changed since HEAD",
+				"Bucket": "no inputs changed since HEAD",
+				"Api":    "no inputs changed since HEAD",
+			},
+		},
+		"manifest wide defaults changed": {
+			manifest: testManifest,
+			change: func(t *testing.T, dir string) {
+				write(t, dir, "manifest.json", strings.Replace(testManifest, `"Regions"`, `"AllowReplacement": false, "Regions"`, 1))
+			},
+			expected: map[string]string{
+				"Users":  "manifest entry changed",
+				"Bucket": "manifest entry changed",
+				"Api":    "manifest entry changed",
+			},
+		},
+		"template, values and function code changed": {
+			change: func(t *testing.T, dir string) {
+				write(t, dir, "users.json", `{"Resources": {"Group": {"Type": "AWS::IAM::Group"}}}`)
+				write(t, dir, "values.json", `{"eu-west-1": {"Bucket": {"Days": "30"}}}`)
+				write(t, dir, "src/handler.py", "def handler(event, context):\n    return 1\n")
+			},
+			expected: map[string]string{
+				"Users":  "template users.json changed",
+				"Bucket": "values in values.json changed",
+				"Api":    "code in src changed",
+			},
+		},
+		"nested template changed": {
+			change: func(t *testing.T, dir string) {
+				write(t, dir, "nested/child.json", `{"Resources": {"Topic": {"Type": "AWS::SNS::Topic"}}}`)
+			},
+			expected: map[string]string{
+				"Users":  "no inputs changed since HEAD",
+				"Bucket": "nested template child.json changed",
+				"Api":    "no inputs changed since HEAD",
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "cfstack-changes")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			if tc.manifest == "" {
+				tc.manifest = testManifest
+			}
+			write(t, dir, "manifest.json", tc.manifest)
+			write(t, dir, "values.json", `{"eu-west-1": {"Bucket": {"Days": "7"}}}`)
+			write(t, dir, "users.json", `{"Resources": {}}`)
+			write(t, dir, "bucket.yaml", "Resources:\n  Child:\n    Type: AWS::CloudFormation::Stack\n    Properties:\n      TemplateURL: nested/child.json\n")
+			write(t, dir, "nested/child.json", `{"Resources": {}}`)
+			write(t, dir, "api.json", `{"Transform": "AWS::Serverless-2016-10-31", "Resources": {"Fn": {"Type": "AWS::Serverless::Function", "Properties": {"CodeUri": "src"}}}}`)
+			write(t, dir, "src/handler.py", "def handler(event, context):\n    return 0\n")
+
+			gitRun(t, dir, "init", "-q")
+			gitRun(t, dir, "add", "-A")
+			gitRun(t, dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial")
+
+			tc.change(t, dir)
+
+			m := manifest.Manifest{}
+			require.NoError(t, m.Parse(filepath.Join(dir, "manifest.json")))
+			// As applied by the bootstrap flags before stacks are selected
+			flags := bootstrap.Config{StackName: "cfstack-Init-Data"}
+			m.Regions[0].Bootstrap = flags
+
+			repo, err := git.Open(dir)
+			require.NoError(t, err)
+
+			decisions, err := Since(&m, &Opts{
+				Ref:           "HEAD",
+				ManifestFile:  filepath.Join(dir, "manifest.json"),
+				TemplatesRoot: dir,
+				ValuesFiles:   []string{filepath.Join(dir, "values.json")},
+				Repo:          repo,
+			})
+			require.NoError(t, err)
+
+			reasons := map[string]string{}
+			for _, d := range decisions {
+				reasons[d.StackName] = d.Reason
+				require.Equal(t, d.Reason != "no inputs changed since HEAD", d.Changed)
+			}
+			require.Equal(t, tc.expected, reasons)
+			for _, region := range m.Regions {
+				require.Equal(t, flags, region.Bootstrap)
+			}
+		})
+	}
+}
+
+func write(t *testing.T, dir string, name string, content string) {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func gitRun(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
@@ -0,0 +1,124 @@
+package cfstack
+
+import (
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/session"
+	"github.com/CleverTap/cfstack/internal/pkg/bootstrap"
+	"github.com/CleverTap/cfstack/internal/pkg/manifest"
+	"github.com/CleverTap/cfstack/internal/pkg/templates"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// bootstrapVersion reads the version from the outputs of the bootstrap stack, stacks created
+// before the version was recorded are version 1
+func bootstrapVersion(info *cloudformation.StackInfo) int {
+	version, err := strconv.Atoi(info.Outputs[templates.BootstrapVersionOutput])
+	if err != nil {
+		return 1
+	}
+	return version
+}
+
+// addBootstrapStackFlags adds the flags naming the bootstrap stack
+func addBootstrapStackFlags(flags *pflag.FlagSet, c *bootstrap.Config) {
+	flags.StringVarP(&c.StackName, "bootstrap-stack", "", "", "Name of the bootstrap stack created by cfstack init (default "+bootstrap.DefaultStackName+")")
+	flags.StringVarP(&c.Qualifier, "qualifier", "", "", "Suffix of the bootstrap stack name, to keep separate bootstraps in one account")
+}
+
+// addBootstrapFlags adds the flags selecting the bootstrap, they override the Bootstrap of
+// the manifest
+func addBootstrapFlags(flags *pflag.FlagSet, c *bootstrap.Config) {
+	addBootstrapStackFlags(flags, c)
+	flags.StringVarP(&c.TemplatesBucket, "templates-bucket", "", "", "Bucket for templates, history and locks, used instead of a bootstrap stack together with --source-bucket")
+	flags.StringVarP(&c.SourceBucket, "source-bucket", "", "", "Bucket for packaged lambda code, used instead of a bootstrap stack together with --templates-bucket")
+}
+
+// applyBootstrapFlags merges the bootstrap flags into the bootstrap of every region
+func applyBootstrapFlags(m *manifest.Manifest, flags bootstrap.Config) error {
+	for i := range m.Regions {
+		region := &m.Regions[i]
+		region.Bootstrap = region.Bootstrap.Merge(flags)
+		err := region.Bootstrap.Validate()
+		if err != nil {
+			return errors.Wrapf(err, "invalid bootstrap for region %s", region.Name)
+		}
+	}
+	return nil
+}
+
+// regionBootstrap returns the bootstrap stack of a region, nil when the region is not
+// initialized
+func regionBootstrap(profile string, region string, c bootstrap.Config) (*cloudformation.StackInfo, error) {
+	sess, err := session.NewSession(&session.Opts{
+		Profile: profile,
+		Region:  region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cloudformation.NewWithoutValues(sess).DescribeStack(c.Name())
+}
+
+// checkBootstrap checks every region before any work starts and refuses to run when a
+// region isn't initialized or its bootstrap stack is older than the bootstrap template of
+// this cfstack. All regions are reported together, regions with their own buckets are not
+// checked.
+func checkBootstrap(profile string, regions []manifest.Region, manifestFile string) error {
+	infos := make([]*cloudformation.StackInfo, len(regions))
+	errs := make([]error, len(regions))
+
+	wg := sync.WaitGroup{}
+	for i, region := range regions {
+		if region.Bootstrap.External() {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, region manifest.Region) {
+			defer wg.Done()
+			infos[i], errs[i] = regionBootstrap(profile, region.Name, region.Bootstrap)
+		}(i, region)
+	}
+	wg.Wait()
+
+	var missing []string
+	var problems []string
+	for i, region := range regions {
+		name := region.Bootstrap.Name()
+		switch {
+		case region.Bootstrap.External():
+		case errs[i] != nil:
+			problems = append(problems, fmt.Sprintf("%s: %v", region.Name, errs[i]))
+		case infos[i] == nil:
+			missing = append(missing, fmt.Sprintf("%s (%s)", region.Name, name))
+		case bootstrapVersion(infos[i]) < templates.BootstrapVersion:
+			problems = append(problems, fmt.Sprintf("%s: %s is at bootstrap version %d, this cfstack needs %d. Run cfstack init upgrade --region %s%s",
+				region.Name, name, bootstrapVersion(infos[i]), templates.BootstrapVersion, region.Name, bootstrapArgs(region.Bootstrap)))
+		}
+	}
+
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("cfstack is not initialized in region(s) %s. Run cfstack init --manifest %s",
+			strings.Join(missing, ", "), manifestFile))
+	}
+	if len(problems) > 0 {
+		return errors.Errorf("bootstrap check failed:\n    %s", strings.Join(problems, "\n    "))
+	}
+	return nil
+}
+
+// bootstrapArgs are the flags that select a bootstrap stack other than the default one
+func bootstrapArgs(c bootstrap.Config) string {
+	args := ""
+	if c.StackName != "" {
+		args += " --bootstrap-stack " + c.StackName
+	}
+	if c.Qualifier != "" {
+		args += " --qualifier " + c.Qualifier
+	}
+	return args
+}
@@ -2,6 +2,7 @@ package cfstack
 
 import (
 	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/secrets"
 	"github.com/fatih/color"
 	"os"
 )
@@ -11,6 +12,8 @@ const (
 	check  = "✅"
 	rocket = "🚀"
 	knife  = "🔪"
+
+	magnifier = "🔍"
 )
 
 type commandInterface interface {
@@ -18,7 +21,7 @@ type commandInterface interface {
 }
 
 func ExitWithError(cmd string, err error) {
-	fmt.Fprintf(os.Stdout, color.RedString("\n❗️ %v\n", err))
+	fmt.Fprintf(os.Stdout, color.RedString("\n❗️ %v\n", secrets.RedactError(err)))
 	fmt.Fprintf(os.Stdout, color.RedString("❗️ %s command has failed\n", cmd))
 	os.Exit(1)
 }
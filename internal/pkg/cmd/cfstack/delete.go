@@ -4,24 +4,30 @@ import (
 	"fmt"
 	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
 	"github.com/CleverTap/cfstack/internal/pkg/aws/session"
+	"github.com/CleverTap/cfstack/internal/pkg/bootstrap"
+	"github.com/CleverTap/cfstack/internal/pkg/lock"
 	"github.com/CleverTap/cfstack/internal/pkg/manifest"
 	"github.com/fatih/color"
 	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 type DeleteOpts struct {
 	manifestFile string
 	profile      string
 	role         string
+	bootstrap    bootstrap.Config
+	lockTTL      time.Duration
 
 	uid           string
 	templatesRoot string
 
 	deleteStackOpts *DeleteStackOpts
 
+	selector manifest.Selector
 	manifest manifest.Manifest
 }
 
@@ -37,6 +43,16 @@ func (opts *DeleteOpts) preRun() error {
 		return err
 	}
 
+	err = applyBootstrapFlags(&opts.manifest, opts.bootstrap)
+	if err != nil {
+		return err
+	}
+
+	err = selectStacks(&opts.manifest, &opts.selector)
+	if err != nil {
+		return err
+	}
+
 	uid, err := uuid.NewUUID()
 	if err != nil {
 		return err
@@ -47,6 +63,12 @@ func (opts *DeleteOpts) preRun() error {
 }
 
 func (opts *DeleteOpts) Run() error {
+	release, err := acquireLocks(opts.profile, opts.manifest.Regions, opts.uid, "delete", opts.lockTTL)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	for _, region := range opts.manifest.Regions {
 		stacks := region.Stacks
 		sess, err := session.NewSession(&session.Opts{
@@ -60,13 +82,18 @@ func (opts *DeleteOpts) Run() error {
 
 		deployer := cloudformation.NewWithoutValues(sess)
 
+		resources, err := region.Bootstrap.Resolve(deployer)
+		if err != nil {
+			return err
+		}
+
 		for _, s := range stacks {
 			fmt.Printf("==> %s  Deleting stack %s in region %s\n", knife, s.StackName, region.Name)
 			s.SetRegion(region.Name)
 			s.TemplateRootPath = opts.templatesRoot
 
 			s.Deployer = deployer
-			s.RoleArn = opts.role
+			s.SetRole(opts.role, resources.RoleArn)
 
 			err := s.Delete()
 
@@ -98,7 +125,10 @@ func NewDeleteCmd() *cobra.Command {
 
 	cmd.PersistentFlags().StringVarP(&opts.manifestFile, "manifest", "m", "", "Set your manifest file")
 	cmd.PersistentFlags().StringVarP(&opts.profile, "profile", "", "default", "Profile to use from AWS credentials")
-	cmd.PersistentFlags().StringVarP(&opts.role, "role", "", "", "Cloudformation service role to be used for stack operations")
+	cmd.PersistentFlags().StringVarP(&opts.role, "role", "", "", "Cloudformation service role to be used for stacks without a RoleArn, defaults to the role created by cfstack init")
+	addBootstrapFlags(cmd.PersistentFlags(), &opts.bootstrap)
+	cmd.PersistentFlags().DurationVarP(&opts.lockTTL, "lock-ttl", "", lock.DefaultTTL, "Time after which the lock of a region taken by this run is considered stale")
+	addSelectorFlags(cmd.Flags(), &opts.selector)
 	cmd.AddCommand(opts.NewDeleteStackCmd())
 	return cmd
 }
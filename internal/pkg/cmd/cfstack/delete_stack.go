@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
 	"github.com/CleverTap/cfstack/internal/pkg/aws/session"
+	"github.com/CleverTap/cfstack/internal/pkg/manifest"
 	"github.com/CleverTap/cfstack/internal/pkg/stack"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -22,6 +23,12 @@ func (opts *DeleteOpts) RunStackDelete() error {
 		if region.Name == opts.deleteStackOpts.region {
 			for _, s := range region.Stacks {
 				if s.StackName == opts.deleteStackOpts.name {
+					release, err := acquireLocks(opts.profile, []manifest.Region{region}, opts.uid, "delete stack", opts.lockTTL)
+					if err != nil {
+						return err
+					}
+					defer release()
+
 					fmt.Printf("==> %s  Deleting stack %s in region %s\n", knife, s.StackName, region.Name)
 					sess, err := session.NewSession(&session.Opts{
 						Profile: opts.profile,
@@ -34,11 +41,16 @@ func (opts *DeleteOpts) RunStackDelete() error {
 
 					deployer := cloudformation.NewWithoutValues(sess)
 
+					resources, err := region.Bootstrap.Resolve(deployer)
+					if err != nil {
+						return err
+					}
+
 					s.SetRegion(region.Name)
 					s.TemplateRootPath = opts.templatesRoot
 
 					s.Deployer = deployer
-					s.RoleArn = opts.role
+					s.SetRole(opts.role, resources.RoleArn)
 
 					return s.Delete()
 				}
@@ -2,13 +2,19 @@ package cfstack
 
 import (
 	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/approval"
 	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
 	"github.com/CleverTap/cfstack/internal/pkg/aws/s3"
 	"github.com/CleverTap/cfstack/internal/pkg/aws/session"
+	"github.com/CleverTap/cfstack/internal/pkg/bootstrap"
+	"github.com/CleverTap/cfstack/internal/pkg/lock"
 	"github.com/CleverTap/cfstack/internal/pkg/manifest"
-	"github.com/CleverTap/cfstack/internal/pkg/util"
+	"github.com/CleverTap/cfstack/internal/pkg/policy"
+	"github.com/CleverTap/cfstack/internal/pkg/secrets"
+	"github.com/CleverTap/cfstack/internal/pkg/stack"
+	"github.com/CleverTap/cfstack/internal/pkg/validate"
+	"github.com/CleverTap/cfstack/internal/pkg/values"
 	"github.com/CleverTap/cfstack/internal/pkg/worker"
-	"github.com/Jeffail/gabs"
 	"github.com/fatih/color"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
@@ -17,13 +23,22 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 )
 
 type DeployOpts struct {
 	manifestFile string
-	valuesFile   string
+	valuesFiles  []string
+	params       []string
+	policiesDir  string
+	approvals    []string
+	interactive  bool
+	yes          bool
 	profile      string
 	role         string
+	bootstrap    bootstrap.Config
+	since        string
+	lockTTL      time.Duration
 
 	workers int
 
@@ -32,8 +47,12 @@ type DeployOpts struct {
 	uid           string
 	templatesRoot string
 
+	selector manifest.Selector
 	manifest manifest.Manifest
-	values   *gabs.Container
+	values   *values.Values
+	policies *policy.Set
+	approver *approval.Approver
+	reviewer stack.Reviewer
 }
 
 func (opts *DeployOpts) preRun() error {
@@ -48,20 +67,67 @@ func (opts *DeployOpts) preRun() error {
 		return err
 	}
 
-	if len(opts.valuesFile) > 0 {
-		if !filepath.IsAbs(opts.valuesFile) {
-			opts.valuesFile = filepath.Join(templatesRoot, opts.valuesFile)
-		}
-		opts.values, err = util.ParseJsonFile(opts.valuesFile)
-		if err != nil {
-			return err
+	err = applyBootstrapFlags(&opts.manifest, opts.bootstrap)
+	if err != nil {
+		return err
+	}
+
+	opts.values, err = loadValues(templatesRoot, opts.valuesFiles)
+	if err != nil {
+		return err
+	}
+
+	err = setParamOverrides(&opts.manifest, opts.values, opts.params)
+	if err != nil {
+		return err
+	}
+
+	err = selectStacks(&opts.manifest, &opts.selector)
+	if err != nil {
+		return err
+	}
+
+	err = selectChangedSince(&opts.manifest, opts.since, opts.manifestFile, templatesRoot, opts.values.Files())
+	if err != nil {
+		return err
+	}
+
+	problems := validate.Parameters(&opts.manifest, opts.values, templatesRoot)
+	problems = append(problems, validate.Imports(&opts.manifest, templatesRoot)...)
+	problems = append(problems, validate.Limits(&opts.manifest, templatesRoot)...)
+	err = validate.Check(problems)
+	if err != nil {
+		return err
+	}
+
+	opts.policies, err = loadPolicies(templatesRoot, opts.policiesDir, &opts.manifest)
+	if err != nil {
+		return err
+	}
+
+	err = checkBootstrap(opts.profile, opts.manifest.Regions, opts.manifestFile)
+	if err != nil {
+		return err
+	}
+
+	opts.approver, err = approval.New(opts.approvals, !opts.yes)
+	if err != nil {
+		return err
+	}
+
+	if opts.interactive && !opts.yes {
+		if !opts.approver.CanPrompt() {
+			return errors.New("--interactive needs a terminal, use --yes to deploy without prompts")
 		}
+		opts.reviewer = changeReviewer{approver: opts.approver}
 	}
 
 	if !opts.manifest.ParallelDeployment {
 		opts.workers = 1
 	}
 
+	setGitSha(&opts.manifest, templatesRoot)
+
 	uid, err := uuid.NewUUID()
 	if err != nil {
 		return err
@@ -72,6 +138,23 @@ func (opts *DeployOpts) preRun() error {
 }
 
 func (opts *DeployOpts) Run() error {
+	release, err := acquireLocks(opts.profile, opts.manifest.Regions, opts.uid, "deploy", opts.lockTTL)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Parallel deployments can't stop for prompts, every change is reviewed before they start
+	reviewer := opts.reviewer
+	approver := opts.approver
+	if reviewer != nil && opts.manifest.ParallelDeployment {
+		err := opts.reviewUpFront()
+		if err != nil {
+			return err
+		}
+		reviewer = nil
+		approver = opts.approver.WithoutPrompts()
+	}
 
 	regionJobs := make(chan worker.RegionDeployWorkerJob, len(opts.manifest.Regions))
 	results := make(chan *worker.RegionDeployWorkerResult, len(opts.manifest.Regions))
@@ -99,7 +182,11 @@ func (opts *DeployOpts) Run() error {
 			TemplatesRoot:      opts.templatesRoot,
 			Values:             opts.values,
 			Role:               opts.role,
+			Bootstrap:          region.Bootstrap,
 			ParallelMode:       opts.manifest.ParallelDeployment,
+			Policies:           opts.policies,
+			Approver:           approver,
+			Reviewer:           reviewer,
 		}
 		wg.Add(1)
 	}
@@ -112,7 +199,7 @@ func (opts *DeployOpts) Run() error {
 		select {
 		case result := <-results:
 			if result.Err != nil {
-				color.New(color.FgRed).Fprintf(os.Stdout, "    %v\n", result.Err)
+				color.New(color.FgRed).Fprintf(os.Stdout, "    %v\n", secrets.RedactError(result.Err))
 				errRegions = append(errRegions, result.Region)
 			}
 		}
@@ -139,8 +226,12 @@ func (opts *DeployOpts) RunSerial() error {
 		uploader := s3.New(sess)
 		deployer := cloudformation.New(sess, opts.values)
 
-		bucket, err := deployer.GetStackResourcePhysicalId("cfstack-Init", "TemplatesS3Bucket")
+		resources, err := region.Bootstrap.Resolve(deployer)
+		if err != nil {
+			return err
+		}
 
+		account, err := opts.policies.Account(sess)
 		if err != nil {
 			return err
 		}
@@ -149,19 +240,24 @@ func (opts *DeployOpts) RunSerial() error {
 			fmt.Printf("==> %s  Deploying stack %s in region %s\n", rocket, s.StackName, region.Name)
 			s.SetRegion(region.Name)
 			s.SetUuid(opts.uid)
-			s.SetBucket(bucket)
+			s.SetBucket(resources.TemplatesBucket)
+			s.SourceBucket = resources.SourceBucket
 			s.SetDeploymentOrder(i)
 			s.TemplateRootPath = opts.templatesRoot
 
 			s.Uploader = uploader
 			s.Deployer = deployer
 
-			s.RoleArn = opts.role
+			s.SetRole(opts.role, resources.RoleArn)
+			s.Policies = opts.policies
+			s.AccountId = account
+			s.Approver = opts.approver
+			s.Reviewer = opts.reviewer
 
 			err = s.Deploy()
 
 			if err != nil {
-				fmt.Fprintf(os.Stdout, color.RedString("    %v\n", err))
+				fmt.Fprintf(os.Stdout, color.RedString("    %v\n", secrets.RedactError(err)))
 				return fmt.Errorf("%s stack deployment has failed", s.StackName)
 			}
 		}
@@ -188,10 +284,19 @@ func NewDeployCmd() *cobra.Command {
 	}
 
 	cmd.PersistentFlags().StringVarP(&opts.manifestFile, "manifest", "m", "", "Set your manifest file")
-	cmd.PersistentFlags().StringVarP(&opts.valuesFile, "values", "", "values.json", "Set your values file")
+	cmd.PersistentFlags().StringArrayVarP(&opts.valuesFiles, "values", "", []string{defaultValuesFile}, "Set your values file, can be repeated. Later files override earlier ones")
+	cmd.PersistentFlags().StringArrayVarP(&opts.params, "param", "", nil, "Override a stack parameter, Stack.Param=value or region/Stack.Param=value. Can be repeated")
+	cmd.PersistentFlags().StringVarP(&opts.policiesDir, "policies", "", defaultPoliciesDir, "Directory of the policy rules, relative to the manifest")
+	cmd.PersistentFlags().StringArrayVarP(&opts.approvals, "approve-replacements", "", nil, "Approve the removal or replacement of a protected resource, Stack/Resource. Can be repeated")
+	cmd.PersistentFlags().BoolVarP(&opts.interactive, "interactive", "i", false, "Show the changes of every stack and ask whether to apply, skip or abort")
+	cmd.PersistentFlags().BoolVarP(&opts.yes, "yes", "y", false, "Don't prompt, protected changes still need --approve-replacements")
 	cmd.PersistentFlags().StringVarP(&opts.profile, "profile", "", "default", "Profile to use from AWS credentials")
-	cmd.PersistentFlags().StringVarP(&opts.role, "role", "", "", "Cloudformation service role to be used for stack operations")
+	cmd.PersistentFlags().StringVarP(&opts.role, "role", "", "", "Cloudformation service role to be used for stacks without a RoleArn, defaults to the role created by cfstack init")
+	addBootstrapFlags(cmd.PersistentFlags(), &opts.bootstrap)
+	cmd.PersistentFlags().DurationVarP(&opts.lockTTL, "lock-ttl", "", lock.DefaultTTL, "Time after which the lock of a region taken by this run is considered stale")
 	cmd.Flags().IntVarP(&opts.workers, "workers", "w", worker.MaxWorker, "No of concurrent workers for deploying stacks")
+	addSelectorFlags(cmd.Flags(), &opts.selector)
+	cmd.Flags().StringVarP(&opts.since, "since", "", "", "Only deploy stacks whose inputs changed since this git reference")
 	cmd.AddCommand(opts.NewDeployStackCmd())
 
 	return cmd
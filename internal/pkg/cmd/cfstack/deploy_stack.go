@@ -6,6 +6,8 @@ import (
 	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
 	"github.com/CleverTap/cfstack/internal/pkg/aws/s3"
 	"github.com/CleverTap/cfstack/internal/pkg/aws/session"
+	"github.com/CleverTap/cfstack/internal/pkg/manifest"
+	"github.com/CleverTap/cfstack/internal/pkg/secrets"
 	"github.com/CleverTap/cfstack/internal/pkg/stack"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/fatih/color"
@@ -28,6 +30,12 @@ func (opts *DeployOpts) RunStackDeploy() error {
 		if region.Name == opts.deployStackOpts.region {
 			for _, s := range region.Stacks {
 				if s.StackName == opts.deployStackOpts.name {
+					release, err := acquireLocks(opts.profile, []manifest.Region{region}, opts.uid, "deploy stack", opts.lockTTL)
+					if err != nil {
+						return err
+					}
+					defer release()
+
 					fmt.Printf("==> %s  Deploying stack %s in region %s\n", rocket, s.StackName, region.Name)
 					sess, err := session.NewSession(&session.Opts{
 						Profile: opts.profile,
@@ -41,21 +49,30 @@ func (opts *DeployOpts) RunStackDeploy() error {
 					uploader := s3.New(sess)
 					deployer := cloudformation.New(sess, opts.values)
 
-					bucket, err := deployer.GetStackResourcePhysicalId("cfstack-Init", "TemplatesS3Bucket")
+					resources, err := region.Bootstrap.Resolve(deployer)
+					if err != nil {
+						return err
+					}
 
+					account, err := opts.policies.Account(sess)
 					if err != nil {
 						return err
 					}
 
 					s.SetRegion(opts.deployStackOpts.region)
 					s.SetUuid(opts.uid)
-					s.SetBucket(bucket)
+					s.SetBucket(resources.TemplatesBucket)
+					s.SourceBucket = resources.SourceBucket
 					s.TemplateRootPath = opts.templatesRoot
 
 					s.Uploader = uploader
 					s.Deployer = deployer
 
-					s.RoleArn = opts.role
+					s.SetRole(opts.role, resources.RoleArn)
+					s.Policies = opts.policies
+					s.AccountId = account
+					s.Approver = opts.approver
+					s.Reviewer = opts.reviewer
 
 					timeout := time.After(24 * time.Hour)
 					ticker := time.Tick(10 * time.Second)
@@ -94,7 +111,7 @@ func (opts *DeployOpts) RunStackDeploy() error {
 						}
 					}
 					if err != nil {
-						fmt.Fprintf(os.Stdout, color.RedString("    %v\n", err))
+						fmt.Fprintf(os.Stdout, color.RedString("    %v\n", secrets.RedactError(err)))
 						return fmt.Errorf("%s stack deployment has failed", s.StackName)
 					}
 					return nil
@@ -113,6 +130,11 @@ func (opts *DeployOpts) NewDeployStackCmd() *cobra.Command {
 		Short:   "Deploy a single stack",
 		Long:    `Deploy specific stack using this command by passing the stack name along with manifest`,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Only the stack that is deployed is validated and only its region checked
+			opts.selector = manifest.Selector{
+				Regions: []string{opts.deployStackOpts.region},
+				Stacks:  []string{opts.deployStackOpts.name},
+			}
 			return opts.preRun()
 		},
 		Run: func(cmd *cobra.Command, args []string) {
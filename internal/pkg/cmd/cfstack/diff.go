@@ -1,32 +1,50 @@
 package cfstack
 
 import (
-	"encoding/json"
 	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/bootstrap"
 	"github.com/CleverTap/cfstack/internal/pkg/manifest"
+	"github.com/CleverTap/cfstack/internal/pkg/policy"
+	"github.com/CleverTap/cfstack/internal/pkg/report"
+	"github.com/CleverTap/cfstack/internal/pkg/secrets"
 	"github.com/CleverTap/cfstack/internal/pkg/util"
+	"github.com/CleverTap/cfstack/internal/pkg/validate"
+	"github.com/CleverTap/cfstack/internal/pkg/values"
 	"github.com/CleverTap/cfstack/internal/pkg/worker"
-	"github.com/Jeffail/gabs"
 	"github.com/fatih/color"
 	"github.com/google/uuid"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 )
 
 type DiffOpts struct {
 	manifestFile string
-	valuesFile   string
+	valuesFiles  []string
+	params       []string
+	policiesDir  string
 	workers      int
 	profile      string
 	role         string
+	bootstrap    bootstrap.Config
+	format       string
+	output       string
+
+	since            string
+	detailedExitCode bool
+	exitCode         int
 
 	uid           string
 	templatesRoot string
 
+	selector manifest.Selector
 	manifest manifest.Manifest
-	values   *gabs.Container
+	values   *values.Values
+	policies *policy.Set
 }
 
 func (opts *DiffOpts) Run() error {
@@ -52,6 +70,8 @@ func (opts *DiffOpts) Run() error {
 			TemplatesRoot:   opts.templatesRoot,
 			Values:          opts.values,
 			Role:            opts.role,
+			Bootstrap:       region.Bootstrap,
+			Policies:        opts.policies,
 		}
 		wg.Add(1)
 	}
@@ -59,12 +79,14 @@ func (opts *DiffOpts) Run() error {
 	wg.Wait()
 
 	out := make([]manifest.Region, 0)
+	regionResults := make([]*worker.RegionDiffWorkerResult, 0, workers)
 
 	var errResult error
 
 	for i := 1; i <= workers; i++ {
 		select {
 		case result := <-results:
+			regionResults = append(regionResults, result)
 			if len(result.Stacks) > 0 {
 				region := manifest.Region{
 					Name:   result.Region,
@@ -73,26 +95,64 @@ func (opts *DiffOpts) Run() error {
 				out = append(out, region)
 			}
 			if result.Err != nil {
-				color.New(color.FgRed).Fprintf(os.Stdout, "    %v\n", result.Err)
+				color.New(color.FgRed).Fprintf(os.Stdout, "    %v\n", secrets.RedactError(result.Err))
 				errResult = fmt.Errorf("diff for %s has failed with a few errors", result.Region)
 			}
 		}
 	}
 
-	res := manifest.Manifest{Regions: out, ParallelDeployment: opts.manifest.ParallelDeployment}
+	opts.exitCode = worker.DiffExitCode(regionResults)
 
-	s, err := json.MarshalIndent(&res, "", "  ")
+	err := opts.writeReport(report.New(out))
 	if err != nil {
 		return err
 	}
 
-	err = util.WriteToFile(s, "diff.json")
+	return errResult
+}
+
+// writeReport renders the diff report in the requested format. JSON is written to
+// diff.json unless an output is given, other formats go to stdout by default.
+func (opts *DiffOpts) writeReport(r *report.Report) error {
+	return writeOutput(r.Render, opts.format, opts.output, "diff.json")
+}
+
+// reportOut is the stdout reports are written to, progressToStderr moves everything else
+// cfstack prints out of its way
+var reportOut = os.Stdout
+
+// reportToStdout reports whether a report in format goes to stdout, JSON goes to a file
+// unless - is given and other formats go to stdout unless a file is given
+func reportToStdout(format string, output string) bool {
+	return output == "-" || (output == "" && format != report.FormatJSON)
+}
+
+// progressToStderr sends progress, warnings and banners to stderr when the report goes to
+// stdout, so that redirecting stdout captures the report alone
+func progressToStderr(format string, output string) {
+	if reportToStdout(format, output) {
+		os.Stdout = os.Stderr
+	}
+}
+
+// writeOutput renders a report to output, JSON goes to jsonFile when no output is given
+// and other formats to stdout
+func writeOutput(render func(w io.Writer, format string) error, format string, output string, jsonFile string) error {
+	if reportToStdout(format, output) {
+		return render(reportOut, format)
+	}
 
+	if output == "" {
+		output = jsonFile
+	}
+
+	b := &strings.Builder{}
+	err := render(b, format)
 	if err != nil {
 		return err
 	}
 
-	return errResult
+	return util.WriteToFile([]byte(b.String()), output)
 }
 
 func NewDiffCmd() *cobra.Command {
@@ -102,6 +162,12 @@ func NewDiffCmd() *cobra.Command {
 		Short: "Diff for you CloudFormation changes",
 		Long:  `Generates a diff of the changes to CloudFormation stacks defined in manifest files.`,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
+			progressToStderr(opts.format, opts.output)
+
+			if !report.ValidFormat(opts.format) {
+				return errors.Errorf("unknown format %s, must be one of %s", opts.format, strings.Join(report.Formats, ", "))
+			}
+
 			templatesRoot, err := filepath.Abs(filepath.Dir(opts.manifestFile))
 			if err != nil {
 				return err
@@ -113,14 +179,47 @@ func NewDiffCmd() *cobra.Command {
 				return err
 			}
 
-			if !filepath.IsAbs(opts.valuesFile) {
-				opts.valuesFile = filepath.Join(templatesRoot, opts.valuesFile)
+			err = applyBootstrapFlags(&opts.manifest, opts.bootstrap)
+			if err != nil {
+				return err
 			}
-			if util.FileExists(opts.valuesFile) {
-				opts.values, err = util.ParseJsonFile(opts.valuesFile)
-				if err != nil {
-					return err
-				}
+
+			opts.values, err = loadValues(templatesRoot, opts.valuesFiles)
+			if err != nil {
+				return err
+			}
+
+			err = setParamOverrides(&opts.manifest, opts.values, opts.params)
+			if err != nil {
+				return err
+			}
+
+			err = selectStacks(&opts.manifest, &opts.selector)
+			if err != nil {
+				return err
+			}
+
+			err = selectChangedSince(&opts.manifest, opts.since, opts.manifestFile, templatesRoot, opts.values.Files())
+			if err != nil {
+				return err
+			}
+
+			problems := validate.Parameters(&opts.manifest, opts.values, templatesRoot)
+			problems = append(problems, validate.Imports(&opts.manifest, templatesRoot)...)
+			problems = append(problems, validate.Limits(&opts.manifest, templatesRoot)...)
+			err = validate.Check(problems)
+			if err != nil {
+				return err
+			}
+
+			opts.policies, err = loadPolicies(templatesRoot, opts.policiesDir, &opts.manifest)
+			if err != nil {
+				return err
+			}
+
+			err = checkBootstrap(opts.profile, opts.manifest.Regions, opts.manifestFile)
+			if err != nil {
+				return err
 			}
 
 			uid, err := uuid.NewUUID()
@@ -137,14 +236,25 @@ func NewDiffCmd() *cobra.Command {
 				ExitWithError("Diff", err)
 			}
 			color.New(color.Bold, color.FgGreen).Fprintf(os.Stdout, "\nDiff command has completed\n")
+			if opts.detailedExitCode {
+				os.Exit(opts.exitCode)
+			}
 		},
 	}
 
 	cmd.Flags().StringVarP(&opts.manifestFile, "manifest", "m", "", "Set your manifest file")
-	cmd.Flags().StringVarP(&opts.valuesFile, "values", "", "values.json", "Set your values file")
+	cmd.Flags().StringArrayVarP(&opts.valuesFiles, "values", "", []string{defaultValuesFile}, "Set your values file, can be repeated. Later files override earlier ones")
+	cmd.Flags().StringArrayVarP(&opts.params, "param", "", nil, "Override a stack parameter, Stack.Param=value or region/Stack.Param=value. Can be repeated")
+	cmd.Flags().StringVarP(&opts.policiesDir, "policies", "", defaultPoliciesDir, "Directory of the policy rules, relative to the manifest")
 	cmd.Flags().IntVarP(&opts.workers, "workers", "w", worker.MaxWorker, "No of concurrent workers for fetching diff")
 	cmd.Flags().StringVarP(&opts.profile, "profile", "", "default", "Profile to use from AWS credentials")
-	cmd.Flags().StringVarP(&opts.role, "role", "", "", "Cloudformation service role to be used for stack operations")
+	cmd.Flags().StringVarP(&opts.role, "role", "", "", "Cloudformation service role to be used for stacks without a RoleArn, defaults to the role created by cfstack init")
+	addBootstrapFlags(cmd.Flags(), &opts.bootstrap)
+	cmd.Flags().StringVarP(&opts.format, "format", "f", report.FormatJSON, "Output format of the diff: json, markdown or table")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "", "File to write the diff to, - for stdout (default diff.json for json, stdout otherwise)")
+	addSelectorFlags(cmd.Flags(), &opts.selector)
+	cmd.Flags().StringVarP(&opts.since, "since", "", "", "Only diff stacks whose inputs changed since this git reference")
+	cmd.Flags().BoolVarP(&opts.detailedExitCode, "detailed-exitcode", "", false, "Exit with 0 when there are no changes, 1 on errors, 2 when there are changes and 3 when some diffs are unknown")
 	err := cmd.MarkFlagRequired("manifest")
 	if err != nil {
 		ExitWithError("diff", err)
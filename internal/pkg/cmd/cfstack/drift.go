@@ -0,0 +1,129 @@
+package cfstack
+
+import (
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"github.com/CleverTap/cfstack/internal/pkg/manifest"
+	"github.com/CleverTap/cfstack/internal/pkg/report"
+	"github.com/CleverTap/cfstack/internal/pkg/secrets"
+	"github.com/CleverTap/cfstack/internal/pkg/worker"
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+	"sync"
+)
+
+type DriftOpts struct {
+	manifestFile string
+	workers      int
+	profile      string
+	format       string
+	output       string
+
+	exitCode int
+
+	selector manifest.Selector
+	manifest manifest.Manifest
+}
+
+func (opts *DriftOpts) preRun() error {
+	progressToStderr(opts.format, opts.output)
+
+	if !report.ValidFormat(opts.format) {
+		return errors.Errorf("unknown format %s, must be one of %s", opts.format, strings.Join(report.Formats, ", "))
+	}
+
+	err := opts.manifest.Parse(opts.manifestFile)
+	if err != nil {
+		return err
+	}
+
+	return selectStacks(&opts.manifest, &opts.selector)
+}
+
+func (opts *DriftOpts) Run() error {
+	regionJobs := make(chan worker.RegionDriftWorkerJob, len(opts.manifest.Regions))
+	results := make(chan *worker.RegionDriftWorkerResult, len(opts.manifest.Regions))
+
+	wg := sync.WaitGroup{}
+
+	workers := len(opts.manifest.Regions)
+
+	for i := 1; i <= workers; i++ {
+		go worker.RegionDriftWorker(i, &wg, regionJobs, results)
+	}
+
+	for _, region := range opts.manifest.Regions {
+		regionJobs <- worker.RegionDriftWorkerJob{
+			Region:           region.Name,
+			Stacks:           region.Stacks,
+			Profile:          opts.profile,
+			StackDriftWorker: opts.workers,
+		}
+		wg.Add(1)
+	}
+	close(regionJobs)
+	wg.Wait()
+
+	drifts := map[string][]cloudformation.StackDrift{}
+	regionResults := make([]*worker.RegionDriftWorkerResult, 0, workers)
+
+	var errResult error
+
+	for i := 1; i <= workers; i++ {
+		result := <-results
+		regionResults = append(regionResults, result)
+		if len(result.Stacks) > 0 {
+			drifts[result.Region] = result.Stacks
+		}
+		if result.Err != nil {
+			color.New(color.FgRed).Fprintf(os.Stdout, "    %v\n", secrets.RedactError(result.Err))
+			errResult = fmt.Errorf("drift detection for %s has failed with a few errors", result.Region)
+		}
+	}
+
+	opts.exitCode = worker.DriftExitCode(regionResults)
+
+	err := writeOutput(report.NewDrift(drifts).Render, opts.format, opts.output, "drift.json")
+	if err != nil {
+		return err
+	}
+
+	return errResult
+}
+
+func NewDriftCmd() *cobra.Command {
+	opts := &DriftOpts{}
+	cmd := &cobra.Command{
+		Use:   "drift",
+		Short: "Detect changes made to your stacks outside of cfstack",
+		Long: `Runs drift detection on the stacks defined in manifest files and reports the resources that were
+			modified or deleted by hand with their expected and actual properties. Exits with 2 when a stack has drifted.`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return opts.preRun()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			err := opts.Run()
+			if err != nil {
+				ExitWithError("Drift", err)
+			}
+			color.New(color.Bold, color.FgGreen).Fprintf(os.Stdout, "\nDrift command has completed\n")
+			os.Exit(opts.exitCode)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.manifestFile, "manifest", "m", "", "Set your manifest file")
+	cmd.Flags().IntVarP(&opts.workers, "workers", "w", worker.MaxWorker, "No of concurrent workers for detecting drift")
+	cmd.Flags().StringVarP(&opts.profile, "profile", "", "default", "Profile to use from AWS credentials")
+	cmd.Flags().StringVarP(&opts.format, "format", "f", report.FormatJSON, "Output format of the drift report: json, markdown or table")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "", "File to write the drift report to, - for stdout (default drift.json for json, stdout otherwise)")
+	addSelectorFlags(cmd.Flags(), &opts.selector)
+	err := cmd.MarkFlagRequired("manifest")
+	if err != nil {
+		ExitWithError("drift", err)
+	}
+
+	return cmd
+}
@@ -0,0 +1,147 @@
+package cfstack
+
+import (
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/s3"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/session"
+	"github.com/CleverTap/cfstack/internal/pkg/bootstrap"
+	"github.com/CleverTap/cfstack/internal/pkg/git"
+	"github.com/CleverTap/cfstack/internal/pkg/history"
+	"github.com/CleverTap/cfstack/internal/pkg/manifest"
+	"github.com/CleverTap/cfstack/internal/pkg/values"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+type HistoryOpts struct {
+	name      string
+	region    string
+	profile   string
+	bootstrap bootstrap.Config
+}
+
+func (opts *HistoryOpts) Run() error {
+	clients, err := newRegionClients(opts.profile, opts.region, opts.bootstrap)
+	if err != nil {
+		return err
+	}
+
+	entries, err := clients.history().List(opts.region, opts.name)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		color.New(color.FgYellow).Fprintf(os.Stdout, "No deployments of stack %s recorded in region %s\n", opts.name, opts.region)
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DEPLOYMENT\tDEPLOYED AT\tGIT SHA\tTEMPLATE VERSION\tROLLBACK OF")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.DeploymentId, e.Timestamp.Local().Format(time.RFC3339),
+			orDash(e.GitSha), orDash(e.TemplateVersion), orDash(e.RollbackOf))
+	}
+	return tw.Flush()
+}
+
+// regionClients are the clients, templates bucket and bootstrap service role of a region for
+// commands that work on a single stack outside of the manifest
+type regionClients struct {
+	deployer cloudformation.CloudFormation
+	uploader s3.S3
+	bucket   string
+	role     string
+}
+
+func newRegionClients(profile string, region string, c bootstrap.Config) (*regionClients, error) {
+	err := c.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	sess, err := session.NewSession(&session.Opts{
+		Profile: profile,
+		Region:  region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	clients := &regionClients{
+		uploader: s3.New(sess),
+		deployer: cloudformation.New(sess, values.New()),
+	}
+
+	resources, err := c.Resolve(clients.deployer)
+	if err != nil {
+		return nil, err
+	}
+	clients.bucket = resources.TemplatesBucket
+	clients.role = resources.RoleArn
+	return clients, nil
+}
+
+// history is the deployment history kept in the templates bucket
+func (c *regionClients) history() *history.Store {
+	return history.New(&c.uploader, c.bucket)
+}
+
+// setGitSha records the commit the manifest is deployed from with every deployment,
+// nothing is recorded outside of a git repository
+func setGitSha(m *manifest.Manifest, templatesRoot string) {
+	repo, err := git.Open(templatesRoot)
+	if err != nil {
+		return
+	}
+	sha, err := repo.Head()
+	if err != nil {
+		return
+	}
+	for i := range m.Regions {
+		for j := range m.Regions[i].Stacks {
+			m.Regions[i].Stacks[j].GitSha = sha
+		}
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func NewHistoryCmd() *cobra.Command {
+	opts := &HistoryOpts{}
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List the recorded deployments of a stack",
+		Long: `Lists the deployments of a stack recorded in the TemplatesS3Bucket, the most recent first, with the
+				git commit and template version they were deployed from. Use the deployment id with cfstack rollback.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := opts.Run()
+			if err != nil {
+				ExitWithError("History", err)
+			}
+			color.New(color.Bold, color.FgGreen).Fprintf(os.Stdout, "\nHistory command has completed\n")
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.name, "name", "n", "", "Name of the stack")
+	cmd.Flags().StringVarP(&opts.region, "region", "r", "", "Region of the stack")
+	cmd.Flags().StringVarP(&opts.profile, "profile", "", "default", "Profile to use from AWS credentials")
+	addBootstrapFlags(cmd.Flags(), &opts.bootstrap)
+	for _, name := range []string{"name", "region"} {
+		err := cmd.MarkFlagRequired(name)
+		if err != nil {
+			ExitWithError("History", err)
+		}
+	}
+
+	return cmd
+}
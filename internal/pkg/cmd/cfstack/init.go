@@ -3,32 +3,64 @@ package cfstack
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/approval"
 	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/s3"
 	"github.com/CleverTap/cfstack/internal/pkg/aws/session"
+	"github.com/CleverTap/cfstack/internal/pkg/bootstrap"
+	"github.com/CleverTap/cfstack/internal/pkg/lock"
+	"github.com/CleverTap/cfstack/internal/pkg/manifest"
 	"github.com/CleverTap/cfstack/internal/pkg/templates"
 	"github.com/fatih/color"
+	"github.com/golang/glog"
 	"github.com/google/uuid"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"os"
+	"strings"
+	"sync"
+	"time"
 )
 
 type InitOpts struct {
-	profile string
-	region  string
+	profile      string
+	region       string
+	manifestFile string
+	yes          bool
+	bootstrap    bootstrap.Config
+
+	// options holds the values of the bootstrap flags, only flags that were set override
+	// the options a region was initialized with
+	options templates.InitOptions
+	flags   *pflag.FlagSet
 
 	deployer cloudformation.CloudFormation
+	uploader s3.S3
 }
 
-func (opts *InitOpts) Run() error {
-
-	templateBody, err := templates.GenerateInitTemplate()
+func (opts *InitOpts) connect() error {
+	sess, err := session.NewSession(&session.Opts{
+		Profile: opts.profile,
+		Region:  opts.region,
+	})
 
 	if err != nil {
 		return err
 	}
 
-	//fmt.Println(templateBody)
+	opts.deployer = cloudformation.NewWithoutValues(sess)
+	opts.uploader = s3.New(sess)
+	return nil
+}
+
+// stackName is the name of the bootstrap stack, cfstack-Init unless another name or a
+// qualifier is given
+func (opts *InitOpts) stackName() string {
+	return opts.bootstrap.Name()
+}
 
+func initStackPolicy() (string, error) {
 	stackPolicy := map[string]interface{}{
 		"Statement": []map[string]string{
 			{
@@ -43,92 +75,446 @@ func (opts *InitOpts) Run() error {
 	s, err := json.Marshal(stackPolicy)
 
 	if err != nil {
-		return err
+		return "", err
 	}
+	return string(s), nil
+}
 
-	stackName := "cfstack-Init"
+func (opts *InitOpts) Run() error {
 
-	sess, err := session.NewSession(&session.Opts{
-		Profile: opts.profile,
-		Region:  opts.region,
-	})
+	s, err := initStackPolicy()
 
 	if err != nil {
 		return err
 	}
 
-	opts.deployer = cloudformation.NewWithoutValues(sess)
+	stackName := opts.stackName()
+
+	err = opts.connect()
+
+	if err != nil {
+		return err
+	}
 
 	fmt.Printf("    Checking if %s already exists in %s \n", stackName, opts.region)
-	stackExists, err := opts.deployer.StackExists(stackName)
+	info, err := opts.deployer.DescribeStack(stackName)
 
 	if err != nil {
 		return err
 	}
 
-	if stackExists {
+	templateBody, err := opts.templateBody(info)
+
+	if err != nil {
+		return err
+	}
+
+	if info != nil {
 		fmt.Printf("    %s stack found in %s. Checking for changes\n", stackName, opts.region)
 
-		uid, err := uuid.NewUUID()
+		changes, changeSetName, err := opts.initChangeSet(templateBody, s)
+
 		if err != nil {
 			return err
 		}
 
-		changes, err := opts.deployer.GetStackChanges(&cloudformation.GetStackChangesOpts{
-			StackName:     stackName,
-			TemplateBody:  templateBody,
-			StackPolicy:   string(s),
-			ChangeSetName: fmt.Sprintf("changeset-%s-%s", uid.String(), stackName),
-			Type:          "UPDATE",
-		})
+		return opts.applyInitChanges(changes, changeSetName, s)
+
+	} else {
+		fmt.Printf("   %s not found in %s. Creating new stack\n", stackName, opts.region)
+		return opts.create(templateBody, s)
+	}
+}
 
+func (opts *InitOpts) create(templateBody string, stackPolicy string) error {
+	err := opts.deployer.CreateNewStack(&cloudformation.CreateStackOpts{
+		StackName:    opts.stackName(),
+		TemplateBody: templateBody,
+		StackPolicy:  stackPolicy,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("    %s stack has been created in %s\n", opts.stackName(), opts.region)
+	return nil
+}
+
+// templateBody generates the bootstrap template from the options the region was initialized
+// with, the defaults for a new region, and the bootstrap flags that were set
+func (opts *InitOpts) templateBody(info *cloudformation.StackInfo) (string, error) {
+	o := templates.DefaultInitOptions()
+	if info != nil {
+		var err error
+		o, err = templates.ParseInitOptions(info.Outputs)
 		if err != nil {
-			return err
+			return "", err
 		}
+	}
 
-		if changes.StackPolicyChange == true {
-			fmt.Printf("    Changes in stack policy detected, it will be updated first\n")
-			err = opts.deployer.SetStackPolicy(stackName, string(s))
+	changed := func(name string) bool {
+		return opts.flags != nil && opts.flags.Changed(name)
+	}
 
-			if err != nil {
-				return err
-			}
+	if changed("kms-key-arn") && changed("create-kms-key") {
+		return "", errors.New("--kms-key-arn and --create-kms-key can't be used together")
+	}
+	if changed("kms-key-arn") {
+		o.KmsKeyArn = opts.options.KmsKeyArn
+		o.CreateKmsKey = false
+	}
+	if changed("create-kms-key") {
+		o.CreateKmsKey = opts.options.CreateKmsKey
+		o.KmsKeyArn = ""
+	}
+	if changed("block-public-access") {
+		o.BlockPublicAccess = opts.options.BlockPublicAccess
+	}
+	if changed("enforce-tls") {
+		o.EnforceTLS = opts.options.EnforceTLS
+	}
+	if changed("expiration-days") {
+		o.ExpirationDays = opts.options.ExpirationDays
+	}
+	if changed("package-expiration-days") {
+		o.PackageExpirationDays = opts.options.PackageExpirationDays
+	}
+	if changed("noncurrent-expiration-days") {
+		o.NoncurrentExpirationDays = opts.options.NoncurrentExpirationDays
+	}
+	if changed("allowed-services") {
+		o.AllowedServices = opts.options.AllowedServices
+	}
 
-			fmt.Printf("    Stack policy updated")
+	return templates.GenerateInitTemplate(o)
+}
+
+// RunManifest initializes every region of the manifest in parallel, regions that are
+// already initialized are left as they are
+func (opts *InitOpts) RunManifest() error {
+	m := manifest.Manifest{}
+	err := m.Parse(opts.manifestFile)
+	if err != nil {
+		return err
+	}
+
+	templateBody, err := opts.templateBody(nil)
+	if err != nil {
+		return err
+	}
+
+	stackPolicy, err := initStackPolicy()
+	if err != nil {
+		return err
+	}
+
+	err = applyBootstrapFlags(&m, opts.bootstrap)
+	if err != nil {
+		return err
+	}
+
+	regions := m.Regions
+	errs := make([]error, len(regions))
+
+	wg := sync.WaitGroup{}
+	for i, region := range regions {
+		if region.Bootstrap.External() {
+			fmt.Printf("    %s uses its own buckets, skipping\n", region.Name)
+			continue
 		}
+		wg.Add(1)
+		go func(i int, region manifest.Region) {
+			defer wg.Done()
+			o := &InitOpts{profile: opts.profile, region: region.Name, bootstrap: region.Bootstrap}
+			errs[i] = o.initRegion(templateBody, stackPolicy)
+		}(i, region)
+	}
+	wg.Wait()
 
-		if len(changes.Resources) == 0 {
-			fmt.Printf("    No resource changes detected in stack %s\n", stackName)
-			return nil
+	var errRegions []string
+	for i, region := range regions {
+		if errs[i] != nil {
+			color.New(color.FgRed).Fprintf(os.Stdout, "    %s: %v\n", region.Name, errs[i])
+			errRegions = append(errRegions, region.Name)
 		}
+	}
+
+	if len(errRegions) > 0 {
+		return errors.Errorf("Initialization failed in region(s): %s", strings.Join(errRegions, ", "))
+	}
+	return nil
+}
+
+func (opts *InitOpts) initRegion(templateBody string, stackPolicy string) error {
+	err := opts.connect()
+	if err != nil {
+		return err
+	}
+
+	stackExists, err := opts.deployer.StackExists(opts.stackName())
+	if err != nil {
+		return err
+	}
 
-		err = opts.deployer.UpdateExistingStack(&cloudformation.CreateStackOpts{
-			StackName:    stackName,
-			TemplateBody: templateBody,
-			StackPolicy:  string(s),
-		})
+	if stackExists {
+		fmt.Printf("    %s already exists in %s, skipping\n", opts.stackName(), opts.region)
+		return nil
+	}
+
+	fmt.Printf("    Creating %s in %s\n", opts.stackName(), opts.region)
+	return opts.create(templateBody, stackPolicy)
+}
+
+func (opts *InitOpts) requireRegion() error {
+	if opts.region == "" {
+		return errors.New("--region is required")
+	}
+	return opts.bootstrap.Validate()
+}
+
+// initChangeSet creates the change set updating the bootstrap stack, it is kept so that
+// the changes shown are the ones that are executed
+func (opts *InitOpts) initChangeSet(templateBody string, stackPolicy string) (*cloudformation.Changes, string, error) {
+	uid, err := uuid.NewUUID()
+	if err != nil {
+		return nil, "", err
+	}
+
+	changeSetName := fmt.Sprintf("changeset-%s-%s", uid.String(), opts.stackName())
+	changes, err := opts.deployer.CreateChangeSet(&cloudformation.GetStackChangesOpts{
+		StackName:     opts.stackName(),
+		TemplateBody:  templateBody,
+		StackPolicy:   stackPolicy,
+		ChangeSetName: changeSetName,
+		Type:          "UPDATE",
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return changes, changeSetName, nil
+}
+
+// discardChangeSet deletes a change set that won't be executed
+func (opts *InitOpts) discardChangeSet(changeSetName string) {
+	err := opts.deployer.DeleteChangeSet(opts.stackName(), changeSetName)
+	if err != nil {
+		glog.Warningf("change set %s of stack %s could not be deleted: %v", changeSetName, opts.stackName(), err)
+	}
+}
+
+func (opts *InitOpts) applyInitChanges(changes *cloudformation.Changes, changeSetName string, stackPolicy string) error {
+	if changes.StackPolicyChange == true {
+		fmt.Printf("    Changes in stack policy detected, it will be updated first\n")
+		err := opts.deployer.SetStackPolicy(opts.stackName(), stackPolicy)
 
 		if err != nil {
+			opts.discardChangeSet(changeSetName)
 			return err
 		}
-		fmt.Printf("    %s stack has been updated\n", stackName)
 
+		fmt.Printf("    Stack policy updated\n")
+	}
+
+	// Outputs only changes, like a new bootstrap version, come without resource changes
+	if len(changes.Resources) == 0 && !changes.ForceStackUpdate {
+		opts.discardChangeSet(changeSetName)
+		fmt.Printf("    No resource changes detected in stack %s\n", opts.stackName())
+		return nil
+	}
+
+	err := opts.deployer.ExecuteChangeSet(opts.stackName(), changeSetName)
+
+	if err != nil {
+		return err
+	}
+	fmt.Printf("    %s stack has been updated\n", opts.stackName())
+	return nil
+}
+
+// initStack returns the bootstrap stack of the region, it fails when the region is
+// not initialized
+func (opts *InitOpts) initStack() (*cloudformation.StackInfo, error) {
+	err := opts.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := opts.deployer.DescribeStack(opts.stackName())
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return nil, errors.Errorf("%s not found in region %s, run cfstack init --region %s%s", opts.stackName(), opts.region, opts.region, bootstrapArgs(opts.bootstrap))
+	}
+	return info, nil
+}
+
+// confirm asks before changing the bootstrap unless --yes was given
+func (opts *InitOpts) confirm(question string) (bool, error) {
+	if opts.yes {
+		return true, nil
+	}
+
+	approver, err := approval.New(nil, true)
+	if err != nil {
+		return false, err
+	}
+	if !approver.CanPrompt() {
+		return false, errors.New("confirmation needs a terminal, use --yes to run without prompts")
+	}
+	return approver.Confirm(question)
+}
+
+func (opts *InitOpts) Status() error {
+	info, err := opts.initStack()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("    %s in region %s is %s, last updated %s\n", opts.stackName(), opts.region, info.Status, info.LastUpdated.Local().Format(time.RFC3339))
+
+	version := bootstrapVersion(info)
+	if version < templates.BootstrapVersion {
+		color.New(color.FgYellow).Fprintf(os.Stdout, "    Bootstrap version %d is outdated, this cfstack needs %d. Run cfstack init upgrade --region %s\n",
+			version, templates.BootstrapVersion, opts.region)
 	} else {
-		fmt.Printf("   %s not found in %s. Creating new stack\n", stackName, opts.region)
-		err = opts.deployer.CreateNewStack(&cloudformation.CreateStackOpts{
-			StackName:    stackName,
-			TemplateBody: templateBody,
-			StackPolicy:  string(s),
-		})
+		color.New(color.FgGreen).Fprintf(os.Stdout, "    Bootstrap version %d is up to date\n", version)
+	}
+
+	for _, resource := range []string{"TemplatesS3Bucket", "SourceS3Bucket"} {
+		bucket, err := opts.deployer.GetStackResourcePhysicalId(opts.stackName(), resource)
 		if err != nil {
 			return err
 		}
-		fmt.Printf("    %s stack has been created\n", stackName)
+		fmt.Printf("    %s: %s\n", resource, bucket)
+	}
+	if role := info.Outputs[templates.ServiceRoleOutput]; role != "" {
+		fmt.Printf("    Service role: %s\n", role)
 	}
 
+	if _, ok := info.Outputs[templates.BootstrapOptionsOutput]; !ok {
+		return nil
+	}
+	o, err := templates.ParseInitOptions(info.Outputs)
+	if err != nil {
+		return err
+	}
+
+	encryption := "S3 managed keys"
+	if key := info.Outputs["KmsKeyArn"]; key != "" {
+		encryption = "KMS key " + key
+	}
+	fmt.Printf("    Encryption: %s\n", encryption)
+	fmt.Printf("    Public access blocked: %t, TLS only: %t\n", o.BlockPublicAccess, o.EnforceTLS)
+	fmt.Printf("    Uploaded templates expire after %s, lambda packages after %s, old versions after %s\n",
+		days(o.ExpirationDays), days(o.PackageExpirationDays), days(o.NoncurrentExpirationDays))
+	fmt.Printf("    Service role allows: %s\n", strings.Join(o.AllowedServices, ", "))
 	return nil
 }
 
+// Upgrade shows the changes the current bootstrap template makes to cfstack-Init and
+// applies them through a change set
+func (opts *InitOpts) Upgrade() error {
+	info, err := opts.initStack()
+	if err != nil {
+		return err
+	}
+
+	templateBody, err := opts.templateBody(info)
+	if err != nil {
+		return err
+	}
+
+	stackPolicy, err := initStackPolicy()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("    Upgrading bootstrap version %d to %d\n", bootstrapVersion(info), templates.BootstrapVersion)
+
+	changes, changeSetName, err := opts.initChangeSet(templateBody, stackPolicy)
+	if err != nil {
+		return err
+	}
+
+	if len(changes.Resources) == 0 && !changes.ForceStackUpdate && !changes.StackPolicyChange {
+		opts.discardChangeSet(changeSetName)
+		color.New(color.FgGreen).Fprintf(os.Stdout, "    %s is up to date\n", opts.stackName())
+		return nil
+	}
+
+	for _, c := range changes.Resources {
+		fmt.Printf("    %s\n", approval.Describe(c))
+	}
+	if changes.ForceStackUpdate {
+		fmt.Printf("    Update outputs\n")
+	}
+	if changes.StackPolicyChange {
+		fmt.Printf("    Update stack policy\n")
+	}
+
+	ok, err := opts.confirm(fmt.Sprintf("    Apply these changes to %s in region %s?", opts.stackName(), opts.region))
+	if err != nil || !ok {
+		opts.discardChangeSet(changeSetName)
+		return err
+	}
+
+	return opts.applyInitChanges(changes, changeSetName, stackPolicy)
+}
+
+// Destroy empties the buckets of the bootstrap stack, every version included, and deletes it
+func (opts *InitOpts) Destroy() error {
+	_, err := opts.initStack()
+	if err != nil {
+		return err
+	}
+
+	templatesBucket, err := opts.deployer.GetStackResourcePhysicalId(opts.stackName(), "TemplatesS3Bucket")
+	if err != nil {
+		return err
+	}
+	sourceBucket, err := opts.deployer.GetStackResourcePhysicalId(opts.stackName(), "SourceS3Bucket")
+	if err != nil {
+		return err
+	}
+
+	held, err := lock.New(&opts.uploader, templatesBucket, opts.region).Status()
+	if err != nil {
+		return err
+	}
+	if held != nil && !held.Expired(time.Now()) {
+		return errors.Errorf("region %s is locked by %s", opts.region, held)
+	}
+
+	color.New(color.FgYellow).Fprintf(os.Stdout, "    Deleting %s removes the buckets %s and %s with every template, deployment history and package in them\n",
+		opts.stackName(), templatesBucket, sourceBucket)
+	ok, err := opts.confirm(fmt.Sprintf("    Destroy %s in region %s?", opts.stackName(), opts.region))
+	if err != nil || !ok {
+		return err
+	}
+
+	for _, bucket := range []string{templatesBucket, sourceBucket} {
+		deleted, err := opts.uploader.EmptyBucket(bucket)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("    Deleted %d object version(s) from %s\n", deleted, bucket)
+	}
+
+	err = opts.deployer.DeleteStack(&cloudformation.DeleteStackOpts{StackName: opts.stackName()})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("    %s stack has been deleted\n", opts.stackName())
+	return nil
+}
+
+func days(n int) string {
+	if n == 0 {
+		return "never"
+	}
+	return fmt.Sprintf("%d days", n)
+}
+
 func NewInitCmd() *cobra.Command {
 	opts := &InitOpts{}
 	cmd := &cobra.Command{
@@ -136,7 +522,27 @@ func NewInitCmd() *cobra.Command {
 		Short: "Init your region to be able use cfstack commands",
 		Long: `Deploys a cfstack-Init cloudformation stack with required resources 
 			(buckets, iam roles etc) needed to run cfstack commands.`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if opts.manifestFile == "" && opts.region == "" {
+				return errors.New("either --region or --manifest is required")
+			}
+			if opts.manifestFile != "" && opts.region != "" {
+				return errors.New("--region and --manifest can't be used together")
+			}
+			return opts.bootstrap.Validate()
+		},
 		Run: func(cmd *cobra.Command, args []string) {
+			opts.flags = cmd.Flags()
+			if opts.manifestFile != "" {
+				fmt.Printf("==> %s  Initializing your account to run cfstack in the regions of %s\n", gear, opts.manifestFile)
+				err := opts.RunManifest()
+				if err != nil {
+					ExitWithError("init", err)
+				}
+				color.New(color.Bold, color.FgGreen).Fprintf(os.Stdout, "\n%s Initialization complete\n", check)
+				return
+			}
+
 			fmt.Printf("==> %s  Initializing your account to run cfstack in %s\n", gear, opts.region)
 			err := opts.Run()
 			if err != nil {
@@ -146,12 +552,69 @@ func NewInitCmd() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVarP(&opts.region, "region", "r", "", "AWS Region to init cfstack in")
-	cmd.Flags().StringVarP(&opts.profile, "profile", "", "default", "Profile to use from AWS credentials")
-	err := cmd.MarkFlagRequired("region")
-	if err != nil {
-		ExitWithError("init", err)
-	}
+	cmd.AddCommand(&cobra.Command{
+		Use: "status",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return opts.requireRegion()
+		},
+		Short: "Show whether a region is initialized and its bootstrap version",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Printf("==> %s  Checking cfstack initialization in %s\n", magnifier, opts.region)
+			err := opts.Status()
+			if err != nil {
+				ExitWithError("init status", err)
+			}
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use: "upgrade",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return opts.requireRegion()
+		},
+		Short: "Upgrade cfstack-Init to the bootstrap template of this cfstack",
+		Run: func(cmd *cobra.Command, args []string) {
+			opts.flags = cmd.Flags()
+			fmt.Printf("==> %s  Upgrading cfstack initialization in %s\n", gear, opts.region)
+			err := opts.Upgrade()
+			if err != nil {
+				ExitWithError("init upgrade", err)
+			}
+			color.New(color.Bold, color.FgGreen).Fprintf(os.Stdout, "\n%s Upgrade complete in %s\n", check, opts.region)
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use: "destroy",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return opts.requireRegion()
+		},
+		Short: "Empty the cfstack buckets and delete cfstack-Init",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Printf("==> %s  Removing cfstack initialization from %s\n", knife, opts.region)
+			err := opts.Destroy()
+			if err != nil {
+				ExitWithError("init destroy", err)
+			}
+			color.New(color.Bold, color.FgGreen).Fprintf(os.Stdout, "\n%s Destroy complete in %s\n", check, opts.region)
+		},
+	})
+
+	cmd.PersistentFlags().StringVarP(&opts.region, "region", "r", "", "AWS Region to init cfstack in")
+	cmd.PersistentFlags().StringVarP(&opts.profile, "profile", "", "default", "Profile to use from AWS credentials")
+	cmd.PersistentFlags().BoolVarP(&opts.yes, "yes", "y", false, "Don't ask before upgrading or destroying")
+	addBootstrapStackFlags(cmd.PersistentFlags(), &opts.bootstrap)
+
+	defaults := templates.DefaultInitOptions()
+	cmd.PersistentFlags().StringVarP(&opts.options.KmsKeyArn, "kms-key-arn", "", "", "Encrypt the cfstack buckets with this KMS key")
+	cmd.PersistentFlags().BoolVarP(&opts.options.CreateKmsKey, "create-kms-key", "", false, "Create a KMS key to encrypt the cfstack buckets")
+	cmd.PersistentFlags().BoolVarP(&opts.options.BlockPublicAccess, "block-public-access", "", defaults.BlockPublicAccess, "Block public access to the cfstack buckets")
+	cmd.PersistentFlags().BoolVarP(&opts.options.EnforceTLS, "enforce-tls", "", defaults.EnforceTLS, "Deny requests to the cfstack buckets that don't use TLS")
+	cmd.PersistentFlags().IntVarP(&opts.options.ExpirationDays, "expiration-days", "", defaults.ExpirationDays, "Expire the templates uploaded by each run this many days after upload, deployed templates are kept with the history. 0 keeps them")
+	cmd.PersistentFlags().IntVarP(&opts.options.PackageExpirationDays, "package-expiration-days", "", defaults.PackageExpirationDays, "Expire lambda packages this many days after upload, 0 keeps them")
+	cmd.PersistentFlags().IntVarP(&opts.options.NoncurrentExpirationDays, "noncurrent-expiration-days", "", defaults.NoncurrentExpirationDays, "Remove overwritten or deleted object versions after this many days, 0 keeps them")
+	cmd.PersistentFlags().StringSliceVarP(&opts.options.AllowedServices, "allowed-services", "", defaults.AllowedServices, "IAM prefixes of the services the CloudFormation service role may manage, * for all")
+	cmd.Flags().StringVarP(&opts.manifestFile, "manifest", "m", "", "Initialize every region of this manifest, regions already initialized are skipped")
 
 	return cmd
 }
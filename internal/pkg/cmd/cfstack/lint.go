@@ -0,0 +1,152 @@
+package cfstack
+
+import (
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/manifest"
+	"github.com/CleverTap/cfstack/internal/pkg/templates"
+	"github.com/CleverTap/cfstack/internal/pkg/util"
+	"github.com/CleverTap/cfstack/internal/pkg/validate"
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+)
+
+const defaultLintConfigFile = ".cfstack-lint.json"
+
+type LintOpts struct {
+	manifestFile string
+	configFile   string
+	listRules    bool
+
+	templatePaths []string
+	config        *templates.LintConfig
+}
+
+func (opts *LintOpts) preRun() error {
+	root := "."
+	if opts.manifestFile != "" {
+		root = filepath.Dir(opts.manifestFile)
+
+		m := manifest.Manifest{}
+		err := m.Parse(opts.manifestFile)
+		if err != nil {
+			return err
+		}
+
+		templatesRoot, err := filepath.Abs(root)
+		if err != nil {
+			return err
+		}
+
+		seen := map[string]bool{}
+		for _, region := range m.Regions {
+			for _, s := range region.Stacks {
+				path := validate.TemplatePath(templatesRoot, s)
+				if s.Action == "DELETE" || seen[path] {
+					continue
+				}
+				seen[path] = true
+				opts.templatePaths = append(opts.templatePaths, path)
+			}
+		}
+	}
+
+	if len(opts.templatePaths) == 0 && !opts.listRules {
+		return errors.New("Set a manifest with --manifest or pass the templates to lint")
+	}
+
+	configFile := opts.configFile
+	if configFile == "" {
+		configFile = filepath.Join(root, defaultLintConfigFile)
+		if !util.FileExists(configFile) {
+			return nil
+		}
+	}
+
+	var err error
+	opts.config, err = templates.LoadLintConfig(configFile)
+	return err
+}
+
+func (opts *LintOpts) Run() error {
+	if opts.listRules {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "RULE\tSEVERITY\tDESCRIPTION")
+		for _, r := range templates.Rules() {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", r.ID, r.Severity, r.Description)
+		}
+		return tw.Flush()
+	}
+
+	var errorCount, warningCount int
+
+	for _, path := range opts.templatePaths {
+		fmt.Printf("==> %s  Linting template %s\n", magnifier, filepath.Base(path))
+
+		t, err := templates.LoadTemplate(path)
+		if err != nil {
+			color.New(color.FgRed).Fprintf(os.Stdout, "    error: %s\n", err)
+			errorCount++
+			continue
+		}
+
+		for _, f := range t.Lint(opts.config) {
+			line := fmt.Sprintf("    %s %s %s: %s\n", f.Severity, f.Rule, f.Path, f.Message)
+			switch f.Severity {
+			case templates.SeverityError:
+				errorCount++
+				color.New(color.FgRed).Fprint(os.Stdout, line)
+			case templates.SeverityWarning:
+				warningCount++
+				color.New(color.FgYellow).Fprint(os.Stdout, line)
+			default:
+				fmt.Print(line)
+			}
+		}
+	}
+
+	fmt.Printf("\n%d error(s), %d warning(s) in %d template(s)\n", errorCount, warningCount, len(opts.templatePaths))
+
+	if errorCount > 0 {
+		return errors.Errorf("Lint found %d error(s)", errorCount)
+	}
+	return nil
+}
+
+func NewLintCmd() *cobra.Command {
+	opts := &LintOpts{}
+	cmd := &cobra.Command{
+		Use:   "lint [template...]",
+		Short: "Lint templates without calling AWS",
+		Long: `Runs local lint rules against the templates of a manifest or the given templates.
+			Rules can be disabled or their severity changed in .cfstack-lint.json, e.g.
+			{"Rules": {"hardcoded-account": {"Enabled": false}, "unused-parameter": {"Severity": "error"}}}
+			and suppressed for a template or a resource with Metadata: {"cfstack": {"lint": {"ignore": ["rule-id"]}}}`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			for _, arg := range args {
+				path, err := filepath.Abs(arg)
+				if err != nil {
+					return err
+				}
+				opts.templatePaths = append(opts.templatePaths, path)
+			}
+			return opts.preRun()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			err := opts.Run()
+			if err != nil {
+				ExitWithError("Lint", err)
+			}
+			color.New(color.Bold, color.FgGreen).Fprintf(os.Stdout, "\nLint command has completed\n")
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.manifestFile, "manifest", "m", "", "Lint every template of the manifest")
+	cmd.Flags().StringVarP(&opts.configFile, "lint-config", "", "", "Lint config file (default .cfstack-lint.json next to the manifest)")
+	cmd.Flags().BoolVarP(&opts.listRules, "rules", "", false, "List the available rules")
+
+	return cmd
+}
@@ -0,0 +1,139 @@
+package cfstack
+
+import (
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/bootstrap"
+	"github.com/CleverTap/cfstack/internal/pkg/lock"
+	"github.com/CleverTap/cfstack/internal/pkg/manifest"
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"os"
+	"time"
+)
+
+type LockOpts struct {
+	region    string
+	profile   string
+	bootstrap bootstrap.Config
+}
+
+// locker is the run lock of a region, kept in its TemplatesS3Bucket
+func (c *regionClients) locker(region string) *lock.Locker {
+	return lock.New(&c.uploader, c.bucket, region)
+}
+
+// acquireLocks locks every region for the run, the returned func releases them. Nothing
+// stays locked when a region can't be locked.
+func acquireLocks(profile string, regions []manifest.Region, uid string, command string, ttl time.Duration) (func(), error) {
+	var held []*lock.Locker
+	release := func() {
+		for _, l := range held {
+			err := l.Release(uid)
+			if err != nil {
+				color.New(color.FgYellow).Fprintf(os.Stdout, "    Lock could not be released: %v\n", err)
+			}
+		}
+	}
+
+	for _, region := range regions {
+		clients, err := newRegionClients(profile, region.Name, region.Bootstrap)
+		if err != nil {
+			release()
+			return nil, err
+		}
+
+		l := clients.locker(region.Name)
+		err = l.Acquire(lock.NewLock(uid, command, ttl))
+		if err != nil {
+			release()
+			return nil, errors.Wrapf(err, "could not lock region %s", region.Name)
+		}
+		held = append(held, l)
+	}
+
+	return release, nil
+}
+
+func (opts *LockOpts) Status() error {
+	clients, err := newRegionClients(opts.profile, opts.region, opts.bootstrap)
+	if err != nil {
+		return err
+	}
+
+	l, err := clients.locker(opts.region).Status()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case l == nil:
+		fmt.Printf("    Region %s is not locked\n", opts.region)
+	case l.Expired(time.Now()):
+		color.New(color.FgYellow).Fprintf(os.Stdout, "    Region %s has a stale lock that the next run will replace: %s\n", opts.region, l)
+	default:
+		color.New(color.FgYellow).Fprintf(os.Stdout, "    Region %s is locked by %s\n", opts.region, l)
+	}
+	return nil
+}
+
+func (opts *LockOpts) Release() error {
+	clients, err := newRegionClients(opts.profile, opts.region, opts.bootstrap)
+	if err != nil {
+		return err
+	}
+
+	l, err := clients.locker(opts.region).ForceRelease()
+	if err != nil {
+		return err
+	}
+
+	if l == nil {
+		fmt.Printf("    Region %s is not locked\n", opts.region)
+		return nil
+	}
+	color.New(color.FgGreen).Fprintf(os.Stdout, "    Removed the lock of region %s held by %s\n", opts.region, l)
+	return nil
+}
+
+func NewLockCmd() *cobra.Command {
+	opts := &LockOpts{}
+	cmd := &cobra.Command{
+		Use:   "lock",
+		Short: "Show or remove the deployment lock of a region",
+		Long: `deploy and delete lock every region they work on so that concurrent runs don't interleave
+				change sets. The lock is kept in the TemplatesS3Bucket of the region and expires on its own.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show who holds the lock of a region",
+		Run: func(cmd *cobra.Command, args []string) {
+			err := opts.Status()
+			if err != nil {
+				ExitWithError("Lock status", err)
+			}
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "release",
+		Short: "Remove the lock of a region, e.g. after a run was killed",
+		Run: func(cmd *cobra.Command, args []string) {
+			err := opts.Release()
+			if err != nil {
+				ExitWithError("Lock release", err)
+			}
+		},
+	})
+
+	cmd.PersistentFlags().StringVarP(&opts.region, "region", "r", "", "Region of the lock")
+	cmd.PersistentFlags().StringVarP(&opts.profile, "profile", "", "default", "Profile to use from AWS credentials")
+	addBootstrapFlags(cmd.PersistentFlags(), &opts.bootstrap)
+	err := cmd.MarkPersistentFlagRequired("region")
+	if err != nil {
+		ExitWithError("Lock", err)
+	}
+
+	return cmd
+}
@@ -0,0 +1,37 @@
+package cfstack
+
+import (
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/manifest"
+	"github.com/CleverTap/cfstack/internal/pkg/policy"
+	"path/filepath"
+)
+
+const defaultPoliciesDir = "policies"
+
+// loadPolicies reads the policy rules, a relative directory is resolved against the
+// templates root. Waivers of every stack must name a rule and give a justification.
+func loadPolicies(templatesRoot string, dir string, m *manifest.Manifest) (*policy.Set, error) {
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(templatesRoot, dir)
+	}
+
+	set, err := policy.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, region := range m.Regions {
+		for _, s := range region.Stacks {
+			err = set.CheckWaivers(s.StackName, s.PolicyWaivers)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if !set.IsEmpty() {
+		fmt.Printf("==> %s  Loaded %d policy rule(s) from %s\n", gear, len(set.Rules), filepath.Base(dir))
+	}
+	return set, nil
+}
@@ -0,0 +1,134 @@
+package cfstack
+
+import (
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/approval"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/s3"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/session"
+	"github.com/CleverTap/cfstack/internal/pkg/manifest"
+	"github.com/CleverTap/cfstack/internal/pkg/report"
+	"github.com/CleverTap/cfstack/internal/pkg/stack"
+	"strings"
+)
+
+// changeReviewer shows the changes of a stack the way diff renders them and asks the
+// operator whether to apply them, stacks that are deleted list the resources they lose
+type changeReviewer struct {
+	approver *approval.Approver
+}
+
+func (r changeReviewer) Review(s stack.Stack) (approval.Decision, error) {
+	b := &strings.Builder{}
+	err := report.New([]manifest.Region{{Name: s.Region, Stacks: []stack.Stack{s}}}).Render(b, report.FormatTable)
+	if err != nil {
+		return approval.Abort, err
+	}
+
+	summary := "\n" + b.String() + "\n"
+	question := fmt.Sprintf("    Apply changes to stack %s in region %s?", s.StackName, s.Region)
+	if s.Action == "DELETE" {
+		summary = fmt.Sprintf("\n    Delete stack %s (%d resources)\n", s.StackName, len(s.Changes.Resources)) + summary
+		question = fmt.Sprintf("    Delete stack %s in region %s?", s.StackName, s.Region)
+	}
+	return r.approver.Decide(summary, question)
+}
+
+// reviewUpFront asks about the changes of every stack before a parallel deployment
+// starts, skipped stacks are removed from the manifest. Protected changes of the applied
+// stacks are approved at the same time.
+func (opts *DeployOpts) reviewUpFront() error {
+	for i, region := range opts.manifest.Regions {
+		sess, err := session.NewSession(&session.Opts{
+			Profile: opts.profile,
+			Region:  region.Name,
+		})
+		if err != nil {
+			return err
+		}
+
+		uploader := s3.New(sess)
+		deployer := cloudformation.New(sess, opts.values)
+
+		resources, err := region.Bootstrap.Resolve(deployer)
+		if err != nil {
+			return err
+		}
+
+		account, err := opts.policies.Account(sess)
+		if err != nil {
+			return err
+		}
+
+		kept := make([]stack.Stack, 0, len(region.Stacks))
+		for j, s := range region.Stacks {
+			fmt.Printf("==> %s  Computing changes for stack %s in region %s\n", magnifier, s.StackName, region.Name)
+			s.SetRegion(region.Name)
+			s.SetUuid(opts.uid)
+			s.SetBucket(resources.TemplatesBucket)
+			s.SourceBucket = resources.SourceBucket
+			s.TemplateRootPath = opts.templatesRoot
+
+			s.Uploader = uploader
+			s.Deployer = deployer
+
+			s.SetRole(opts.role, resources.RoleArn)
+			s.Policies = opts.policies
+			s.AccountId = account
+
+			var changes *cloudformation.Changes
+			if s.Action == "DELETE" {
+				changes, err = deleteChanges(s)
+			} else {
+				changes, err = s.Preview()
+			}
+			if err != nil {
+				if strings.Contains(err.Error(), "No updates are to be performed") {
+					kept = append(kept, region.Stacks[j])
+					continue
+				}
+				return err
+			}
+			s.Changes = changes
+
+			if changes == nil || (s.Action != "DELETE" && !changes.StackPolicyChange && !changes.ForceStackUpdate && len(changes.Resources) == 0) {
+				kept = append(kept, region.Stacks[j])
+				continue
+			}
+
+			decision, err := opts.reviewer.Review(s)
+			if err != nil {
+				return err
+			}
+			switch decision {
+			case approval.Abort:
+				return approval.ErrAborted
+			case approval.Apply:
+				// Protected changes are approved now too, the deployment doesn't prompt
+				if s.Action != "DELETE" {
+					err = opts.approver.Approve(region.Name, s.StackName, s.ProtectedChanges(changes.Resources))
+					if err != nil {
+						return err
+					}
+				}
+				kept = append(kept, region.Stacks[j])
+			}
+		}
+		opts.manifest.Regions[i].Stacks = kept
+	}
+	return nil
+}
+
+// deleteChanges lists the resources a deleted stack loses, nil when there is no stack
+func deleteChanges(s stack.Stack) (*cloudformation.Changes, error) {
+	exists, err := s.Deployer.StackExists(s.StackName)
+	if err != nil || !exists {
+		return nil, err
+	}
+	changes, err := s.Deployer.DeleteStackChanges(s.StackName)
+	if err != nil {
+		return nil, err
+	}
+	changes.Status = stack.DiffSuccessStatus
+	return changes, nil
+}
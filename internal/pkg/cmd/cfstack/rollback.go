@@ -0,0 +1,136 @@
+package cfstack
+
+import (
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/approval"
+	"github.com/CleverTap/cfstack/internal/pkg/bootstrap"
+	"github.com/CleverTap/cfstack/internal/pkg/lock"
+	"github.com/CleverTap/cfstack/internal/pkg/manifest"
+	"github.com/CleverTap/cfstack/internal/pkg/stack"
+	"github.com/fatih/color"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"os"
+	"time"
+)
+
+type RollbackOpts struct {
+	name      string
+	region    string
+	to        string
+	profile   string
+	role      string
+	bootstrap bootstrap.Config
+	approvals []string
+	yes       bool
+	lockTTL   time.Duration
+
+	approver *approval.Approver
+	reviewer stack.Reviewer
+}
+
+func (opts *RollbackOpts) preRun() error {
+	var err error
+	opts.approver, err = approval.New(opts.approvals, !opts.yes)
+	if err != nil {
+		return err
+	}
+
+	if !opts.yes {
+		if !opts.approver.CanPrompt() {
+			return errors.New("rollback asks to confirm the changes in a terminal, use --yes to roll back without prompts")
+		}
+		opts.reviewer = changeReviewer{approver: opts.approver}
+	}
+	return nil
+}
+
+// Run deploys the template, parameters and stack policy of a recorded deployment again
+// through a change set like any other update
+func (opts *RollbackOpts) Run() error {
+	clients, err := newRegionClients(opts.profile, opts.region, opts.bootstrap)
+	if err != nil {
+		return err
+	}
+
+	entry, err := clients.history().Get(opts.region, opts.name, opts.to)
+	if err != nil {
+		return err
+	}
+
+	stackExists, err := clients.deployer.StackExists(opts.name)
+	if err != nil {
+		return err
+	}
+	if !stackExists {
+		return errors.Errorf("stack %s does not exist in region %s, rollback only updates existing stacks", opts.name, opts.region)
+	}
+
+	s, err := stack.FromHistory(*entry)
+	if err != nil {
+		return err
+	}
+
+	uid, err := uuid.NewUUID()
+	if err != nil {
+		return err
+	}
+
+	release, err := acquireLocks(opts.profile, []manifest.Region{{Name: opts.region, Bootstrap: opts.bootstrap}}, uid.String(), "rollback", opts.lockTTL)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	s.Region = opts.region
+	s.UID = uid.String()
+	s.Bucket = clients.bucket
+	s.Approver = opts.approver
+	s.Reviewer = opts.reviewer
+	s.Deployer = clients.deployer
+	s.Uploader = clients.uploader
+
+	s.SetRole(opts.role, clients.role)
+
+	fmt.Printf("==> %s  Rolling back stack %s in region %s to deployment %s\n", rocket, s.StackName, s.Region, entry.DeploymentId)
+	return s.Deploy()
+}
+
+func NewRollbackCmd() *cobra.Command {
+	opts := &RollbackOpts{}
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Deploy a previous deployment of a stack again",
+		Long: `Redeploys the template version, parameters and stack policy recorded for a deployment of a stack,
+				see cfstack history. Secrets are resolved again from their ssm or secretsmanager placeholders.`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return opts.preRun()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			err := opts.Run()
+			if err != nil {
+				ExitWithError("Rollback", err)
+			}
+			color.New(color.Bold, color.FgGreen).Fprintf(os.Stdout, "\nRollback command has completed\n")
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.name, "name", "n", "", "Name of the stack")
+	cmd.Flags().StringVarP(&opts.region, "region", "r", "", "Region of the stack")
+	cmd.Flags().StringVarP(&opts.to, "to", "", "", "Id of the deployment to roll back to")
+	cmd.Flags().StringVarP(&opts.profile, "profile", "", "default", "Profile to use from AWS credentials")
+	cmd.Flags().StringVarP(&opts.role, "role", "", "", "Cloudformation service role to be used for the rollback, defaults to the role created by cfstack init")
+	addBootstrapFlags(cmd.Flags(), &opts.bootstrap)
+	cmd.Flags().StringArrayVarP(&opts.approvals, "approve-replacements", "", nil, "Approve the removal or replacement of a protected resource, Stack/Resource. Can be repeated")
+	cmd.Flags().DurationVarP(&opts.lockTTL, "lock-ttl", "", lock.DefaultTTL, "Time after which the lock of the region taken by this run is considered stale")
+	cmd.Flags().BoolVarP(&opts.yes, "yes", "y", false, "Don't show the changes and ask for confirmation")
+	for _, name := range []string{"name", "region", "to"} {
+		err := cmd.MarkFlagRequired(name)
+		if err != nil {
+			ExitWithError("Rollback", err)
+		}
+	}
+
+	return cmd
+}
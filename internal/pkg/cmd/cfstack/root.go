@@ -18,6 +18,7 @@ package cfstack
 import (
 	goflag "flag"
 	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/secrets"
 	"github.com/fatih/color"
 	"github.com/golang/glog"
 	"github.com/mitchellh/go-homedir"
@@ -47,7 +48,7 @@ var rootCmd = &cobra.Command{
 
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stdout, color.RedString("❗️ %v\n", err))
+		fmt.Fprintf(os.Stdout, color.RedString("❗️ %v\n", secrets.RedactError(err)))
 		os.Exit(1)
 	}
 }
@@ -61,7 +62,14 @@ func init() {
 	rootCmd.AddCommand(NewInitCmd())
 	rootCmd.AddCommand(NewDeployCmd())
 	rootCmd.AddCommand(NewDiffCmd())
+	rootCmd.AddCommand(NewDriftCmd())
+	rootCmd.AddCommand(NewHistoryCmd())
+	rootCmd.AddCommand(NewRollbackCmd())
+	rootCmd.AddCommand(NewLockCmd())
 	rootCmd.AddCommand(NewDeleteCmd())
+	rootCmd.AddCommand(NewValuesCmd())
+	rootCmd.AddCommand(NewValidateCmd())
+	rootCmd.AddCommand(NewLintCmd())
 
 	flag.CommandLine.AddGoFlagSet(goflag.CommandLine)
 }
@@ -0,0 +1,30 @@
+package cfstack
+
+import (
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/manifest"
+	"github.com/spf13/pflag"
+)
+
+func addSelectorFlags(flags *pflag.FlagSet, sel *manifest.Selector) {
+	flags.StringSliceVarP(&sel.Regions, "region", "", nil, "Only work on these regions from the manifest")
+	flags.StringSliceVarP(&sel.Stacks, "stack", "", nil, "Only work on stacks matching these glob patterns")
+	flags.StringSliceVarP(&sel.ExcludeStacks, "exclude-stack", "", nil, "Skip stacks matching these glob patterns")
+	flags.StringSliceVarP(&sel.Tags, "tag", "", nil, "Only work on stacks having these key=value tags")
+	flags.BoolVarP(&sel.WithDependencies, "with-dependencies", "", false, "Also include the stacks the selected stacks depend on")
+}
+
+func selectStacks(m *manifest.Manifest, sel *manifest.Selector) error {
+	if sel.IsEmpty() {
+		return nil
+	}
+
+	total := m.StackCount()
+	err := m.Select(sel)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("    Selected %d of %d stacks in %d region(s)\n", m.StackCount(), total, len(m.Regions))
+	return nil
+}
@@ -0,0 +1,51 @@
+package cfstack
+
+import (
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/changes"
+	"github.com/CleverTap/cfstack/internal/pkg/git"
+	"github.com/CleverTap/cfstack/internal/pkg/manifest"
+	"github.com/fatih/color"
+	"os"
+)
+
+// selectChangedSince keeps only the stacks whose inputs changed since ref. All stacks are
+// kept when git is not available or the manifest is not in a git repository.
+func selectChangedSince(m *manifest.Manifest, ref string, manifestFile string, templatesRoot string, valuesFiles []string) error {
+	if ref == "" {
+		return nil
+	}
+
+	fmt.Printf("==> %s  Looking for stacks changed since %s\n", magnifier, ref)
+
+	repo, err := git.Open(templatesRoot)
+	if err != nil {
+		color.New(color.FgYellow).Fprintf(os.Stdout, "    %v, all stacks will be processed\n", err)
+		return nil
+	}
+
+	decisions, err := changes.Since(m, &changes.Opts{
+		Ref:           ref,
+		ManifestFile:  manifestFile,
+		TemplatesRoot: templatesRoot,
+		ValuesFiles:   valuesFiles,
+		Repo:          repo,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, d := range decisions {
+		if d.Changed {
+			fmt.Printf("    Processing stack %s in region %s: %s\n", d.StackName, d.Region, d.Reason)
+		} else {
+			color.New(color.FgYellow).Fprintf(os.Stdout, "    Skipping stack %s in region %s: %s\n", d.StackName, d.Region, d.Reason)
+		}
+	}
+
+	if m.StackCount() == 0 {
+		color.New(color.FgYellow).Fprintf(os.Stdout, "    No stacks changed since %s\n", ref)
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package cfstack
+
+import (
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/validate"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+)
+
+type ValidateOpts struct {
+	manifestFile string
+	valuesFiles  []string
+	params       []string
+}
+
+func (opts *ValidateOpts) Run() error {
+	fmt.Printf("==> %s  Validating manifest file %s\n", magnifier, filepath.Base(opts.manifestFile))
+
+	templatesRoot, err := filepath.Abs(filepath.Dir(opts.manifestFile))
+	if err != nil {
+		return err
+	}
+
+	problems := validate.Run(&validate.Opts{
+		ManifestFile: opts.manifestFile,
+		ValuesFiles:  valuesPaths(templatesRoot, opts.valuesFiles),
+		Params:       opts.params,
+	})
+	if len(problems) > 0 {
+		return validate.Check(problems)
+	}
+
+	fmt.Printf("    No problems found\n")
+	return nil
+}
+
+func NewValidateCmd() *cobra.Command {
+	opts := &ValidateOpts{}
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate a manifest, its values and templates without AWS credentials",
+		Long: `Checks the manifest, values files, templates, stack policies and code paths and
+			resolves every placeholder that doesn't need AWS. All problems are reported at once.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := opts.Run()
+			if err != nil {
+				ExitWithError("Validate", err)
+			}
+			color.New(color.Bold, color.FgGreen).Fprintf(os.Stdout, "\nValidate command has completed\n")
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.manifestFile, "manifest", "m", "", "Set your manifest file")
+	cmd.Flags().StringArrayVarP(&opts.valuesFiles, "values", "", []string{defaultValuesFile}, "Set your values file, can be repeated. Later files override earlier ones")
+	cmd.Flags().StringArrayVarP(&opts.params, "param", "", nil, "Override a stack parameter, Stack.Param=value or region/Stack.Param=value. Can be repeated")
+	err := cmd.MarkFlagRequired("manifest")
+	if err != nil {
+		ExitWithError("Validate", err)
+	}
+
+	return cmd
+}
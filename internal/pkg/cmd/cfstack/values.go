@@ -0,0 +1,162 @@
+package cfstack
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/manifest"
+	"github.com/CleverTap/cfstack/internal/pkg/util"
+	"github.com/CleverTap/cfstack/internal/pkg/values"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+)
+
+const defaultValuesFile = "values.json"
+
+type ValuesExplainOpts struct {
+	manifestFile string
+	valuesFiles  []string
+	stack        string
+	region       string
+}
+
+// loadValues parses the values files in order, relative paths are resolved against the
+// templates root. The default values file is optional, any other file has to exist.
+func loadValues(templatesRoot string, files []string) (*values.Values, error) {
+	return values.Load(valuesPaths(templatesRoot, files))
+}
+
+func valuesPaths(templatesRoot string, files []string) []string {
+	var paths []string
+	for _, f := range files {
+		path := f
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(templatesRoot, path)
+		}
+		if f == defaultValuesFile && !util.FileExists(path) {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// setParamOverrides stores the --param overrides, every override must target a stack
+// of the manifest and, when given, one of its regions
+func setParamOverrides(m *manifest.Manifest, v *values.Values, params []string) error {
+	err := v.SetOverrides(params)
+	if err != nil {
+		return err
+	}
+
+	for _, o := range v.AllOverrides() {
+		found := false
+		for _, region := range m.Regions {
+			if o.Region != "" && region.Name != o.Region {
+				continue
+			}
+			for _, s := range region.Stacks {
+				if s.StackName == o.Stack {
+					found = true
+				}
+			}
+		}
+		if !found && o.Region != "" {
+			return errors.Errorf("--param %s.%s: stack %s not found in region %s", o.Stack, o.Parameter, o.Stack, o.Region)
+		}
+		if !found {
+			return errors.Errorf("--param %s.%s: stack %s not found in manifest", o.Stack, o.Parameter, o.Stack)
+		}
+	}
+	return nil
+}
+
+func (opts *ValuesExplainOpts) Run() error {
+	root, err := filepath.Abs(".")
+	if err != nil {
+		return err
+	}
+	if opts.manifestFile != "" {
+		root, err = filepath.Abs(filepath.Dir(opts.manifestFile))
+		if err != nil {
+			return err
+		}
+	}
+
+	v, err := loadValues(root, opts.valuesFiles)
+	if err != nil {
+		return err
+	}
+
+	names := v.Names(opts.region, opts.stack)
+	if len(names) == 0 {
+		fmt.Printf("No values found for stack %s in region %s\n", opts.stack, opts.region)
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tVALUE\tSCOPE\tFILE")
+	for _, name := range names {
+		value, source, _ := v.Lookup(opts.region, opts.stack, name)
+		file, err := filepath.Rel(root, source.File)
+		if err != nil {
+			file = source.File
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", name, formatValue(value), source.Scope, file)
+	}
+	return tw.Flush()
+}
+
+func formatValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+func NewValuesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "values",
+		Short: "Inspect values used to resolve stack parameters",
+	}
+	cmd.AddCommand(newValuesExplainCmd())
+	return cmd
+}
+
+func newValuesExplainCmd() *cobra.Command {
+	opts := &ValuesExplainOpts{}
+	cmd := &cobra.Command{
+		Use:   "explain",
+		Short: "Show where each value of a stack comes from",
+		Long: `Lists every value visible to a stack in a region along with the values file
+			and the scope (stack, stack in all regions, region default or global default) it was resolved from.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := opts.Run()
+			if err != nil {
+				ExitWithError("Values explain", err)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.manifestFile, "manifest", "m", "", "Manifest file, values files are resolved relative to it")
+	cmd.Flags().StringArrayVarP(&opts.valuesFiles, "values", "", []string{defaultValuesFile}, "Values file, can be repeated. Later files override earlier ones")
+	cmd.Flags().StringVarP(&opts.stack, "stack", "", "", "Name of the stack")
+	cmd.Flags().StringVarP(&opts.region, "region", "r", "", "Region of the stack")
+	err := cmd.MarkFlagRequired("stack")
+	if err != nil {
+		ExitWithError("Values explain", err)
+	}
+
+	err = cmd.MarkFlagRequired("region")
+	if err != nil {
+		ExitWithError("Values explain", err)
+	}
+
+	return cmd
+}
@@ -0,0 +1,159 @@
+package git
+
+import (
+	"bytes"
+	"github.com/pkg/errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Repo is a local git work tree
+type Repo struct {
+	Root string
+}
+
+// Open finds the git work tree containing dir. It fails when git is not installed
+// or dir is not part of a repository.
+func Open(dir string) (*Repo, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, errors.New("git executable not found")
+	}
+
+	out, err := run(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := filepath.EvalSymlinks(strings.TrimSpace(out))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Repo{Root: root}, nil
+}
+
+// ChangedFiles returns the absolute paths of files that are different in the work tree
+// compared to ref, including files that are not tracked yet
+func (r *Repo) ChangedFiles(ref string) ([]string, error) {
+	if _, err := run(r.Root, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+		return nil, errors.Errorf("%s is not a valid git reference", ref)
+	}
+
+	diff, err := run(r.Root, "diff", "--name-only", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+
+	untracked, err := run(r.Root, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, line := range strings.Split(diff+"\n"+untracked, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		files = append(files, filepath.Join(r.Root, filepath.FromSlash(line)))
+	}
+
+	return files, nil
+}
+
+// Show returns the content of a file at ref. The boolean result is false when the file
+// didn't exist at ref.
+func (r *Repo) Show(ref string, path string) ([]byte, bool, error) {
+	rel, err := r.Rel(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if _, err := run(r.Root, "cat-file", "-e", ref+":"+rel); err != nil {
+		return nil, false, nil
+	}
+
+	out, err := run(r.Root, "show", ref+":"+rel)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return []byte(out), true, nil
+}
+
+// Head returns the commit SHA the work tree is at
+func (r *Repo) Head() (string, error) {
+	out, err := run(r.Root, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Rel returns path relative to the root of the repository using forward slashes
+func (r *Repo) Rel(path string) (string, error) {
+	abs, err := Canonical(path)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(r.Root, abs)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.HasPrefix(rel, "..") {
+		return "", errors.Errorf("%s is outside of git repository %s", path, r.Root)
+	}
+
+	return filepath.ToSlash(rel), nil
+}
+
+// Contains reports whether path is inside the work tree
+func (r *Repo) Contains(path string) bool {
+	_, err := r.Rel(path)
+	return err == nil
+}
+
+// Canonical returns the absolute path with symlinks resolved as far as the path exists
+func Canonical(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	dir, rest := abs, ""
+	for {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			return filepath.Join(resolved, rest), nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return abs, nil
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+		dir = parent
+	}
+}
+
+func run(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", errors.Errorf("git %s: %s", args[0], msg)
+	}
+
+	return stdout.String(), nil
+}
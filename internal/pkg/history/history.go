@@ -0,0 +1,179 @@
+package history
+
+import (
+	"encoding/json"
+	"github.com/CleverTap/cfstack/internal/pkg/secrets"
+	"github.com/CleverTap/cfstack/internal/pkg/templates"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// prefix is where deployment entries and their templates are kept in the TemplatesS3Bucket,
+// it never expires
+const prefix = "history"
+
+// Entry records a single deployment of a stack
+type Entry struct {
+	DeploymentId    string            `json:"DeploymentId"`
+	StackName       string            `json:"StackName"`
+	Region          string            `json:"Region"`
+	TemplateUrl     string            `json:"TemplateUrl,omitempty"`
+	TemplateVersion string            `json:"TemplateVersion,omitempty"`
+	Parameters      map[string]string `json:"Parameters"`
+	// SecretParameters holds the placeholders of the parameters that were redacted, they
+	// are resolved again on rollback
+	SecretParameters map[string]string        `json:"SecretParameters,omitempty"`
+	StackPolicy      templates.PolicyDocument `json:"StackPolicy"`
+	Timestamp        time.Time                `json:"Timestamp"`
+	GitSha           string                   `json:"GitSha,omitempty"`
+	RollbackOf       string                   `json:"RollbackOf,omitempty"`
+	// TemplateBody holds templates that were passed inline instead of uploaded
+	TemplateBody string `json:"TemplateBody,omitempty"`
+	// Serverless is set for SAM templates, they are deployed with CAPABILITY_AUTO_EXPAND
+	Serverless bool `json:"Serverless,omitempty"`
+}
+
+// ObjectStore is the part of the S3 client the history needs
+type ObjectStore interface {
+	PutObject(bucket string, key string, body []byte) error
+	GetObject(bucket string, key string) ([]byte, error)
+	ListKeys(bucket string, prefix string) ([]string, error)
+}
+
+type Store struct {
+	objects ObjectStore
+	bucket  string
+}
+
+func New(objects ObjectStore, bucket string) *Store {
+	return &Store{objects: objects, bucket: bucket}
+}
+
+func stackPrefix(region string, stackName string) string {
+	return prefix + "/" + region + "/" + stackName + "/"
+}
+
+func key(region string, stackName string, deploymentId string) string {
+	return stackPrefix(region, stackName) + deploymentId + ".json"
+}
+
+// TemplateKey is where the template of a deployment is kept next to its entry, templates
+// uploaded by a run expire and rollbacks need the deployed one
+func TemplateKey(region string, stackName string, deploymentId string) string {
+	return stackPrefix(region, stackName) + deploymentId + ".template"
+}
+
+func (s *Store) Record(e Entry) error {
+	b, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	return s.objects.PutObject(s.bucket, key(e.Region, e.StackName, e.DeploymentId), b)
+}
+
+// List returns the deployments of a stack, the most recent first
+func (s *Store) List(region string, stackName string) ([]Entry, error) {
+	keys, err := s.objects.ListKeys(s.bucket, stackPrefix(region, stackName))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(keys))
+	for _, k := range keys {
+		if !strings.HasSuffix(k, ".json") {
+			continue
+		}
+		e, err := s.load(k)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *e)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.After(entries[j].Timestamp)
+	})
+	return entries, nil
+}
+
+func (s *Store) Get(region string, stackName string, deploymentId string) (*Entry, error) {
+	e, err := s.load(key(region, stackName, deploymentId))
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, errors.Errorf("no deployment %s of stack %s in region %s, see cfstack history", deploymentId, stackName, region)
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+func (s *Store) load(k string) (*Entry, error) {
+	b, err := s.objects.GetObject(s.bucket, k)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Entry{}
+	err = json.Unmarshal(b, e)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid deployment entry %s", k)
+	}
+	return e, nil
+}
+
+// Redact masks the resolved parameters that hold a value read from ssm or secretsmanager,
+// the placeholders they were resolved from are returned for those parameters
+func Redact(params map[string]string, resolved map[string]string) (map[string]string, map[string]string) {
+	out := make(map[string]string, len(resolved))
+	var placeholders map[string]string
+
+	for k, v := range resolved {
+		masked := secrets.Redact(v)
+		out[k] = masked
+		if masked == v {
+			continue
+		}
+		if placeholders == nil {
+			placeholders = map[string]string{}
+		}
+		placeholders[k] = params[k]
+	}
+	return out, placeholders
+}
+
+// VersionedTemplateUrl points to the template exactly as it was deployed
+func (e Entry) VersionedTemplateUrl() string {
+	if e.TemplateVersion == "" {
+		return e.TemplateUrl
+	}
+	return e.TemplateUrl + "?versionId=" + url.QueryEscape(e.TemplateVersion)
+}
+
+// RollbackParameters returns the parameters to deploy the entry again, redacted values
+// are replaced by their placeholders
+func (e Entry) RollbackParameters() (map[string]string, error) {
+	params := make(map[string]string, len(e.Parameters))
+	for k, v := range e.Parameters {
+		params[k] = v
+	}
+
+	var missing []string
+	for k, placeholder := range e.SecretParameters {
+		if placeholder == "" {
+			missing = append(missing, k)
+			continue
+		}
+		params[k] = placeholder
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, errors.Errorf("parameter(s) %s of deployment %s were redacted and can't be restored", strings.Join(missing, ", "), e.DeploymentId)
+	}
+	return params, nil
+}
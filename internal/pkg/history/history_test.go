@@ -0,0 +1,97 @@
+package history
+
+import (
+	"github.com/CleverTap/cfstack/internal/pkg/secrets"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/require"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeObjectStore map[string][]byte
+
+func (f fakeObjectStore) PutObject(bucket string, key string, body []byte) error {
+	f[bucket+"/"+key] = body
+	return nil
+}
+
+func (f fakeObjectStore) GetObject(bucket string, key string) ([]byte, error) {
+	b, ok := f[bucket+"/"+key]
+	if !ok {
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "The specified key does not exist.", nil)
+	}
+	return b, nil
+}
+
+func (f fakeObjectStore) ListKeys(bucket string, prefix string) ([]string, error) {
+	var keys []string
+	for k := range f {
+		if strings.HasPrefix(k, bucket+"/"+prefix) {
+			keys = append(keys, strings.TrimPrefix(k, bucket+"/"))
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+type fakeParameterStore map[string]string
+
+func (f fakeParameterStore) GetParameter(name string) (string, error) {
+	return f[name], nil
+}
+
+func TestStore(t *testing.T) {
+	store := New(fakeObjectStore{}, "templates")
+	now := time.Date(2019, 11, 20, 10, 0, 0, 0, time.UTC)
+
+	require.NoError(t, store.Record(Entry{DeploymentId: "a", StackName: "Api", Region: "eu-west-1", Timestamp: now}))
+	require.NoError(t, store.Record(Entry{DeploymentId: "b", StackName: "Api", Region: "eu-west-1", Timestamp: now.Add(time.Hour)}))
+	require.NoError(t, store.Record(Entry{DeploymentId: "c", StackName: "Api", Region: "us-east-1", Timestamp: now}))
+	require.NoError(t, store.Record(Entry{DeploymentId: "d", StackName: "Api-Internal", Region: "eu-west-1", Timestamp: now}))
+
+	entries, err := store.List("eu-west-1", "Api")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "b", entries[0].DeploymentId)
+	require.Equal(t, "a", entries[1].DeploymentId)
+
+	e, err := store.Get("us-east-1", "Api", "c")
+	require.NoError(t, err)
+	require.Equal(t, "c", e.DeploymentId)
+
+	_, err = store.Get("us-east-1", "Api", "a")
+	require.EqualError(t, err, "no deployment a of stack Api in region us-east-1, see cfstack history")
+}
+
+func TestRedact(t *testing.T) {
+	store := secrets.NewStore("eu-west-1", fakeParameterStore{"/api/password": "hunter2-hunter2"}, nil)
+	_, err := store.Resolve("ssm:/api/password")
+	require.NoError(t, err)
+
+	params := map[string]string{"Env": "{{ Env }}", "Password": "{{ ssm:/api/password }}"}
+	resolved := map[string]string{"Env": "prod", "Password": "hunter2-hunter2"}
+
+	out, placeholders := Redact(params, resolved)
+	require.Equal(t, map[string]string{"Env": "prod", "Password": secrets.Mask}, out)
+	require.Equal(t, map[string]string{"Password": "{{ ssm:/api/password }}"}, placeholders)
+
+	e := Entry{DeploymentId: "a", Parameters: out, SecretParameters: placeholders}
+	restored, err := e.RollbackParameters()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"Env": "prod", "Password": "{{ ssm:/api/password }}"}, restored)
+
+	e.SecretParameters = map[string]string{"Password": ""}
+	_, err = e.RollbackParameters()
+	require.EqualError(t, err, "parameter(s) Password of deployment a were redacted and can't be restored")
+}
+
+func TestVersionedTemplateUrl(t *testing.T) {
+	e := Entry{TemplateUrl: "https://s3-eu-west-1.amazonaws.com/templates/uid/api.json"}
+	require.Equal(t, e.TemplateUrl, e.VersionedTemplateUrl())
+
+	e.TemplateVersion = "3/L4kqtJl+/4"
+	require.Equal(t, e.TemplateUrl+"?versionId=3%2FL4kqtJl%2B%2F4", e.VersionedTemplateUrl())
+}
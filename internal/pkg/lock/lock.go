@@ -0,0 +1,175 @@
+package lock
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+	"os"
+	"os/user"
+	"time"
+)
+
+// DefaultTTL is how long a lock is held before other runs consider it stale
+const DefaultTTL = 3 * time.Hour
+
+// Lock is the object written to the TemplatesS3Bucket while a run deploys to a region
+type Lock struct {
+	Owner    string    `json:"Owner"`
+	Host     string    `json:"Host"`
+	Uid      string    `json:"Uid"`
+	Command  string    `json:"Command"`
+	Acquired time.Time `json:"Acquired"`
+	Expires  time.Time `json:"Expires"`
+}
+
+func (l Lock) String() string {
+	return fmt.Sprintf("%s on %s (run %s, %s) since %s, expires %s", l.Owner, l.Host, l.Uid, l.Command,
+		l.Acquired.Local().Format(time.RFC3339), l.Expires.Local().Format(time.RFC3339))
+}
+
+// Expired reports whether the run that took the lock is considered gone
+func (l Lock) Expired(now time.Time) bool {
+	return !now.Before(l.Expires)
+}
+
+// ObjectStore is the part of the S3 client the lock needs. PutObjectIfAbsent is a
+// conditional write that fails when the key exists, PutObjectIfMatch and
+// DeleteObjectIfMatch fail when the object no longer has the ETag that was read.
+type ObjectStore interface {
+	PutObjectIfAbsent(bucket string, key string, body []byte) (bool, error)
+	PutObjectIfMatch(bucket string, key string, body []byte, etag string) (bool, error)
+	GetObjectWithETag(bucket string, key string) ([]byte, string, error)
+	DeleteObject(bucket string, key string) error
+	DeleteObjectIfMatch(bucket string, key string, etag string) (bool, error)
+}
+
+type Locker struct {
+	objects ObjectStore
+	bucket  string
+	key     string
+	now     func() time.Time
+}
+
+// New returns the lock of a region, the bucket belongs to a single account and region
+func New(objects ObjectStore, bucket string, region string) *Locker {
+	return &Locker{
+		objects: objects,
+		bucket:  bucket,
+		key:     "locks/" + region + ".json",
+		now:     time.Now,
+	}
+}
+
+// NewLock describes the current run as the owner of a lock
+func NewLock(uid string, command string, ttl time.Duration) Lock {
+	owner := "unknown"
+	if u, err := user.Current(); err == nil {
+		owner = u.Username
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	now := time.Now().UTC()
+	return Lock{
+		Owner:    owner,
+		Host:     host,
+		Uid:      uid,
+		Command:  command,
+		Acquired: now,
+		Expires:  now.Add(ttl),
+	}
+}
+
+// Acquire takes the lock for l.Uid. A stale lock is replaced, a lock held by another run
+// is an error.
+func (lk *Locker) Acquire(l Lock) error {
+	b, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; attempt < 3; attempt++ {
+		ok, err := lk.objects.PutObjectIfAbsent(lk.bucket, lk.key, b)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+
+		held, etag, err := lk.read()
+		if err != nil {
+			return err
+		}
+		if held == nil {
+			// Released in the meantime
+			continue
+		}
+		if held.Uid == l.Uid {
+			return nil
+		}
+		if !held.Expired(lk.now()) {
+			return errors.Errorf("locked by %s\nWait for that run to finish or remove the lock with cfstack lock release", held)
+		}
+
+		// Only replace the stale lock that was read, when another run replaced it first
+		// the next attempt finds that run's lock
+		ok, err = lk.objects.PutObjectIfMatch(lk.bucket, lk.key, b, etag)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+
+	return errors.New("lock is being taken by another run, try again")
+}
+
+// Release removes the lock when it is still held by uid, a lock replaced by another run
+// in the meantime is left alone
+func (lk *Locker) Release(uid string) error {
+	held, etag, err := lk.read()
+	if err != nil || held == nil || held.Uid != uid {
+		return err
+	}
+	_, err = lk.objects.DeleteObjectIfMatch(lk.bucket, lk.key, etag)
+	return err
+}
+
+// ForceRelease removes the lock whoever holds it and returns the removed lock
+func (lk *Locker) ForceRelease() (*Lock, error) {
+	held, err := lk.Status()
+	if err != nil || held == nil {
+		return nil, err
+	}
+	return held, lk.objects.DeleteObject(lk.bucket, lk.key)
+}
+
+// Status returns the current lock, nil when nobody holds it
+func (lk *Locker) Status() (*Lock, error) {
+	l, _, err := lk.read()
+	return l, err
+}
+
+// read returns the current lock with the ETag of its object
+func (lk *Locker) read() (*Lock, string, error) {
+	b, etag, err := lk.objects.GetObjectWithETag(lk.bucket, lk.key)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, "", nil
+		}
+		return nil, "", err
+	}
+
+	l := &Lock{}
+	err = json.Unmarshal(b, l)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "invalid lock object %s", lk.key)
+	}
+	return l, etag, nil
+}
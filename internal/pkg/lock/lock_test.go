@@ -0,0 +1,184 @@
+package lock
+
+import (
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type object struct {
+	body []byte
+	etag string
+}
+
+// fakeObjectStore keeps objects in memory, afterRead runs once after the next read so
+// tests can make another run act between a read and the write that depends on it
+type fakeObjectStore struct {
+	objects   map[string]object
+	writes    int
+	afterRead func()
+}
+
+func newFakeObjectStore() *fakeObjectStore {
+	return &fakeObjectStore{objects: map[string]object{}}
+}
+
+func (f *fakeObjectStore) put(bucket string, key string, body []byte) {
+	f.writes++
+	f.objects[bucket+"/"+key] = object{body: body, etag: strconv.Itoa(f.writes)}
+}
+
+func (f *fakeObjectStore) PutObjectIfAbsent(bucket string, key string, body []byte) (bool, error) {
+	if _, ok := f.objects[bucket+"/"+key]; ok {
+		return false, nil
+	}
+	f.put(bucket, key, body)
+	return true, nil
+}
+
+func (f *fakeObjectStore) PutObjectIfMatch(bucket string, key string, body []byte, etag string) (bool, error) {
+	if o, ok := f.objects[bucket+"/"+key]; !ok || o.etag != etag {
+		return false, nil
+	}
+	f.put(bucket, key, body)
+	return true, nil
+}
+
+func (f *fakeObjectStore) GetObjectWithETag(bucket string, key string) ([]byte, string, error) {
+	if f.afterRead != nil {
+		afterRead := f.afterRead
+		f.afterRead = nil
+		defer afterRead()
+	}
+	o, ok := f.objects[bucket+"/"+key]
+	if !ok {
+		return nil, "", awserr.New(s3.ErrCodeNoSuchKey, "The specified key does not exist.", nil)
+	}
+	return o.body, o.etag, nil
+}
+
+func (f *fakeObjectStore) DeleteObject(bucket string, key string) error {
+	delete(f.objects, bucket+"/"+key)
+	return nil
+}
+
+func (f *fakeObjectStore) DeleteObjectIfMatch(bucket string, key string, etag string) (bool, error) {
+	if o, ok := f.objects[bucket+"/"+key]; !ok || o.etag != etag {
+		return false, nil
+	}
+	delete(f.objects, bucket+"/"+key)
+	return true, nil
+}
+
+func TestAcquire(t *testing.T) {
+	now := time.Date(2019, 11, 20, 10, 0, 0, 0, time.UTC)
+	held := Lock{Owner: "alice", Host: "ci-1", Uid: "run-1", Command: "deploy", Acquired: now, Expires: now.Add(time.Hour)}
+
+	testCases := map[string]struct {
+		held  *Lock
+		at    time.Time
+		uid   string
+		err   string
+		owner string
+	}{
+		"unlocked":          {at: now, uid: "run-2", owner: "run-2"},
+		"held by other run": {held: &held, at: now.Add(30 * time.Minute), uid: "run-2", err: "locked by alice on ci-1 (run run-1, deploy)", owner: "run-1"},
+		"held by same run":  {held: &held, at: now.Add(30 * time.Minute), uid: "run-1", owner: "run-1"},
+		"stale":             {held: &held, at: now.Add(time.Hour), uid: "run-2", owner: "run-2"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			lk := New(newFakeObjectStore(), "templates", "eu-west-1")
+			lk.now = func() time.Time { return tc.at }
+			if tc.held != nil {
+				require.NoError(t, lk.Acquire(*tc.held))
+			}
+
+			err := lk.Acquire(Lock{Owner: "bob", Host: "laptop", Uid: tc.uid, Command: "deploy", Acquired: tc.at, Expires: tc.at.Add(time.Hour)})
+			if tc.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			l, err := lk.Status()
+			require.NoError(t, err)
+			require.Equal(t, tc.owner, l.Uid)
+		})
+	}
+}
+
+func TestRelease(t *testing.T) {
+	lk := New(newFakeObjectStore(), "templates", "eu-west-1")
+	require.NoError(t, lk.Acquire(NewLock("run-1", "deploy", DefaultTTL)))
+
+	require.NoError(t, lk.Release("run-2"))
+	l, err := lk.Status()
+	require.NoError(t, err)
+	require.Equal(t, "run-1", l.Uid)
+
+	require.NoError(t, lk.Release("run-1"))
+	l, err = lk.Status()
+	require.NoError(t, err)
+	require.Nil(t, l)
+
+	require.NoError(t, lk.Acquire(NewLock("run-3", "delete", DefaultTTL)))
+	removed, err := lk.ForceRelease()
+	require.NoError(t, err)
+	require.Equal(t, "run-3", removed.Uid)
+
+	removed, err = lk.ForceRelease()
+	require.NoError(t, err)
+	require.Nil(t, removed)
+}
+
+func TestAcquireStaleRace(t *testing.T) {
+	now := time.Date(2019, 11, 20, 10, 0, 0, 0, time.UTC)
+	store := newFakeObjectStore()
+	a := New(store, "templates", "eu-west-1")
+	b := New(store, "templates", "eu-west-1")
+	for _, lk := range []*Locker{a, b} {
+		lk.now = func() time.Time { return now.Add(2 * time.Hour) }
+	}
+	require.NoError(t, a.Acquire(Lock{Uid: "run-0", Acquired: now, Expires: now.Add(time.Hour)}))
+
+	runA := Lock{Owner: "alice", Host: "ci-1", Uid: "run-a", Command: "deploy", Acquired: now, Expires: now.Add(3 * time.Hour)}
+	runB := Lock{Owner: "bob", Host: "ci-2", Uid: "run-b", Command: "deploy", Acquired: now, Expires: now.Add(3 * time.Hour)}
+
+	// Both runs read the stale lock, b replaces it before a does
+	var errB error
+	store.afterRead = func() { errB = b.Acquire(runB) }
+	errA := a.Acquire(runA)
+
+	require.NoError(t, errB)
+	require.Error(t, errA)
+	require.Contains(t, errA.Error(), "locked by bob on ci-2 (run run-b, deploy)")
+
+	l, err := a.Status()
+	require.NoError(t, err)
+	require.Equal(t, "run-b", l.Uid)
+}
+
+func TestReleaseReplacedRace(t *testing.T) {
+	now := time.Date(2019, 11, 20, 10, 0, 0, 0, time.UTC)
+	store := newFakeObjectStore()
+	a := New(store, "templates", "eu-west-1")
+	b := New(store, "templates", "eu-west-1")
+	b.now = func() time.Time { return now.Add(2 * time.Hour) }
+	require.NoError(t, a.Acquire(Lock{Uid: "run-a", Acquired: now, Expires: now.Add(time.Hour)}))
+
+	// a's lock expired and b takes it over while a releases
+	store.afterRead = func() {
+		require.NoError(t, b.Acquire(Lock{Uid: "run-b", Acquired: now, Expires: now.Add(3 * time.Hour)}))
+	}
+	require.NoError(t, a.Release("run-a"))
+
+	l, err := a.Status()
+	require.NoError(t, err)
+	require.Equal(t, "run-b", l.Uid)
+}
@@ -3,6 +3,8 @@ package manifest
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/s3"
+	"github.com/CleverTap/cfstack/internal/pkg/bootstrap"
 	"github.com/CleverTap/cfstack/internal/pkg/stack"
 	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/golang/glog"
@@ -16,11 +18,25 @@ import (
 type Region struct {
 	Name   string        `validate:"required" json:"Name"`
 	Stacks []stack.Stack `validate:"required" json:"Stacks"`
+
+	// Bootstrap overrides the bootstrap of the manifest for this region
+	Bootstrap bootstrap.Config `json:"Bootstrap"`
 }
 
 type Manifest struct {
 	Regions            []Region `validate:"required" json:"Regions"`
 	ParallelDeployment bool     `json:"ParallelDeployment"`
+
+	// Bootstrap selects the cfstack init stack or the buckets used in every region
+	Bootstrap bootstrap.Config `json:"Bootstrap"`
+
+	// S3UrlStyle is how the templates bucket appears in template URLs, virtual or path.
+	// By default the bucket is in the host name unless its name has dots.
+	S3UrlStyle s3.URLStyle `json:"S3UrlStyle,omitempty"`
+
+	// Defaults for the stacks that don't set their own
+	AllowReplacement       *bool    `json:"AllowReplacement,omitempty"`
+	ProtectedResourceTypes []string `json:"ProtectedResourceTypes,omitempty"`
 }
 
 func (manifest *Manifest) Parse(file string) error {
@@ -44,7 +60,7 @@ func (manifest *Manifest) Parse(file string) error {
 	defer jsonFile.Close()
 
 	byteValue, _ := ioutil.ReadAll(jsonFile)
-	err = json.Unmarshal(byteValue, &manifest)
+	err = manifest.Load(byteValue)
 	if err != nil {
 		return err
 	}
@@ -52,6 +68,17 @@ func (manifest *Manifest) Parse(file string) error {
 	return manifest.validateManifestFile()
 }
 
+// Load decodes a manifest and applies its defaults without validating it, stacks come out
+// as Parse returns them so that other revisions of a manifest can be compared
+func (manifest *Manifest) Load(b []byte) error {
+	err := json.Unmarshal(b, manifest)
+	if err != nil {
+		return err
+	}
+	manifest.applyDefaults()
+	return nil
+}
+
 func (manifest *Manifest) validateManifestFile() error {
 	resolver := endpoints.DefaultResolver()
 	partitions := resolver.(endpoints.EnumPartitions).Partitions()
@@ -61,8 +88,13 @@ func (manifest *Manifest) validateManifestFile() error {
 		return errors.Errorf("No Regions found")
 	}
 
+	err := manifest.S3UrlStyle.Validate()
+	if err != nil {
+		return err
+	}
+
 	validate := validator.New()
-	err := validate.Struct(manifest)
+	err = validate.Struct(manifest)
 	if err != nil {
 		glog.Errorf("Manifest validation error %v", err)
 		if _, ok := err.(*validator.InvalidValidationError); ok {
@@ -95,6 +127,11 @@ func (manifest *Manifest) validateManifestFile() error {
 			return errors.Errorf("%s is not a valid region", region.Name)
 		}
 
+		err = region.Bootstrap.Validate()
+		if err != nil {
+			return errors.Wrapf(err, "invalid bootstrap for region %s", region.Name)
+		}
+
 		for j, s := range region.Stacks {
 			err := validate.Struct(s)
 			if err != nil {
@@ -106,8 +143,44 @@ func (manifest *Manifest) validateManifestFile() error {
 					}
 				}
 			}
+
+			for _, dep := range s.DependsOn {
+				if dep == s.StackName {
+					return errors.Errorf("Stack %s in region %s depends on itself", s.StackName, region.Name)
+				}
+				if !region.hasStack(dep) {
+					return errors.Errorf("Stack %s in region %s depends on %s which is not defined in the region", s.StackName, region.Name, dep)
+				}
+			}
 		}
 	}
 
 	return nil
 }
+
+// applyDefaults copies the manifest wide replacement settings to stacks without their own,
+// the URL style to every stack and the manifest bootstrap to regions
+func (manifest *Manifest) applyDefaults() {
+	for i := range manifest.Regions {
+		manifest.Regions[i].Bootstrap = manifest.Bootstrap.Merge(manifest.Regions[i].Bootstrap)
+		for j := range manifest.Regions[i].Stacks {
+			s := &manifest.Regions[i].Stacks[j]
+			if s.AllowReplacement == nil {
+				s.AllowReplacement = manifest.AllowReplacement
+			}
+			if s.ProtectedResourceTypes == nil {
+				s.ProtectedResourceTypes = manifest.ProtectedResourceTypes
+			}
+			s.S3UrlStyle = manifest.S3UrlStyle
+		}
+	}
+}
+
+func (region *Region) hasStack(name string) bool {
+	for _, s := range region.Stacks {
+		if s.StackName == name {
+			return true
+		}
+	}
+	return false
+}
@@ -2,7 +2,13 @@ package manifest
 
 import (
 	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/s3"
+	"github.com/CleverTap/cfstack/internal/pkg/bootstrap"
+	"github.com/CleverTap/cfstack/internal/pkg/stack"
 	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -44,3 +50,170 @@ func TestParse(t *testing.T) {
 	}
 
 }
+
+func TestParseAppliesDefaults(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cfstack-manifest")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "manifest.json")
+	err = ioutil.WriteFile(file, []byte(`{
+		"AllowReplacement": false,
+		"ProtectedResourceTypes": ["AWS::RDS::DBInstance"],
+		"S3UrlStyle": "path",
+		"Regions": [{"Name": "eu-west-1", "Stacks": [
+			{"StackName": "Data", "TemplatePath": "data.json", "Action": "UPDATE", "StackPolicy": {}, "Parameters": {}},
+			{"StackName": "Web", "TemplatePath": "web.json", "Action": "UPDATE", "StackPolicy": {}, "Parameters": {},
+			 "AllowReplacement": true, "ProtectedResourceTypes": []}
+		]}]
+	}`), 0644)
+	require.NoError(t, err)
+
+	m := Manifest{}
+	require.NoError(t, m.Parse(file))
+
+	data, web := m.Regions[0].Stacks[0], m.Regions[0].Stacks[1]
+	require.False(t, *data.AllowReplacement)
+	require.Equal(t, []string{"AWS::RDS::DBInstance"}, data.ProtectedResourceTypes)
+	require.True(t, *web.AllowReplacement)
+	require.Empty(t, web.ProtectedResourceTypes)
+	require.Equal(t, s3.PathStyle, data.S3UrlStyle)
+	require.Equal(t, s3.PathStyle, web.S3UrlStyle)
+}
+
+func TestParseBootstrap(t *testing.T) {
+	testCases := map[string]struct {
+		manifest string
+		expected []bootstrap.Config
+		err      string
+	}{
+		"default": {
+			manifest: `{"Regions": [{"Name": "eu-west-1", "Stacks": []}]}`,
+			expected: []bootstrap.Config{{}},
+		},
+		"qualifier for every region": {
+			manifest: `{"Bootstrap": {"Qualifier": "payments"}, "Regions": [
+				{"Name": "eu-west-1", "Stacks": []},
+				{"Name": "us-east-1", "Stacks": [], "Bootstrap": {"TemplatesBucket": "templates", "SourceBucket": "source"}}
+			]}`,
+			expected: []bootstrap.Config{
+				{Qualifier: "payments"},
+				{TemplatesBucket: "templates", SourceBucket: "source"},
+			},
+		},
+		"invalid": {
+			manifest: `{"Regions": [{"Name": "eu-west-1", "Stacks": [], "Bootstrap": {"TemplatesBucket": "templates"}}]}`,
+			err:      "invalid bootstrap for region eu-west-1: TemplatesBucket and SourceBucket must be given together",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "cfstack-manifest")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			file := filepath.Join(dir, "manifest.json")
+			require.NoError(t, ioutil.WriteFile(file, []byte(tc.manifest), 0644))
+
+			m := Manifest{}
+			err = m.Parse(file)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			for i, expected := range tc.expected {
+				require.Equal(t, expected, m.Regions[i].Bootstrap)
+			}
+		})
+	}
+}
+
+func TestSelect(t *testing.T) {
+	bootstraps := map[string]bootstrap.Config{
+		"eu-west-1": {StackName: "cfstack-Init-Data", Qualifier: "data"},
+		"us-east-1": {TemplatesBucket: "templates-us", SourceBucket: "source-us"},
+	}
+	newManifest := func() Manifest {
+		return Manifest{
+			Regions: []Region{
+				{
+					Name:      "eu-west-1",
+					Bootstrap: bootstraps["eu-west-1"],
+					Stacks: []stack.Stack{
+						{StackName: "Network"},
+						{StackName: "Data-Tables", DependsOn: []string{"Network"}, Tags: map[string]string{"team": "data"}},
+						{StackName: "Data-Api", DependsOn: []string{"Data-Tables"}, Tags: map[string]string{"team": "data"}},
+						{StackName: "Web", DependsOn: []string{"Network"}, Tags: map[string]string{"team": "web"}},
+					},
+				},
+				{
+					Name:      "us-east-1",
+					Bootstrap: bootstraps["us-east-1"],
+					Stacks: []stack.Stack{
+						{StackName: "Data-Tables", Tags: map[string]string{"team": "data"}},
+					},
+				},
+			},
+		}
+	}
+
+	testCases := map[string]struct {
+		selector      Selector
+		expected      map[string][]string
+		expectedError string
+	}{
+		"region": {
+			selector: Selector{Regions: []string{"us-east-1"}},
+			expected: map[string][]string{"us-east-1": {"Data-Tables"}},
+		},
+		"stack glob with exclusion": {
+			selector: Selector{Stacks: []string{"Data-*"}, ExcludeStacks: []string{"*-Api"}},
+			expected: map[string][]string{"eu-west-1": {"Data-Tables"}, "us-east-1": {"Data-Tables"}},
+		},
+		"tag with dependencies": {
+			selector: Selector{Regions: []string{"eu-west-1"}, Tags: []string{"team=data"}, WithDependencies: true},
+			expected: map[string][]string{"eu-west-1": {"Network", "Data-Tables", "Data-Api"}},
+		},
+		"excluded dependency": {
+			selector: Selector{Stacks: []string{"Web"}, ExcludeStacks: []string{"Network"}, WithDependencies: true},
+			expected: map[string][]string{"eu-west-1": {"Web"}},
+		},
+		"unknown region": {
+			selector:      Selector{Regions: []string{"ap-south-1"}},
+			expectedError: "region ap-south-1 was not found in manifest",
+		},
+		"invalid tag": {
+			selector:      Selector{Tags: []string{"team"}},
+			expectedError: "invalid tag selector team, expected key=value",
+		},
+		"nothing selected": {
+			selector:      Selector{Stacks: []string{"Missing"}},
+			expectedError: "No stacks in manifest match the selection",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			m := newManifest()
+
+			err := m.Select(&tc.selector)
+
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+
+			selected := map[string][]string{}
+			for _, region := range m.Regions {
+				require.Equal(t, bootstraps[region.Name], region.Bootstrap)
+				for _, s := range region.Stacks {
+					selected[region.Name] = append(selected[region.Name], s.StackName)
+				}
+			}
+			require.Equal(t, tc.expected, selected)
+		})
+	}
+}
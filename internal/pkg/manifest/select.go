@@ -0,0 +1,170 @@
+package manifest
+
+import (
+	"github.com/CleverTap/cfstack/internal/pkg/stack"
+	"github.com/pkg/errors"
+	"path"
+	"strings"
+)
+
+// Selector narrows a parsed manifest down to the regions and stacks a command should work on
+type Selector struct {
+	Regions          []string
+	Stacks           []string
+	ExcludeStacks    []string
+	Tags             []string
+	WithDependencies bool
+}
+
+func (sel *Selector) IsEmpty() bool {
+	return len(sel.Regions) == 0 && len(sel.Stacks) == 0 && len(sel.ExcludeStacks) == 0 && len(sel.Tags) == 0
+}
+
+// Select drops every region and stack that doesn't match the selector. Stacks keep their
+// manifest order. With WithDependencies the upstream stacks of every selected stack are
+// kept as well, unless they are explicitly excluded.
+func (manifest *Manifest) Select(sel *Selector) error {
+	if sel == nil || sel.IsEmpty() {
+		return nil
+	}
+
+	tags, err := parseTags(sel.Tags)
+	if err != nil {
+		return err
+	}
+
+	for _, pattern := range append(append([]string{}, sel.Stacks...), sel.ExcludeStacks...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.Errorf("invalid stack pattern %s", pattern)
+		}
+	}
+
+	for _, name := range sel.Regions {
+		found := false
+		for _, region := range manifest.Regions {
+			if region.Name == name {
+				found = true
+			}
+		}
+		if !found {
+			return errors.Errorf("region %s was not found in manifest", name)
+		}
+	}
+
+	regions := make([]Region, 0)
+
+	for _, region := range manifest.Regions {
+		if len(sel.Regions) > 0 && !contains(sel.Regions, region.Name) {
+			continue
+		}
+
+		selected := map[string]bool{}
+		for _, s := range region.Stacks {
+			if matchesAny(sel.Stacks, s.StackName, true) && hasTags(s, tags) && !matchesAny(sel.ExcludeStacks, s.StackName, false) {
+				selected[s.StackName] = true
+			}
+		}
+
+		if sel.WithDependencies {
+			addDependencies(region.Stacks, selected, sel.ExcludeStacks)
+		}
+
+		stacks := make([]stack.Stack, 0)
+		for _, s := range region.Stacks {
+			if selected[s.StackName] {
+				stacks = append(stacks, s)
+			}
+		}
+
+		if len(stacks) > 0 {
+			region.Stacks = stacks
+			regions = append(regions, region)
+		}
+	}
+
+	if len(regions) == 0 {
+		return errors.Errorf("No stacks in manifest match the selection")
+	}
+
+	manifest.Regions = regions
+	return nil
+}
+
+// StackCount returns the number of stacks across all regions
+func (manifest *Manifest) StackCount() int {
+	n := 0
+	for _, region := range manifest.Regions {
+		n += len(region.Stacks)
+	}
+	return n
+}
+
+func addDependencies(stacks []stack.Stack, selected map[string]bool, exclude []string) {
+	byName := map[string]stack.Stack{}
+	for _, s := range stacks {
+		byName[s.StackName] = s
+	}
+
+	queue := make([]string, 0, len(selected))
+	for _, s := range stacks {
+		if selected[s.StackName] {
+			queue = append(queue, s.StackName)
+		}
+	}
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for _, dep := range byName[name].DependsOn {
+			if selected[dep] || matchesAny(exclude, dep, false) {
+				continue
+			}
+			selected[dep] = true
+			queue = append(queue, dep)
+		}
+	}
+}
+
+func parseTags(tags []string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, t := range tags {
+		kv := strings.SplitN(t, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, errors.Errorf("invalid tag selector %s, expected key=value", t)
+		}
+		out[kv[0]] = kv[1]
+	}
+	return out, nil
+}
+
+func hasTags(s stack.Stack, tags map[string]string) bool {
+	for k, v := range tags {
+		if value, ok := s.Tags[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesAny reports whether name matches one of the glob patterns, or
+// returns emptyResult when there are no patterns at all
+func matchesAny(patterns []string, name string, emptyResult bool) bool {
+	if len(patterns) == 0 {
+		return emptyResult
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
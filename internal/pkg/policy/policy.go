@@ -0,0 +1,576 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/sts"
+	"github.com/CleverTap/cfstack/internal/pkg/templates"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Rule is an org guardrail evaluated against every resource of a template. A resource
+// violates the rule when it matches ResourceTypes, Actions and the account selectors
+// and any of the assertions fails. A rule without assertions forbids the match itself.
+type Rule struct {
+	Id             string      `json:"Id"`
+	Description    string      `json:"Description"`
+	ResourceTypes  []string    `json:"ResourceTypes"`
+	Actions        []string    `json:"Actions,omitempty"`
+	Accounts       []string    `json:"Accounts,omitempty"`
+	ExceptAccounts []string    `json:"ExceptAccounts,omitempty"`
+	Assert         []Assertion `json:"Assert,omitempty"`
+}
+
+// Assertion checks the values found at Path, a dot separated path in the resource
+// definition where [*] walks every item of a list, e.g. Properties.PolicyDocument.Statement[*].Action
+type Assertion struct {
+	Path        string        `json:"Path"`
+	Exists      *bool         `json:"Exists,omitempty"`
+	Equals      interface{}   `json:"Equals,omitempty"`
+	NotEquals   interface{}   `json:"NotEquals,omitempty"`
+	In          []interface{} `json:"In,omitempty"`
+	NotContains interface{}   `json:"NotContains,omitempty"`
+	Matches     string        `json:"Matches,omitempty"`
+
+	matches *regexp.Regexp
+}
+
+// Waiver lets a stack violate a rule, optionally for a single resource. Justification
+// is mandatory and shows up in every report.
+type Waiver struct {
+	Rule          string `json:"Rule"`
+	Resource      string `json:"Resource,omitempty"`
+	Justification string `json:"Justification"`
+}
+
+type Violation struct {
+	Rule          string `json:"Rule"`
+	Description   string `json:"Description,omitempty"`
+	Resource      string `json:"Resource"`
+	ResourceType  string `json:"ResourceType"`
+	Action        string `json:"Action,omitempty"`
+	Message       string `json:"Message"`
+	Waived        bool   `json:"Waived"`
+	Justification string `json:"Justification,omitempty"`
+}
+
+// Input is a template with its resolved parameters and, when known, its change set
+type Input struct {
+	Account    string
+	Region     string
+	StackName  string
+	Template   templates.Template
+	Parameters map[string]string
+	// Changes is nil when the stack is created, every resource is then added
+	Changes []cloudformation.ChangeResource
+	Waivers []Waiver
+}
+
+type file struct {
+	Rules []Rule `json:"Rules"`
+}
+
+type Set struct {
+	Rules []Rule
+
+	accountOnce sync.Once
+	account     string
+	accountErr  error
+}
+
+// Load reads every .json, .yaml and .yml file of dir. A missing directory is an
+// empty set so that policies are opt-in.
+func Load(dir string) (*Set, error) {
+	s := &Set{}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+
+	ids := map[string]string{}
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.IsDir() || (ext != ".json" && ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+
+		p := filepath.Join(dir, e.Name())
+		rules, err := parseFile(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rules {
+			if other, ok := ids[r.Id]; ok {
+				return nil, errors.Errorf("policy rule %s is defined in %s and %s", r.Id, other, e.Name())
+			}
+			ids[r.Id] = e.Name()
+			s.Rules = append(s.Rules, r)
+		}
+	}
+
+	sort.Slice(s.Rules, func(i, j int) bool {
+		return s.Rules[i].Id < s.Rules[j].Id
+	})
+	return s, nil
+}
+
+func parseFile(p string) ([]Rule, error) {
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	// YAML is a superset of JSON, going through JSON gives the same value types for both
+	var doc interface{}
+	err = yaml.Unmarshal(b, &doc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid policy file %s", filepath.Base(p))
+	}
+	j, err := json.Marshal(doc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid policy file %s", filepath.Base(p))
+	}
+	f := &file{}
+	err = json.Unmarshal(j, f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid policy file %s", filepath.Base(p))
+	}
+
+	for i := range f.Rules {
+		r := &f.Rules[i]
+		if r.Id == "" {
+			return nil, errors.Errorf("rule %d in policy file %s has no Id", i, filepath.Base(p))
+		}
+		if len(r.ResourceTypes) == 0 {
+			return nil, errors.Errorf("policy rule %s has no ResourceTypes", r.Id)
+		}
+		for k := range r.Assert {
+			a := &r.Assert[k]
+			if a.Path == "" {
+				return nil, errors.Errorf("assertion %d of policy rule %s has no Path", k, r.Id)
+			}
+			if a.Matches != "" {
+				a.matches, err = regexp.Compile(a.Matches)
+				if err != nil {
+					return nil, errors.Wrapf(err, "invalid Matches in policy rule %s", r.Id)
+				}
+			}
+		}
+	}
+	return f.Rules, nil
+}
+
+func (s *Set) IsEmpty() bool {
+	return s == nil || len(s.Rules) == 0
+}
+
+func (s *Set) hasRule(id string) bool {
+	for _, r := range s.Rules {
+		if r.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckWaivers makes sure every waiver names a known rule and is justified
+func (s *Set) CheckWaivers(stackName string, waivers []Waiver) error {
+	for _, w := range waivers {
+		if strings.TrimSpace(w.Justification) == "" {
+			return errors.Errorf("policy waiver for rule %s in stack %s has no Justification", w.Rule, stackName)
+		}
+		if !s.hasRule(w.Rule) {
+			return errors.Errorf("policy waiver in stack %s refers to unknown rule %s", stackName, w.Rule)
+		}
+	}
+	return nil
+}
+
+// Account returns the account of the session when a rule is limited to some accounts.
+// It is looked up once per run.
+func (s *Set) Account(sess *session.Session) (string, error) {
+	if s.IsEmpty() {
+		return "", nil
+	}
+
+	needed := false
+	for _, r := range s.Rules {
+		if len(r.Accounts) > 0 || len(r.ExceptAccounts) > 0 {
+			needed = true
+		}
+	}
+	if !needed {
+		return "", nil
+	}
+
+	s.accountOnce.Do(func() {
+		s.account, s.accountErr = sts.New(sess).GetAccountId()
+	})
+	return s.account, s.accountErr
+}
+
+// Evaluate returns every violation of the template, waived ones included
+func (s *Set) Evaluate(in Input) []Violation {
+	if s.IsEmpty() {
+		return nil
+	}
+
+	actions := map[string]string{}
+	for _, c := range in.Changes {
+		actions[c.Name] = c.Action
+	}
+
+	resources := in.Template.Section("Resources")
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+	// Removed resources are only in the change set
+	for _, c := range in.Changes {
+		if _, ok := resources[c.Name]; !ok && c.Action == "Remove" {
+			names = append(names, c.Name)
+		}
+	}
+	sort.Strings(names)
+
+	substitute := refValues(in)
+
+	var violations []Violation
+	for _, name := range names {
+		definition, _ := resources[name].(map[string]interface{})
+		resourceType, _ := definition["Type"].(string)
+		action := actions[name]
+		if in.Changes == nil {
+			action = "Add"
+		}
+		if _, ok := resources[name]; !ok {
+			definition = map[string]interface{}{}
+			action = "Remove"
+			for _, c := range in.Changes {
+				if c.Name == name {
+					resourceType = c.Type
+				}
+			}
+		}
+
+		resolved := substituteRefs(definition, substitute)
+
+		for _, r := range s.Rules {
+			if !r.applies(resourceType, action, in.Account) {
+				continue
+			}
+			msg, ok := r.check(resolved)
+			if ok {
+				continue
+			}
+			v := Violation{
+				Rule:         r.Id,
+				Description:  r.Description,
+				Resource:     name,
+				ResourceType: resourceType,
+				Action:       action,
+				Message:      msg,
+			}
+			if w, waived := waiverFor(in.Waivers, r.Id, name); waived {
+				v.Waived = true
+				v.Justification = w.Justification
+			}
+			violations = append(violations, v)
+		}
+	}
+	return violations
+}
+
+// Blocking returns the violations that are not waived
+func Blocking(violations []Violation) []Violation {
+	var out []Violation
+	for _, v := range violations {
+		if !v.Waived {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Error lists blocking violations of a stack
+func Error(stackName string, violations []Violation) error {
+	blocking := Blocking(violations)
+	if len(blocking) == 0 {
+		return nil
+	}
+	lines := make([]string, 0, len(blocking))
+	for _, v := range blocking {
+		lines = append(lines, v.String())
+	}
+	return errors.Errorf("stack %s violates %d policy rule(s):\n    %s", stackName, len(blocking), strings.Join(lines, "\n    "))
+}
+
+func (v Violation) String() string {
+	s := fmt.Sprintf("%s %s (%s): %s", v.Rule, v.Resource, v.ResourceType, v.Message)
+	if v.Waived {
+		s += fmt.Sprintf(" [waived: %s]", v.Justification)
+	}
+	return s
+}
+
+func waiverFor(waivers []Waiver, rule string, resource string) (Waiver, bool) {
+	for _, w := range waivers {
+		if w.Rule == rule && (w.Resource == "" || w.Resource == resource) {
+			return w, true
+		}
+	}
+	return Waiver{}, false
+}
+
+func (r Rule) applies(resourceType string, action string, account string) bool {
+	if !matchesAny(r.ResourceTypes, resourceType) {
+		return false
+	}
+	if len(r.Actions) > 0 && !contains(r.Actions, action) {
+		return false
+	}
+	if len(r.Actions) == 0 && action == "Remove" {
+		return false
+	}
+	if len(r.Accounts) > 0 && !contains(r.Accounts, account) {
+		return false
+	}
+	if contains(r.ExceptAccounts, account) {
+		return false
+	}
+	return true
+}
+
+// check returns the reason of the first failed assertion
+func (r Rule) check(resource map[string]interface{}) (string, bool) {
+	if len(r.Assert) == 0 {
+		if r.Description != "" {
+			return r.Description, false
+		}
+		return "not allowed", false
+	}
+	for _, a := range r.Assert {
+		if msg, ok := a.check(lookup(resource, a.Path)); !ok {
+			return msg, false
+		}
+	}
+	return "", true
+}
+
+func (a Assertion) check(found []interface{}) (string, bool) {
+	if a.Exists != nil {
+		if *a.Exists && len(found) == 0 {
+			return fmt.Sprintf("%s is not set", a.Path), false
+		}
+		if !*a.Exists && len(found) > 0 {
+			return fmt.Sprintf("%s must not be set", a.Path), false
+		}
+	}
+
+	if a.Equals != nil {
+		if len(found) == 0 {
+			return fmt.Sprintf("%s is not set, must be %s", a.Path, format(a.Equals)), false
+		}
+		for _, v := range found {
+			if !equal(v, a.Equals) {
+				return fmt.Sprintf("%s is %s, must be %s", a.Path, format(v), format(a.Equals)), false
+			}
+		}
+	}
+
+	if a.NotEquals != nil {
+		for _, v := range found {
+			if equal(v, a.NotEquals) {
+				return fmt.Sprintf("%s must not be %s", a.Path, format(a.NotEquals)), false
+			}
+		}
+	}
+
+	if len(a.In) > 0 {
+		if len(found) == 0 {
+			return fmt.Sprintf("%s is not set", a.Path), false
+		}
+		for _, v := range found {
+			allowed := false
+			for _, in := range a.In {
+				if equal(v, in) {
+					allowed = true
+				}
+			}
+			if !allowed {
+				return fmt.Sprintf("%s is %s, must be one of %s", a.Path, format(v), format(a.In)), false
+			}
+		}
+	}
+
+	if a.NotContains != nil {
+		for _, v := range found {
+			items, isList := v.([]interface{})
+			if !isList {
+				items = []interface{}{v}
+			}
+			for _, item := range items {
+				if equal(item, a.NotContains) {
+					return fmt.Sprintf("%s contains %s", a.Path, format(a.NotContains)), false
+				}
+			}
+		}
+	}
+
+	if a.matches != nil {
+		if len(found) == 0 {
+			return fmt.Sprintf("%s is not set", a.Path), false
+		}
+		for _, v := range found {
+			if s, ok := v.(string); !ok || !a.matches.MatchString(s) {
+				return fmt.Sprintf("%s is %s, must match %s", a.Path, format(v), a.Matches), false
+			}
+		}
+	}
+
+	return "", true
+}
+
+// lookup returns every value found at a path, [*] expands lists
+func lookup(v interface{}, p string) []interface{} {
+	current := []interface{}{v}
+	for _, part := range strings.Split(p, ".") {
+		wildcard := strings.HasSuffix(part, "[*]")
+		key := strings.TrimSuffix(part, "[*]")
+
+		var next []interface{}
+		for _, c := range current {
+			m, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			child, ok := m[key]
+			if !ok {
+				continue
+			}
+			if wildcard {
+				if l, ok := child.([]interface{}); ok {
+					next = append(next, l...)
+				}
+				continue
+			}
+			next = append(next, child)
+		}
+		current = next
+	}
+	return current
+}
+
+// refValues maps the names a Ref can point to onto the values known before deploy
+func refValues(in Input) map[string]string {
+	values := map[string]string{}
+	for name, p := range in.Template.Section("Parameters") {
+		if def, ok := p.(map[string]interface{})["Default"]; ok {
+			values[name] = format(def)
+		}
+	}
+	for k, v := range in.Parameters {
+		values[k] = v
+	}
+	if in.Region != "" {
+		values["AWS::Region"] = in.Region
+	}
+	if in.Account != "" {
+		values["AWS::AccountId"] = in.Account
+	}
+	if in.StackName != "" {
+		values["AWS::StackName"] = in.StackName
+	}
+	return values
+}
+
+// substituteRefs returns a copy of v with Refs to known values replaced by the value
+func substituteRefs(v interface{}, values map[string]string) map[string]interface{} {
+	out, _ := substitute(v, values).(map[string]interface{})
+	return out
+}
+
+func substitute(v interface{}, values map[string]string) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if ref, ok := t["Ref"].(string); ok && len(t) == 1 {
+			if value, known := values[ref]; known {
+				return value
+			}
+		}
+		out := make(map[string]interface{}, len(t))
+		for k, item := range t {
+			out[k] = substitute(item, values)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, item := range t {
+			out[i] = substitute(item, values)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// equal compares scalars by their text so that "true" in a template matches true in a rule
+func equal(a interface{}, b interface{}) bool {
+	if isScalar(a) && isScalar(b) {
+		return format(a) == format(b)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case string, bool, float64:
+		return true
+	}
+	return false
+}
+
+func format(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func matchesAny(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package policy
+
+import (
+	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"github.com/CleverTap/cfstack/internal/pkg/templates"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const rulesYAML = `
+Rules:
+  - Id: s3-no-public-access
+    Description: S3 buckets must not be public
+    ResourceTypes: [AWS::S3::Bucket]
+    Assert:
+      - Path: Properties.AccessControl
+        NotEquals: PublicRead
+  - Id: rds-encrypted
+    ResourceTypes: ["AWS::RDS::*"]
+    Assert:
+      - Path: Properties.StorageEncrypted
+        Equals: true
+  - Id: iam-no-wildcard-actions
+    ResourceTypes: [AWS::IAM::Policy, AWS::IAM::Role]
+    ExceptAccounts: ["111111111111"]
+    Assert:
+      - Path: Properties.PolicyDocument.Statement[*].Action
+        NotContains: "*"
+`
+
+const rulesJSON = `{"Rules": [{"Id": "no-table-removal", "Description": "DynamoDB tables must not be removed", "ResourceTypes": ["AWS::DynamoDB::Table"], "Actions": ["Remove"]}]}`
+
+const template = `
+Parameters:
+  Acl:
+    Type: String
+    Default: Private
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      AccessControl: !Ref Acl
+  Database:
+    Type: AWS::RDS::DBInstance
+    Properties:
+      StorageEncrypted: "true"
+  Policy:
+    Type: AWS::IAM::Policy
+    Properties:
+      PolicyDocument:
+        Statement:
+          - Effect: Allow
+            Action: s3:GetObject
+          - Effect: Allow
+            Action: ["sqs:*", "*"]
+`
+
+func loadSet(t *testing.T) *Set {
+	dir, err := ioutil.TempDir("", "cfstack-policy")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "guardrails.yaml"), []byte(rulesYAML), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "tables.json"), []byte(rulesJSON), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("not a policy"), 0644))
+
+	s, err := Load(dir)
+	require.NoError(t, err)
+	return s
+}
+
+func TestLoad(t *testing.T) {
+	s := loadSet(t)
+	ids := make([]string, 0, len(s.Rules))
+	for _, r := range s.Rules {
+		ids = append(ids, r.Id)
+	}
+	require.Equal(t, []string{"iam-no-wildcard-actions", "no-table-removal", "rds-encrypted", "s3-no-public-access"}, ids)
+
+	missing, err := Load(filepath.Join(os.TempDir(), "cfstack-no-such-policies"))
+	require.NoError(t, err)
+	require.True(t, missing.IsEmpty())
+
+	dir, err := ioutil.TempDir("", "cfstack-policy")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "bad.json"), []byte(`{"Rules": [{"Id": "no-types"}]}`), 0644))
+	_, err = Load(dir)
+	require.EqualError(t, err, "policy rule no-types has no ResourceTypes")
+}
+
+func TestCheckWaivers(t *testing.T) {
+	s := loadSet(t)
+
+	testCases := map[string]struct {
+		waivers []Waiver
+		err     string
+	}{
+		"justified": {
+			waivers: []Waiver{{Rule: "rds-encrypted", Justification: "legacy database"}},
+		},
+		"no justification": {
+			waivers: []Waiver{{Rule: "rds-encrypted"}},
+			err:     "policy waiver for rule rds-encrypted in stack Sample has no Justification",
+		},
+		"unknown rule": {
+			waivers: []Waiver{{Rule: "rds-backups", Justification: "legacy database"}},
+			err:     "policy waiver in stack Sample refers to unknown rule rds-backups",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := s.CheckWaivers("Sample", tc.waivers)
+			if tc.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	s := loadSet(t)
+	tmpl, err := templates.ParseTemplate([]byte(template))
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		input    Input
+		expected []string
+	}{
+		"parameter defaults are used": {
+			input:    Input{Template: tmpl},
+			expected: []string{"iam-no-wildcard-actions Policy (AWS::IAM::Policy): Properties.PolicyDocument.Statement[*].Action contains *"},
+		},
+		"resolved parameters win over defaults": {
+			input: Input{Template: tmpl, Parameters: map[string]string{"Acl": "PublicRead"}},
+			expected: []string{
+				"s3-no-public-access Bucket (AWS::S3::Bucket): Properties.AccessControl must not be PublicRead",
+				"iam-no-wildcard-actions Policy (AWS::IAM::Policy): Properties.PolicyDocument.Statement[*].Action contains *",
+			},
+		},
+		"excepted account": {
+			input: Input{Template: tmpl, Account: "111111111111"},
+		},
+		"waived for a resource": {
+			input: Input{Template: tmpl, Waivers: []Waiver{{Rule: "iam-no-wildcard-actions", Resource: "Policy", Justification: "break glass"}}},
+			expected: []string{
+				"iam-no-wildcard-actions Policy (AWS::IAM::Policy): Properties.PolicyDocument.Statement[*].Action contains * [waived: break glass]",
+			},
+		},
+		"removed resources match change set actions": {
+			input: Input{
+				Template: tmpl,
+				Account:  "111111111111",
+				Changes: []cloudformation.ChangeResource{
+					{Name: "Bucket", Type: "AWS::S3::Bucket", Action: "Modify"},
+					{Name: "Orders", Type: "AWS::DynamoDB::Table", Action: "Remove"},
+				},
+			},
+			expected: []string{"no-table-removal Orders (AWS::DynamoDB::Table): DynamoDB tables must not be removed"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var out []string
+			for _, v := range s.Evaluate(tc.input) {
+				out = append(out, v.String())
+			}
+			require.ElementsMatch(t, tc.expected, out)
+		})
+	}
+}
+
+func TestBlocking(t *testing.T) {
+	violations := []Violation{
+		{Rule: "rds-encrypted", Resource: "Database", ResourceType: "AWS::RDS::DBInstance", Message: "Properties.StorageEncrypted is not set"},
+		{Rule: "s3-no-public-access", Resource: "Bucket", Waived: true, Justification: "static website"},
+	}
+
+	require.Len(t, Blocking(violations), 1)
+	require.EqualError(t, Error("Sample", violations),
+		"stack Sample violates 1 policy rule(s):\n    rds-encrypted Database (AWS::RDS::DBInstance): Properties.StorageEncrypted is not set")
+	require.NoError(t, Error("Sample", violations[1:]))
+}
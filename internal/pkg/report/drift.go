@@ -0,0 +1,190 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"github.com/pkg/errors"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+type DriftReport struct {
+	Regions []DriftRegion `json:"Regions"`
+}
+
+type DriftRegion struct {
+	Name   string       `json:"Name"`
+	Stacks []DriftStack `json:"Stacks"`
+}
+
+type DriftStack struct {
+	StackName string          `json:"StackName"`
+	Status    string          `json:"Status"`
+	Resources []DriftResource `json:"Resources"`
+}
+
+type DriftResource struct {
+	Name        string          `json:"Name"`
+	Type        string          `json:"Type"`
+	PhysicalId  string          `json:"PhysicalId,omitempty"`
+	Status      string          `json:"Status"`
+	Differences []DriftProperty `json:"Differences,omitempty"`
+}
+
+type DriftProperty struct {
+	Path     string `json:"Path"`
+	Type     string `json:"Type"`
+	Expected string `json:"Expected"`
+	Actual   string `json:"Actual"`
+}
+
+// NewDrift builds a drift report keyed by region name. Regions are sorted by name and
+// resources by logical ID, stacks keep the order of the manifest.
+func NewDrift(regions map[string][]cloudformation.StackDrift) *DriftReport {
+	r := &DriftReport{Regions: make([]DriftRegion, 0, len(regions))}
+
+	for name, stacks := range regions {
+		out := DriftRegion{Name: name, Stacks: make([]DriftStack, 0, len(stacks))}
+		for _, s := range stacks {
+			ds := DriftStack{StackName: s.StackName, Status: s.Status, Resources: []DriftResource{}}
+			for _, res := range s.Resources {
+				dr := DriftResource{Name: res.Name, Type: res.Type, PhysicalId: res.PhysicalId, Status: res.Status}
+				for _, d := range res.Differences {
+					dr.Differences = append(dr.Differences, DriftProperty(d))
+				}
+				ds.Resources = append(ds.Resources, dr)
+			}
+			sort.Slice(ds.Resources, func(i, j int) bool {
+				return ds.Resources[i].Name < ds.Resources[j].Name
+			})
+			out.Stacks = append(out.Stacks, ds)
+		}
+		r.Regions = append(r.Regions, out)
+	}
+
+	sort.Slice(r.Regions, func(i, j int) bool {
+		return r.Regions[i].Name < r.Regions[j].Name
+	})
+	return r
+}
+
+// Drifted reports whether any stack drifted
+func (r *DriftReport) Drifted() bool {
+	for _, region := range r.Regions {
+		for _, s := range region.Stacks {
+			if s.Status == cloudformation.DriftStatusDrifted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *DriftReport) Render(w io.Writer, format string) error {
+	switch format {
+	case FormatJSON:
+		b, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	case FormatMarkdown:
+		return r.renderMarkdown(w)
+	case FormatTable:
+		return r.renderTable(w)
+	default:
+		return errors.Errorf("unknown format %s, must be one of %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+func (r *DriftReport) renderMarkdown(w io.Writer) error {
+	b := &strings.Builder{}
+
+	b.WriteString("## cfstack drift\n\n")
+
+	if !r.Drifted() {
+		b.WriteString("No drift detected.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	for _, region := range r.Regions {
+		fmt.Fprintf(b, "### %s\n\n", region.Name)
+		b.WriteString("| Stack | Status | Drifted resources |\n")
+		b.WriteString("|---|---|---:|\n")
+		for _, s := range region.Stacks {
+			status := s.Status
+			if s.Status == cloudformation.DriftStatusDrifted {
+				status = "⚠️ " + s.Status
+			}
+			fmt.Fprintf(b, "| %s | %s | %d |\n", s.StackName, status, len(s.Resources))
+		}
+		b.WriteString("\n")
+
+		for _, s := range region.Stacks {
+			if len(s.Resources) == 0 {
+				continue
+			}
+			fmt.Fprintf(b, "<details><summary>%s</summary>\n\n", s.StackName)
+			b.WriteString("| Resource | Type | Status | Property | Expected | Actual |\n")
+			b.WriteString("|---|---|---|---|---|---|\n")
+			for _, res := range s.Resources {
+				if len(res.Differences) == 0 {
+					fmt.Fprintf(b, "| %s | %s | %s |  |  |  |\n", res.Name, res.Type, res.Status)
+					continue
+				}
+				for _, d := range res.Differences {
+					fmt.Fprintf(b, "| %s | %s | %s | %s | %s | %s |\n", res.Name, res.Type, res.Status,
+						inline(d.Path), code(d.Expected), code(d.Actual))
+				}
+			}
+			b.WriteString("\n</details>\n\n")
+		}
+	}
+
+	_, err := io.WriteString(w, strings.TrimRight(b.String(), "\n")+"\n")
+	return err
+}
+
+func (r *DriftReport) renderTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "REGION\tSTACK\tSTATUS\tRESOURCE\tTYPE\tPROPERTY\tEXPECTED\tACTUAL")
+
+	for _, region := range r.Regions {
+		for _, s := range region.Stacks {
+			if len(s.Resources) == 0 {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t-\t-\t-\t-\t-\n", region.Name, s.StackName, s.Status)
+			}
+			for _, res := range s.Resources {
+				if len(res.Differences) == 0 {
+					fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t-\t-\t-\n", region.Name, s.StackName, res.Status, res.Name, res.Type)
+				}
+				for _, d := range res.Differences {
+					fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", region.Name, s.StackName, res.Status, res.Name, res.Type,
+						d.Path, oneLine(d.Expected), oneLine(d.Actual))
+				}
+			}
+		}
+	}
+
+	return tw.Flush()
+}
+
+// code shows a property value as inline code in a markdown table
+func code(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + strings.Replace(inline(s), "`", "'", -1) + "`"
+}
+
+func oneLine(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return strings.Replace(strings.Replace(s, "\r", "", -1), "\n", " ", -1)
+}
@@ -0,0 +1,69 @@
+package report
+
+import (
+	"bytes"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRenderDriftMarkdown(t *testing.T) {
+	testCases := map[string]struct {
+		regions  map[string][]cloudformation.StackDrift
+		expected string
+	}{
+		"in sync": {
+			regions: map[string][]cloudformation.StackDrift{
+				"eu-west-1": {{StackName: "Network", Status: cloudformation.DriftStatusInSync}},
+			},
+			expected: "## cfstack drift\n\nNo drift detected.\n",
+		},
+		"drifted": {
+			regions: map[string][]cloudformation.StackDrift{
+				"eu-west-1": {
+					{StackName: "Network", Status: cloudformation.DriftStatusInSync},
+					{
+						StackName: "Sample-Bucket",
+						Status:    cloudformation.DriftStatusDrifted,
+						Resources: []cloudformation.ResourceDrift{
+							{Name: "Queue", Type: "AWS::SQS::Queue", Status: "DELETED"},
+							{Name: "S3Bucket", Type: "AWS::S3::Bucket", Status: "MODIFIED", Differences: []cloudformation.PropertyDifference{
+								{Path: "/VersioningConfiguration/Status", Type: "NOT_EQUAL", Expected: "Enabled", Actual: "Suspended"},
+							}},
+						},
+					},
+				},
+			},
+			expected: `## cfstack drift
+
+### eu-west-1
+
+| Stack | Status | Drifted resources |
+|---|---|---:|
+| Network | IN_SYNC | 0 |
+| Sample-Bucket | ⚠️ DRIFTED | 2 |
+
+<details><summary>Sample-Bucket</summary>
+
+| Resource | Type | Status | Property | Expected | Actual |
+|---|---|---|---|---|---|
+| Queue | AWS::SQS::Queue | DELETED |  |  |  |
+| S3Bucket | AWS::S3::Bucket | MODIFIED | /VersioningConfiguration/Status | ` + "`Enabled`" + ` | ` + "`Suspended`" + ` |
+
+</details>
+`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			r := NewDrift(tc.regions)
+			err := r.Render(out, FormatMarkdown)
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, out.String())
+			require.Equal(t, name == "drifted", r.Drifted())
+		})
+	}
+}
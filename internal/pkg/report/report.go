@@ -0,0 +1,337 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"github.com/CleverTap/cfstack/internal/pkg/manifest"
+	"github.com/CleverTap/cfstack/internal/pkg/policy"
+	"github.com/CleverTap/cfstack/internal/pkg/secrets"
+	"github.com/CleverTap/cfstack/internal/pkg/stack"
+	"github.com/pkg/errors"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	FormatJSON     = "json"
+	FormatMarkdown = "markdown"
+	FormatTable    = "table"
+)
+
+// Formats lists the output formats accepted by Render
+var Formats = []string{FormatJSON, FormatMarkdown, FormatTable}
+
+// actionOrder is the order in which resource changes are grouped in every format
+var actionOrder = []string{"Add", "Modify", "Remove", "Import", "Dynamic"}
+
+type Report struct {
+	Regions []Region `json:"Regions"`
+}
+
+type Region struct {
+	Name   string  `json:"Name"`
+	Stacks []Stack `json:"Stacks"`
+}
+
+type Stack struct {
+	StackName         string                          `json:"StackName"`
+	Status            string                          `json:"Status"`
+	StatusReason      string                          `json:"StatusReason,omitempty"`
+	StackPolicyChange bool                            `json:"StackPolicyChange"`
+	ForceStackUpdate  bool                            `json:"ForceStackUpdate"`
+	Drifted           bool                            `json:"Drifted,omitempty"`
+	Resources         []cloudformation.ChangeResource `json:"Resources"`
+	PolicyViolations  []policy.Violation              `json:"PolicyViolations,omitempty"`
+}
+
+// New builds a report out of diff results. Regions are sorted by name, stacks keep
+// their deployment order and resources are grouped by action so that the output
+// is identical between runs with the same changes.
+func New(regions []manifest.Region) *Report {
+	r := &Report{Regions: make([]Region, 0, len(regions))}
+
+	for _, region := range regions {
+		out := Region{Name: region.Name, Stacks: make([]Stack, 0, len(region.Stacks))}
+
+		stacks := make([]stack.Stack, len(region.Stacks))
+		copy(stacks, region.Stacks)
+		sort.SliceStable(stacks, func(i, j int) bool {
+			return stacks[i].DeploymentOrder < stacks[j].DeploymentOrder
+		})
+
+		for _, s := range stacks {
+			rs := Stack{
+				StackName: s.StackName,
+				Resources: []cloudformation.ChangeResource{},
+			}
+			if s.Changes != nil {
+				rs.Status = s.Changes.Status
+				rs.StatusReason = secrets.Redact(s.Changes.StatusReason)
+				rs.StackPolicyChange = s.Changes.StackPolicyChange
+				rs.ForceStackUpdate = s.Changes.ForceStackUpdate
+				rs.Drifted = s.Changes.Drifted
+				rs.Resources = append(rs.Resources, s.Changes.Resources...)
+			}
+			rs.PolicyViolations = append(rs.PolicyViolations, s.Violations...)
+			sort.SliceStable(rs.Resources, func(i, j int) bool {
+				a, b := rs.Resources[i], rs.Resources[j]
+				if actionRank(a.Action) != actionRank(b.Action) {
+					return actionRank(a.Action) < actionRank(b.Action)
+				}
+				return a.Name < b.Name
+			})
+			out.Stacks = append(out.Stacks, rs)
+		}
+		r.Regions = append(r.Regions, out)
+	}
+
+	sort.Slice(r.Regions, func(i, j int) bool {
+		return r.Regions[i].Name < r.Regions[j].Name
+	})
+
+	return r
+}
+
+// ValidFormat reports whether format can be passed to Render
+func ValidFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Report) Render(w io.Writer, format string) error {
+	switch format {
+	case FormatJSON:
+		return r.renderJSON(w)
+	case FormatMarkdown:
+		return r.renderMarkdown(w)
+	case FormatTable:
+		return r.renderTable(w)
+	default:
+		return errors.Errorf("unknown format %s, must be one of %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+func (r *Report) renderJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+func (r *Report) renderMarkdown(w io.Writer) error {
+	b := &strings.Builder{}
+
+	b.WriteString("## cfstack diff\n\n")
+
+	if r.isEmpty() {
+		b.WriteString("No changes detected.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	for _, region := range r.Regions {
+		if len(region.Stacks) == 0 {
+			continue
+		}
+		fmt.Fprintf(b, "### %s\n\n", region.Name)
+		b.WriteString("| Stack | Status | " + strings.Join(actionOrder, " | ") + " | Replacements | Stack policy |\n")
+		b.WriteString("|---|---|" + strings.Repeat("---:|", len(actionOrder)) + "---:|---|\n")
+
+		for _, s := range region.Stacks {
+			counts := s.countByAction()
+			policy := ""
+			if s.StackPolicyChange {
+				policy = "changed"
+			}
+			fmt.Fprintf(b, "| %s | %s | ", s.StackName, statusLabel(s.Status))
+			for _, action := range actionOrder {
+				fmt.Fprintf(b, "%d | ", counts[action])
+			}
+			fmt.Fprintf(b, "%s | %s |\n", replacementLabel(s.replacements()), policy)
+		}
+		b.WriteString("\n")
+
+		for _, s := range region.Stacks {
+			if len(s.Resources) == 0 {
+				continue
+			}
+			fmt.Fprintf(b, "<details><summary>%s</summary>\n\n", s.StackName)
+			for _, group := range s.groupByAction() {
+				fmt.Fprintf(b, "**%s**\n\n", group.action)
+				b.WriteString("| Resource | Type | Replacement |\n")
+				b.WriteString("|---|---|---|\n")
+				for _, res := range group.resources {
+					fmt.Fprintf(b, "| %s | %s | %s |\n", res.Name, res.Type, replacementCell(res.Replacement))
+				}
+				b.WriteString("\n")
+			}
+			b.WriteString("</details>\n\n")
+		}
+
+		var violations []string
+		for _, s := range region.Stacks {
+			for _, v := range s.PolicyViolations {
+				marker, waiver := "⛔ ", ""
+				if v.Waived {
+					marker, waiver = "", fmt.Sprintf(" _waived: %s_", inline(v.Justification))
+				}
+				violations = append(violations, fmt.Sprintf("- %s**%s** `%s` %s (%s): %s%s",
+					marker, s.StackName, v.Rule, v.Resource, v.ResourceType, inline(v.Message), waiver))
+			}
+		}
+		if len(violations) > 0 {
+			b.WriteString("#### Policy violations\n\n")
+			b.WriteString(strings.Join(violations, "\n"))
+			b.WriteString("\n\n")
+		}
+
+		var problems []Stack
+		for _, s := range region.Stacks {
+			if s.Status == stack.DiffFailStatus || s.Status == stack.DiffUnknownStatus {
+				problems = append(problems, s)
+			}
+		}
+		if len(problems) > 0 {
+			b.WriteString("#### Failed and unknown diffs\n\n")
+			for _, s := range problems {
+				fmt.Fprintf(b, "- **%s** (%s): %s\n", s.StackName, s.Status, inline(s.StatusReason))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	_, err := io.WriteString(w, strings.TrimRight(b.String(), "\n")+"\n")
+	return err
+}
+
+func (r *Report) renderTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "REGION\tSTACK\tSTATUS\tACTION\tRESOURCE\tTYPE\tREPLACEMENT")
+
+	for _, region := range r.Regions {
+		for _, s := range region.Stacks {
+			status := s.Status
+			if s.StackPolicyChange {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", region.Name, s.StackName, status, "Policy", "-", "StackPolicy", "-")
+			}
+			if len(s.Resources) == 0 && !s.StackPolicyChange && len(s.PolicyViolations) == 0 {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", region.Name, s.StackName, status, "-", "-", "-", "-")
+			}
+			for _, res := range s.Resources {
+				replacement := res.Replacement
+				if replacement == "" {
+					replacement = "-"
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", region.Name, s.StackName, status, res.Action, res.Name, res.Type, replacement)
+			}
+			for _, v := range s.PolicyViolations {
+				action := "Violation " + v.Rule
+				if v.Waived {
+					action = "Waived " + v.Rule
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", region.Name, s.StackName, status, action, v.Resource, v.ResourceType, "-")
+			}
+		}
+	}
+
+	return tw.Flush()
+}
+
+func (r *Report) isEmpty() bool {
+	for _, region := range r.Regions {
+		if len(region.Stacks) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+type actionGroup struct {
+	action    string
+	resources []cloudformation.ChangeResource
+}
+
+func (s Stack) groupByAction() []actionGroup {
+	var groups []actionGroup
+	for _, res := range s.Resources {
+		if len(groups) == 0 || groups[len(groups)-1].action != res.Action {
+			groups = append(groups, actionGroup{action: res.Action})
+		}
+		groups[len(groups)-1].resources = append(groups[len(groups)-1].resources, res)
+	}
+	return groups
+}
+
+func (s Stack) countByAction() map[string]int {
+	counts := map[string]int{}
+	for _, res := range s.Resources {
+		counts[res.Action]++
+	}
+	return counts
+}
+
+func (s Stack) replacements() int {
+	n := 0
+	for _, res := range s.Resources {
+		if isReplacement(res.Replacement) {
+			n++
+		}
+	}
+	return n
+}
+
+func isReplacement(replacement string) bool {
+	return replacement == "True" || replacement == "Conditional"
+}
+
+func actionRank(action string) int {
+	for i, a := range actionOrder {
+		if a == action {
+			return i
+		}
+	}
+	return len(actionOrder)
+}
+
+func statusLabel(status string) string {
+	switch status {
+	case stack.DiffSuccessStatus:
+		return "changes"
+	case stack.DiffFailStatus:
+		return "❌ failed"
+	case stack.DiffUnknownStatus:
+		return "❔ unknown"
+	default:
+		return status
+	}
+}
+
+func replacementLabel(n int) string {
+	if n == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("**⚠️ %d**", n)
+}
+
+func replacementCell(replacement string) string {
+	if isReplacement(replacement) {
+		return fmt.Sprintf("**⚠️ %s**", replacement)
+	}
+	return replacement
+}
+
+// inline makes a free form message safe to use on a single markdown line
+func inline(s string) string {
+	s = strings.Replace(s, "\r", "", -1)
+	s = strings.Replace(s, "\n", " ", -1)
+	return strings.Replace(s, "|", "\\|", -1)
+}
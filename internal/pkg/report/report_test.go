@@ -0,0 +1,211 @@
+package report
+
+import (
+	"bytes"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"github.com/CleverTap/cfstack/internal/pkg/manifest"
+	"github.com/CleverTap/cfstack/internal/pkg/policy"
+	"github.com/CleverTap/cfstack/internal/pkg/stack"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func sampleRegions() []manifest.Region {
+	return []manifest.Region{
+		{
+			Name: "us-east-1",
+			Stacks: []stack.Stack{
+				{
+					StackName: "Broken",
+					Changes: &cloudformation.Changes{
+						Status:       stack.DiffFailStatus,
+						StatusReason: "Template format error:\nbad | input",
+					},
+				},
+			},
+		},
+		{
+			Name: "eu-west-1",
+			Stacks: []stack.Stack{
+				{
+					StackName:       "Sample-Bucket",
+					DeploymentOrder: 1,
+					UID:             "7c8f8a56",
+					Bucket:          "cfstack-init-templates",
+					Changes: &cloudformation.Changes{
+						Status:            stack.DiffSuccessStatus,
+						StackPolicyChange: true,
+						Resources: []cloudformation.ChangeResource{
+							{Name: "Queue", Type: "AWS::SQS::Queue", Action: "Remove"},
+							{Name: "S3Bucket", Type: "AWS::S3::Bucket", Action: "Modify", Replacement: "True"},
+							{Name: "Alarm", Type: "AWS::CloudWatch::Alarm", Action: "Add"},
+						},
+					},
+				},
+				{
+					StackName:       "System-Users",
+					DeploymentOrder: 0,
+					Changes: &cloudformation.Changes{
+						Status:       stack.DiffUnknownStatus,
+						StatusReason: "Stack:System-Users is in UPDATE_IN_PROGRESS state and can not be updated.",
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	testCases := map[string]struct {
+		regions  []manifest.Region
+		expected string
+	}{
+		"no changes": {
+			regions:  []manifest.Region{},
+			expected: "## cfstack diff\n\nNo changes detected.\n",
+		},
+		"changes failures and unknown diffs": {
+			regions: sampleRegions(),
+			expected: `## cfstack diff
+
+### eu-west-1
+
+| Stack | Status | Add | Modify | Remove | Import | Dynamic | Replacements | Stack policy |
+|---|---|---:|---:|---:|---:|---:|---:|---|
+| System-Users | ❔ unknown | 0 | 0 | 0 | 0 | 0 | 0 |  |
+| Sample-Bucket | changes | 1 | 1 | 1 | 0 | 0 | **⚠️ 1** | changed |
+
+<details><summary>Sample-Bucket</summary>
+
+**Add**
+
+| Resource | Type | Replacement |
+|---|---|---|
+| Alarm | AWS::CloudWatch::Alarm |  |
+
+**Modify**
+
+| Resource | Type | Replacement |
+|---|---|---|
+| S3Bucket | AWS::S3::Bucket | **⚠️ True** |
+
+**Remove**
+
+| Resource | Type | Replacement |
+|---|---|---|
+| Queue | AWS::SQS::Queue |  |
+
+</details>
+
+#### Failed and unknown diffs
+
+- **System-Users** (unknown): Stack:System-Users is in UPDATE_IN_PROGRESS state and can not be updated.
+
+### us-east-1
+
+| Stack | Status | Add | Modify | Remove | Import | Dynamic | Replacements | Stack policy |
+|---|---|---:|---:|---:|---:|---:|---:|---|
+| Broken | ❌ failed | 0 | 0 | 0 | 0 | 0 | 0 |  |
+
+#### Failed and unknown diffs
+
+- **Broken** (failed): Template format error: bad \| input
+`,
+		},
+		"imports and dynamic changes": {
+			regions: []manifest.Region{
+				{
+					Name: "eu-west-1",
+					Stacks: []stack.Stack{
+						{
+							StackName: "Data-Tables",
+							Changes: &cloudformation.Changes{
+								Status: stack.DiffSuccessStatus,
+								Resources: []cloudformation.ChangeResource{
+									{Name: "Orders", Type: "AWS::DynamoDB::Table", Action: "Import"},
+									{Name: "Users", Type: "AWS::DynamoDB::Table", Action: "Import"},
+									{Name: "Alarm", Type: "AWS::CloudWatch::Alarm", Action: "Dynamic"},
+								},
+							},
+						},
+					},
+				},
+			},
+			expected: `## cfstack diff
+
+### eu-west-1
+
+| Stack | Status | Add | Modify | Remove | Import | Dynamic | Replacements | Stack policy |
+|---|---|---:|---:|---:|---:|---:|---:|---|
+| Data-Tables | changes | 0 | 0 | 0 | 2 | 1 | 0 |  |
+
+<details><summary>Data-Tables</summary>
+
+**Import**
+
+| Resource | Type | Replacement |
+|---|---|---|
+| Orders | AWS::DynamoDB::Table |  |
+| Users | AWS::DynamoDB::Table |  |
+
+**Dynamic**
+
+| Resource | Type | Replacement |
+|---|---|---|
+| Alarm | AWS::CloudWatch::Alarm |  |
+
+</details>
+`,
+		},
+		"policy violations": {
+			regions: []manifest.Region{
+				{
+					Name: "eu-west-1",
+					Stacks: []stack.Stack{
+						{
+							StackName: "Sample-Bucket",
+							Changes:   &cloudformation.Changes{},
+							Violations: []policy.Violation{
+								{Rule: "s3-no-public-access", Resource: "S3Bucket", ResourceType: "AWS::S3::Bucket", Message: "Properties.AccessControl is PublicRead"},
+								{Rule: "s3-encryption", Resource: "Logs", ResourceType: "AWS::S3::Bucket", Message: "Properties.BucketEncryption is not set", Waived: true, Justification: "legacy | bucket"},
+							},
+						},
+					},
+				},
+			},
+			expected: `## cfstack diff
+
+### eu-west-1
+
+| Stack | Status | Add | Modify | Remove | Import | Dynamic | Replacements | Stack policy |
+|---|---|---:|---:|---:|---:|---:|---:|---|
+| Sample-Bucket |  | 0 | 0 | 0 | 0 | 0 | 0 |  |
+
+#### Policy violations
+
+- ⛔ **Sample-Bucket** ` + "`s3-no-public-access`" + ` S3Bucket (AWS::S3::Bucket): Properties.AccessControl is PublicRead
+- **Sample-Bucket** ` + "`s3-encryption`" + ` Logs (AWS::S3::Bucket): Properties.BucketEncryption is not set _waived: legacy \| bucket_
+`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			err := New(tc.regions).Render(out, FormatMarkdown)
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, out.String())
+		})
+	}
+}
+
+func TestRenderJSONOmitsInternalFields(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := New(sampleRegions()).Render(out, FormatJSON)
+
+	require.NoError(t, err)
+	require.NotContains(t, out.String(), "7c8f8a56")
+	require.NotContains(t, out.String(), "cfstack-init-templates")
+	require.NotContains(t, out.String(), "AbsTemplatePath")
+}
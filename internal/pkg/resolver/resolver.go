@@ -0,0 +1,285 @@
+package resolver
+
+import (
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/secrets"
+	"github.com/CleverTap/cfstack/internal/pkg/values"
+	"github.com/pkg/errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxDepth bounds how deep values may reference other values
+const maxDepth = 10
+
+var placeholder = regexp.MustCompile(`\{\{(.*?)\}\}`)
+
+// Remote resolves ssm: and secretsmanager: references, *secrets.Store satisfies it
+type Remote interface {
+	Resolve(ref string) (string, error)
+}
+
+// Resolver substitutes {{ ... }} placeholders in the parameters of a single stack.
+// A placeholder is either a value name, an env: reference or an ssm: / secretsmanager:
+// reference and may carry a default, e.g. {{ LogLevel | default "info" }}.
+type Resolver struct {
+	Region string
+	Stack  string
+	Values *values.Values
+	Remote Remote
+}
+
+// Unresolved is a placeholder that could not be resolved
+type Unresolved struct {
+	Parameter string
+	Reference string
+	Err       error
+}
+
+// Error lists every placeholder that could not be resolved
+type Error struct {
+	Stack      string
+	Region     string
+	Unresolved []Unresolved
+}
+
+func (e *Error) Error() string {
+	lines := make([]string, 0, len(e.Unresolved))
+	for _, u := range e.Unresolved {
+		lines = append(lines, fmt.Sprintf("Parameter %s: %s", u.Parameter, u.Err))
+	}
+	return fmt.Sprintf("%d unresolved reference(s) in stack %s in region %s:\n    %s",
+		len(e.Unresolved), e.Stack, e.Region, strings.Join(lines, "\n    "))
+}
+
+func New(region string, stack string, v *values.Values, remote Remote) *Resolver {
+	return &Resolver{
+		Region: region,
+		Stack:  stack,
+		Values: v,
+		Remote: remote,
+	}
+}
+
+// Resolve applies the --param overrides to params and substitutes every placeholder.
+// All unresolved references are returned together as an *Error.
+func (r *Resolver) Resolve(params map[string]string) (map[string]string, error) {
+	out, _, err := r.resolve(params, false)
+	return out, err
+}
+
+// ResolveOffline resolves every parameter that doesn't need AWS. Parameters that
+// reference ssm: or secretsmanager: are left out and returned as deferred.
+func (r *Resolver) ResolveOffline(params map[string]string) (map[string]string, []string, error) {
+	return r.resolve(params, true)
+}
+
+func (r *Resolver) resolve(params map[string]string, offline bool) (map[string]string, []string, error) {
+	params = r.Values.Parameters(r.Region, r.Stack, params)
+
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make(map[string]string, len(params))
+	var deferred []string
+	resolveErr := &Error{Stack: r.Stack, Region: r.Region}
+
+	for _, k := range keys {
+		value, unresolved := r.interpolate(params[k], nil)
+
+		remote := len(unresolved) > 0
+		for _, u := range unresolved {
+			if _, ok := u.Err.(*remoteError); !ok {
+				remote = false
+			}
+		}
+		if offline && remote {
+			deferred = append(deferred, k)
+			continue
+		}
+
+		for _, u := range unresolved {
+			u.Parameter = k
+			resolveErr.Unresolved = append(resolveErr.Unresolved, u)
+		}
+		out[k] = value
+	}
+
+	if len(resolveErr.Unresolved) > 0 {
+		return nil, deferred, resolveErr
+	}
+	return out, deferred, nil
+}
+
+// interpolate substitutes every placeholder of s, chain holds the value names being
+// resolved to detect values that reference each other
+func (r *Resolver) interpolate(s string, chain []string) (string, []Unresolved) {
+	var unresolved []Unresolved
+
+	out := placeholder.ReplaceAllStringFunc(s, func(match string) string {
+		expr := strings.TrimSpace(match[2 : len(match)-2])
+		value, err := r.evaluate(expr, chain)
+		if err != nil {
+			if u, ok := err.(*nestedError); ok {
+				unresolved = append(unresolved, u.unresolved...)
+			} else {
+				unresolved = append(unresolved, Unresolved{Reference: expr, Err: err})
+			}
+			return match
+		}
+		return value
+	})
+
+	return out, unresolved
+}
+
+// nestedError carries the unresolved references of a value that itself holds placeholders
+type nestedError struct {
+	unresolved []Unresolved
+}
+
+func (e *nestedError) Error() string {
+	return e.unresolved[0].Err.Error()
+}
+
+// remoteError marks references that need AWS to be resolved
+type remoteError struct {
+	ref string
+}
+
+func (e *remoteError) Error() string {
+	return fmt.Sprintf("%s can't be resolved without AWS access", e.ref)
+}
+
+func (r *Resolver) evaluate(expr string, chain []string) (string, error) {
+	name, def, hasDefault, err := parseExpression(expr)
+	if err != nil {
+		return "", err
+	}
+
+	value, found, err := r.lookup(name, chain)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		if hasDefault {
+			return def, nil
+		}
+		if strings.HasPrefix(name, values.EnvPrefix) {
+			return "", errors.Errorf("environment variable %s is not set", strings.TrimPrefix(name, values.EnvPrefix))
+		}
+		return "", errors.Errorf("Value %s not found in values for stack %s in region %s", name, r.Stack, r.Region)
+	}
+	return value, nil
+}
+
+// lookup returns found=false only when a default may be used in place of the value
+func (r *Resolver) lookup(name string, chain []string) (string, bool, error) {
+	if strings.HasPrefix(name, values.EnvPrefix) {
+		value, err := values.LookupEnv(name)
+		if err != nil {
+			return "", false, nil
+		}
+		return value, true, nil
+	}
+
+	if secrets.IsReference(name) {
+		if r.Remote == nil {
+			return "", false, &remoteError{ref: name}
+		}
+		value, err := r.Remote.Resolve(name)
+		return value, err == nil, err
+	}
+
+	for _, c := range chain {
+		if c == name {
+			return "", false, errors.Errorf("circular reference %s", strings.Join(append(chain, name), " -> "))
+		}
+	}
+	if len(chain) >= maxDepth {
+		return "", false, errors.Errorf("values nested more than %d levels deep at %s", maxDepth, name)
+	}
+
+	raw, source, found := r.Values.Lookup(r.Region, r.Stack, name)
+	if !found {
+		return "", false, nil
+	}
+
+	value, err := Format(raw)
+	if err != nil {
+		return "", false, errors.Wrapf(err, "Value %s from %s (%s)", name, source.File, source.Scope)
+	}
+
+	value, unresolved := r.interpolate(value, append(chain, name))
+	if len(unresolved) > 0 {
+		return "", false, &nestedError{unresolved: unresolved}
+	}
+	return value, true, nil
+}
+
+// Format converts a value from a values file to a parameter value. Numbers and booleans
+// are written as in JSON and lists are joined for CommaDelimitedList parameters.
+func Format(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case []interface{}:
+		items := make([]string, 0, len(t))
+		for _, item := range t {
+			s, err := formatScalar(item)
+			if err != nil {
+				return "", errors.New("lists must only hold strings, numbers or booleans")
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return formatScalar(v)
+	}
+}
+
+func formatScalar(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	default:
+		return "", errors.New("must be a string, number, boolean or list")
+	}
+}
+
+// parseExpression splits `Name | default "x"` into the reference name and its default
+func parseExpression(expr string) (string, string, bool, error) {
+	parts := strings.SplitN(expr, "|", 2)
+	name := strings.TrimSpace(parts[0])
+	if name == "" {
+		return "", "", false, errors.Errorf("empty placeholder {{ %s }}", expr)
+	}
+	if len(parts) == 1 {
+		return name, "", false, nil
+	}
+
+	filter := strings.TrimSpace(parts[1])
+	if !strings.HasPrefix(filter, "default") {
+		return "", "", false, errors.Errorf("unknown filter %s in {{ %s }}, only default is supported", filter, expr)
+	}
+
+	arg := strings.TrimSpace(strings.TrimPrefix(filter, "default"))
+	if arg == "" {
+		return "", "", false, errors.Errorf("default in {{ %s }} is missing a value", expr)
+	}
+	if strings.HasPrefix(arg, `"`) {
+		def, err := strconv.Unquote(arg)
+		if err != nil {
+			return "", "", false, errors.Errorf("invalid default %s in {{ %s }}", arg, expr)
+		}
+		return name, def, true, nil
+	}
+	return name, arg, true, nil
+}
@@ -0,0 +1,136 @@
+package resolver
+
+import (
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/values"
+	"github.com/Jeffail/gabs"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+type fakeRemote map[string]string
+
+func (f fakeRemote) Resolve(ref string) (string, error) {
+	v, ok := f[ref]
+	if !ok {
+		return "", fmt.Errorf("%s not found", ref)
+	}
+	return v, nil
+}
+
+func TestResolve(t *testing.T) {
+	data, err := gabs.ParseJSON([]byte(`{
+		"*": {
+			"*": { "Bucket": "assets", "Replicas": 3, "Ratio": 0.5, "Enabled": true }
+		},
+		"eu-west-1": {
+			"Api": {
+				"Subnets": ["subnet-1", "subnet-2"],
+				"Arn": "arn:aws:s3:::{{ Bucket }}",
+				"Password": "{{ ssm:/api/password }}",
+				"Nested": [["a"]],
+				"Loop": "{{ Loop }}"
+			}
+		}
+	}`))
+	require.NoError(t, err)
+	v := values.New(values.File{Path: "values.json", Data: data})
+
+	os.Setenv("CFSTACK_TEST_IMAGE_TAG", "abc123")
+	defer os.Unsetenv("CFSTACK_TEST_IMAGE_TAG")
+
+	r := New("eu-west-1", "Api", v, fakeRemote{"ssm:/api/password": "hunter22"})
+
+	testCases := map[string]struct {
+		value         string
+		expected      string
+		expectedError string
+	}{
+		"plain value":           {value: "plain", expected: "plain"},
+		"whole placeholder":     {value: "{{ Bucket }}", expected: "assets"},
+		"interpolation":         {value: "arn:aws:s3:::{{ Bucket }}/*", expected: "arn:aws:s3:::assets/*"},
+		"number":                {value: "{{ Replicas }}", expected: "3"},
+		"float":                 {value: "{{Ratio}}", expected: "0.5"},
+		"boolean":               {value: "{{ Enabled }}", expected: "true"},
+		"list":                  {value: "{{ Subnets }}", expected: "subnet-1,subnet-2"},
+		"value with a value":    {value: "{{ Arn }}/logs", expected: "arn:aws:s3:::assets/logs"},
+		"value with a secret":   {value: "{{ Password }}", expected: "hunter22"},
+		"env":                   {value: "{{ env:CFSTACK_TEST_IMAGE_TAG }}", expected: "abc123"},
+		"default":               {value: `{{ Missing | default "info" }}`, expected: "info"},
+		"empty default":         {value: `{{ Missing | default "" }}`, expected: ""},
+		"env default":           {value: `{{ env:CFSTACK_TEST_MISSING | default "latest" }}`, expected: "latest"},
+		"default is not needed": {value: `{{ Bucket | default "other" }}`, expected: "assets"},
+		"missing value": {
+			value:         "{{ Missing }}",
+			expectedError: "Parameter P: Value Missing not found in values for stack Api in region eu-west-1",
+		},
+		"missing env": {
+			value:         "{{ env:CFSTACK_TEST_MISSING }}",
+			expectedError: "Parameter P: environment variable CFSTACK_TEST_MISSING is not set",
+		},
+		"nested list": {
+			value:         "{{ Nested }}",
+			expectedError: "Parameter P: Value Nested from values.json (stack): lists must only hold strings, numbers or booleans",
+		},
+		"circular": {
+			value:         "{{ Loop }}",
+			expectedError: "Parameter P: circular reference Loop -> Loop",
+		},
+		"unknown filter": {
+			value:         "{{ Bucket | upper }}",
+			expectedError: "Parameter P: unknown filter upper in {{ Bucket | upper }}, only default is supported",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			out, err := r.Resolve(map[string]string{"P": tc.value})
+			if tc.expectedError != "" {
+				require.Error(t, err)
+				require.Len(t, err.(*Error).Unresolved, 1)
+				require.Contains(t, err.Error(), tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, out["P"])
+		})
+	}
+
+	t.Run("every unresolved reference is reported", func(t *testing.T) {
+		_, err := r.Resolve(map[string]string{
+			"A": "{{ Missing }}-{{ AlsoMissing }}",
+			"B": "{{ Bucket }}",
+			"C": "{{ ssm:/missing }}",
+		})
+		require.EqualError(t, err, `3 unresolved reference(s) in stack Api in region eu-west-1:
+    Parameter A: Value Missing not found in values for stack Api in region eu-west-1
+    Parameter A: Value AlsoMissing not found in values for stack Api in region eu-west-1
+    Parameter C: ssm:/missing not found`)
+	})
+
+	t.Run("remote references need AWS access", func(t *testing.T) {
+		_, err := New("eu-west-1", "Api", v, nil).Resolve(map[string]string{"P": "{{ Password }}"})
+		require.EqualError(t, err, `1 unresolved reference(s) in stack Api in region eu-west-1:
+    Parameter P: ssm:/api/password can't be resolved without AWS access`)
+	})
+}
+
+func TestResolveOffline(t *testing.T) {
+	data, err := gabs.ParseJSON([]byte(`{"*": {"*": {"Bucket": "assets", "Password": "{{ secretsmanager:db#password }}"}}}`))
+	require.NoError(t, err)
+	v := values.New(values.File{Path: "values.json", Data: data})
+
+	out, deferred, err := New("eu-west-1", "Api", v, nil).ResolveOffline(map[string]string{
+		"Bucket":   "{{ Bucket }}",
+		"Password": "{{ Password }}",
+		"Token":    "{{ ssm:/api/token }}",
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"Bucket": "assets"}, out)
+	require.Equal(t, []string{"Password", "Token"}, deferred)
+
+	_, _, err = New("eu-west-1", "Api", v, nil).ResolveOffline(map[string]string{"Url": "{{ ssm:/host }}/{{ Missing }}"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Value Missing not found")
+}
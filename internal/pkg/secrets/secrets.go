@@ -0,0 +1,216 @@
+package secrets
+
+import (
+	"encoding/json"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/secretsmanager"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/ssm"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/pkg/errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	SSMPrefix            = "ssm:"
+	SecretsManagerPrefix = "secretsmanager:"
+
+	// Mask replaces resolved values in anything cfstack prints or writes
+	Mask = "******"
+)
+
+type ParameterGetter interface {
+	GetParameter(name string) (string, error)
+}
+
+type SecretGetter interface {
+	GetSecretString(id string) (string, error)
+}
+
+// Store resolves ssm and secretsmanager references for a single region. Resolved
+// values are cached for the rest of the run.
+type Store struct {
+	region     string
+	parameters ParameterGetter
+	secrets    SecretGetter
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+var (
+	storesMu sync.Mutex
+	stores   = map[string]*Store{}
+
+	redactMu sync.RWMutex
+	redacted = map[string]bool{}
+)
+
+func NewStore(region string, parameters ParameterGetter, secrets SecretGetter) *Store {
+	return &Store{
+		region:     region,
+		parameters: parameters,
+		secrets:    secrets,
+		cache:      map[string]string{},
+	}
+}
+
+// ForSession returns the store for the region of the session. Stores are shared by
+// every caller in the same region so references are resolved only once per run.
+func ForSession(sess *session.Session) *Store {
+	region := aws.StringValue(sess.Config.Region)
+
+	storesMu.Lock()
+	defer storesMu.Unlock()
+
+	if s, ok := stores[region]; ok {
+		return s
+	}
+
+	s := NewStore(region, ssm.New(sess), secretsmanager.New(sess))
+	stores[region] = s
+	return s
+}
+
+// IsReference reports whether a placeholder name points to ssm or secretsmanager
+func IsReference(name string) bool {
+	return strings.HasPrefix(name, SSMPrefix) || strings.HasPrefix(name, SecretsManagerPrefix)
+}
+
+// Resolve returns the value of an ssm:/path or secretsmanager:name#jsonKey reference
+func (s *Store) Resolve(ref string) (string, error) {
+	if s == nil {
+		return "", errors.Errorf("%s can't be resolved without AWS access", ref)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if v, ok := s.cache[ref]; ok {
+		return v, nil
+	}
+
+	var value string
+	var err error
+
+	switch {
+	case strings.HasPrefix(ref, SSMPrefix):
+		value, err = s.resolveParameter(strings.TrimPrefix(ref, SSMPrefix))
+	case strings.HasPrefix(ref, SecretsManagerPrefix):
+		value, err = s.resolveSecret(strings.TrimPrefix(ref, SecretsManagerPrefix))
+	default:
+		err = errors.Errorf("%s is not an ssm or secretsmanager reference", ref)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	register(value)
+	s.cache[ref] = value
+	return value, nil
+}
+
+func (s *Store) resolveParameter(name string) (string, error) {
+	if name == "" {
+		return "", errors.New("ssm reference is missing the parameter name")
+	}
+
+	value, err := s.parameters.GetParameter(name)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read ssm parameter %s in region %s", name, s.region)
+	}
+	return value, nil
+}
+
+func (s *Store) resolveSecret(ref string) (string, error) {
+	id, key := ref, ""
+	if i := strings.LastIndex(ref, "#"); i >= 0 {
+		id, key = ref[:i], ref[i+1:]
+	}
+
+	if id == "" {
+		return "", errors.New("secretsmanager reference is missing the secret name")
+	}
+
+	secret, err := s.secrets.GetSecretString(id)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read secret %s in region %s", id, s.region)
+	}
+
+	if key == "" {
+		return secret, nil
+	}
+
+	// The whole secret is redacted too as it may show up in error messages
+	register(secret)
+
+	fields := map[string]interface{}{}
+	err = json.Unmarshal([]byte(secret), &fields)
+	if err != nil {
+		return "", errors.Errorf("secret %s is not a JSON object, can't read key %s", id, key)
+	}
+
+	field, ok := fields[key]
+	if !ok {
+		return "", errors.Errorf("key %s not found in secret %s", key, id)
+	}
+
+	if v, ok := field.(string); ok {
+		return v, nil
+	}
+
+	b, err := json.Marshal(field)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// register adds a resolved value to the redacted ones whatever its length, a short secret
+// masked inside unrelated words is better than a secret printed in clear text
+func register(value string) {
+	if value == "" {
+		return
+	}
+	redactMu.Lock()
+	redacted[value] = true
+	redactMu.Unlock()
+}
+
+// Redact masks every value resolved from ssm or secretsmanager during this run
+func Redact(s string) string {
+	redactMu.RLock()
+	defer redactMu.RUnlock()
+
+	if len(redacted) == 0 {
+		return s
+	}
+
+	values := make([]string, 0, len(redacted))
+	for v := range redacted {
+		values = append(values, v)
+	}
+	// Longer values first so a value containing another one is masked as a whole
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+
+	for _, v := range values {
+		s = strings.Replace(s, v, Mask, -1)
+	}
+	return s
+}
+
+// RedactError returns an error with the same message as err with secrets masked
+func RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := Redact(err.Error())
+	if msg == err.Error() {
+		return err
+	}
+	return errors.New(msg)
+}
@@ -0,0 +1,114 @@
+package secrets
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// fakeParameterStore and fakeSecretsManager are local stand-ins for the AWS services
+type fakeParameterStore struct {
+	parameters map[string]string
+	calls      int
+}
+
+func (f *fakeParameterStore) GetParameter(name string) (string, error) {
+	f.calls++
+	v, ok := f.parameters[name]
+	if !ok {
+		return "", fmt.Errorf("ParameterNotFound: %s", name)
+	}
+	return v, nil
+}
+
+type fakeSecretsManager struct {
+	secrets map[string]string
+	calls   int
+}
+
+func (f *fakeSecretsManager) GetSecretString(id string) (string, error) {
+	f.calls++
+	v, ok := f.secrets[id]
+	if !ok {
+		return "", fmt.Errorf("ResourceNotFoundException: %s", id)
+	}
+	return v, nil
+}
+
+func TestResolve(t *testing.T) {
+	parameters := &fakeParameterStore{parameters: map[string]string{
+		"/app/db/host": "db.internal.example.com",
+	}}
+	secretsManager := &fakeSecretsManager{secrets: map[string]string{
+		"prod/db":    `{"username": "admin", "password": "s3cr3t-pa55", "port": 5432}`,
+		"prod/token": "plain-token-value",
+		"prod/pin":   "x7",
+	}}
+	store := NewStore("eu-west-1", parameters, secretsManager)
+
+	testCases := map[string]struct {
+		ref           string
+		expected      string
+		expectedError string
+	}{
+		"ssm parameter": {
+			ref:      "ssm:/app/db/host",
+			expected: "db.internal.example.com",
+		},
+		"secret json key": {
+			ref:      "secretsmanager:prod/db#password",
+			expected: "s3cr3t-pa55",
+		},
+		"secret json key with a number": {
+			ref:      "secretsmanager:prod/db#port",
+			expected: "5432",
+		},
+		"whole secret": {
+			ref:      "secretsmanager:prod/token",
+			expected: "plain-token-value",
+		},
+		"short secret": {
+			ref:      "secretsmanager:prod/pin",
+			expected: "x7",
+		},
+		"missing ssm parameter": {
+			ref:           "ssm:/app/missing",
+			expectedError: "failed to read ssm parameter /app/missing in region eu-west-1: ParameterNotFound: /app/missing",
+		},
+		"missing json key": {
+			ref:           "secretsmanager:prod/db#host",
+			expectedError: "key host not found in secret prod/db",
+		},
+		"secret that is not json": {
+			ref:           "secretsmanager:prod/token#password",
+			expectedError: "secret prod/token is not a JSON object, can't read key password",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			value, err := store.Resolve(tc.ref)
+
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, value)
+		})
+	}
+
+	t.Run("values are cached for the run", func(t *testing.T) {
+		calls := parameters.calls
+		_, err := store.Resolve("ssm:/app/db/host")
+		require.NoError(t, err)
+		require.Equal(t, calls, parameters.calls)
+	})
+
+	t.Run("resolved values are redacted", func(t *testing.T) {
+		msg := Redact(`Parameter DbPassword "s3cr3t-pa55" for host db.internal.example.com is invalid`)
+		require.Equal(t, `Parameter DbPassword "******" for host ****** is invalid`, msg)
+		require.NotContains(t, Redact(secretsManager.secrets["prod/db"]), "s3cr3t-pa55")
+		require.Equal(t, `Parameter Pin "******" is invalid`, Redact(`Parameter Pin "x7" is invalid`))
+	})
+}
@@ -0,0 +1,98 @@
+package stack
+
+import (
+	"github.com/CleverTap/cfstack/internal/pkg/aws/s3"
+	"github.com/CleverTap/cfstack/internal/pkg/history"
+	"github.com/CleverTap/cfstack/internal/pkg/secrets"
+	"github.com/fatih/color"
+	"os"
+	"strings"
+	"time"
+)
+
+// recordDeployment keeps an entry of the deployment in the TemplatesS3Bucket for history
+// and rollback. The stack is deployed at this point, a failure is only a warning.
+func (s *Stack) recordDeployment() {
+	resolved, err := s.Deployer.ResolveParameters(s.StackName, s.Parameters)
+	if err == nil {
+		params, placeholders := history.Redact(s.Parameters, resolved)
+		entry := s.historyEntry(params, placeholders)
+		err = s.keepTemplate(&entry)
+		if err == nil {
+			err = history.New(&s.Uploader, s.Bucket).Record(entry)
+		}
+	}
+	if err != nil {
+		color.New(color.FgYellow).Fprintf(os.Stdout, "    Deployment of stack %s could not be recorded: %v\n", s.StackName, secrets.RedactError(err))
+	}
+}
+
+// keepTemplate copies the template uploaded by the run next to the history entry and
+// points the entry to the copy, the run prefix expires
+func (s *Stack) keepTemplate(entry *history.Entry) error {
+	if s.uploadedTemplate == nil {
+		return nil
+	}
+
+	key := history.TemplateKey(s.Region, s.StackName, s.UID)
+	templateUrl, err := s3.ObjectURL(s.Region, s.Bucket, key, s.S3UrlStyle)
+	if err != nil {
+		return err
+	}
+
+	version, err := s.Uploader.Upload(&s3.Opts{
+		Bucket: s.Bucket,
+		Key:    key,
+		Body:   s.uploadedTemplate,
+	})
+	if err != nil {
+		return err
+	}
+
+	entry.TemplateUrl = templateUrl
+	entry.TemplateVersion = version
+	return nil
+}
+
+// historyEntry records the deployment of the stack with redacted parameters
+func (s *Stack) historyEntry(params map[string]string, placeholders map[string]string) history.Entry {
+	return history.Entry{
+		DeploymentId:     s.UID,
+		StackName:        s.StackName,
+		Region:           s.Region,
+		TemplateUrl:      strings.SplitN(s.TemplateUrl, "?", 2)[0],
+		TemplateVersion:  s.TemplateVersion,
+		TemplateBody:     s.TemplateBody,
+		Serverless:       s.serverless,
+		Parameters:       params,
+		SecretParameters: placeholders,
+		StackPolicy:      s.StackPolicy,
+		Timestamp:        time.Now().UTC(),
+		GitSha:           s.GitSha,
+		RollbackOf:       s.RollbackOf,
+	}
+}
+
+// FromHistory returns the stack deploying a recorded deployment again, its template is
+// the recorded one so it isn't packaged or uploaded again. Redacted parameters are
+// resolved again from their placeholders.
+func FromHistory(entry history.Entry) (Stack, error) {
+	params, err := entry.RollbackParameters()
+	if err != nil {
+		return Stack{}, err
+	}
+
+	return Stack{
+		StackName:       entry.StackName,
+		Action:          "UPDATE",
+		Region:          entry.Region,
+		TemplateUrl:     entry.VersionedTemplateUrl(),
+		TemplateVersion: entry.TemplateVersion,
+		TemplateBody:    entry.TemplateBody,
+		Parameters:      params,
+		StackPolicy:     entry.StackPolicy,
+		GitSha:          entry.GitSha,
+		RollbackOf:      entry.DeploymentId,
+		serverless:      entry.Serverless,
+	}, nil
+}
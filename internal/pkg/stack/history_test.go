@@ -0,0 +1,37 @@
+package stack
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestFromHistory(t *testing.T) {
+	testCases := map[string]struct {
+		serverless bool
+	}{
+		"serverless": {serverless: true},
+		"plain":      {serverless: false},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			deployed := Stack{
+				StackName:       "Api",
+				Region:          "eu-west-1",
+				UID:             "run-1",
+				TemplateUrl:     "https://templates.s3.eu-west-1.amazonaws.com/runs/run-1/api.yaml?versionId=v1",
+				TemplateVersion: "v1",
+				serverless:      tc.serverless,
+			}
+			entry := deployed.historyEntry(map[string]string{"Stage": "prod"}, nil)
+
+			s, err := FromHistory(entry)
+			require.NoError(t, err)
+			require.Equal(t, tc.serverless, s.serverless)
+			require.Equal(t, "UPDATE", s.Action)
+			require.Equal(t, "run-1", s.RollbackOf)
+			require.Equal(t, map[string]string{"Stage": "prod"}, s.Parameters)
+			require.Equal(t, "https://templates.s3.eu-west-1.amazonaws.com/runs/run-1/api.yaml?versionId=v1", s.TemplateUrl)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package stack
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"github.com/CleverTap/cfstack/internal/pkg/policy"
+	"github.com/fatih/color"
+	"github.com/golang/glog"
+	"os"
+)
+
+// pendingImports returns the ResourcesToImport the stack doesn't manage yet, resources stay
+// in the manifest once imported and are skipped by later deploys
+func (s *Stack) pendingImports(stackExists bool) ([]cloudformation.ResourceToImport, error) {
+	if len(s.ResourcesToImport) == 0 || !stackExists {
+		return s.ResourcesToImport, nil
+	}
+
+	existing, err := s.Deployer.StackResourceIds(s.StackName)
+	if err != nil {
+		return nil, err
+	}
+	return remainingImports(s.ResourcesToImport, existing), nil
+}
+
+func remainingImports(imports []cloudformation.ResourceToImport, existing map[string]bool) []cloudformation.ResourceToImport {
+	var out []cloudformation.ResourceToImport
+	for _, r := range imports {
+		if !existing[r.LogicalResourceId] {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func (s *Stack) importChangeSetOpts(imports []cloudformation.ResourceToImport) (*cloudformation.GetStackChangesOpts, error) {
+	stackPolicy, err := json.Marshal(s.StackPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cloudformation.GetStackChangesOpts{
+		StackName:         s.StackName,
+		TemplateUrl:       s.TemplateUrl,
+		TemplateBody:      s.TemplateBody,
+		StackPolicy:       string(stackPolicy),
+		Parameters:        s.Parameters,
+		ChangeSetName:     s.getChangeSetName(),
+		Type:              cloudformation.ChangeSetTypeImport,
+		RoleArn:           s.RoleArn,
+		ResourcesToImport: imports,
+	}, nil
+}
+
+// importResources brings existing resources under the management of the stack with an
+// IMPORT change set, the stack is created when it doesn't exist yet
+func (s *Stack) importResources(imports []cloudformation.ResourceToImport) error {
+	if !s.SuppressMessages {
+		fmt.Printf("    Importing %d existing resource(s) into stack %s\n", len(imports), s.StackName)
+	}
+
+	opts, err := s.importChangeSetOpts(imports)
+	if err != nil {
+		return err
+	}
+
+	changes, err := s.Deployer.CreateChangeSet(opts)
+	if err != nil {
+		return err
+	}
+
+	apply, err := s.confirmImport(changes)
+	if err != nil || !apply {
+		delErr := s.Deployer.DeleteChangeSet(s.StackName, opts.ChangeSetName)
+		if delErr != nil {
+			glog.Warningf("import change set of stack %s could not be deleted: %v", s.StackName, delErr)
+		}
+		return err
+	}
+
+	err = s.Deployer.ExecuteChangeSet(s.StackName, opts.ChangeSetName)
+	if err != nil {
+		return err
+	}
+
+	// Change sets don't carry a stack policy, it is set once the resources are imported
+	if opts.StackPolicy != "{}" {
+		err = s.Deployer.SetStackPolicy(s.StackName, opts.StackPolicy)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.recordDeployment()
+	if !s.SuppressMessages {
+		color.New(color.FgGreen).Fprintf(os.Stdout, "    Stack import complete\n")
+	}
+	return nil
+}
+
+func (s *Stack) confirmImport(changes *cloudformation.Changes) (bool, error) {
+	err := s.checkPolicies(changes.Resources)
+	if err != nil {
+		return false, err
+	}
+	err = policy.Error(s.StackName, s.Violations)
+	if err != nil {
+		return false, err
+	}
+	return s.review(changes)
+}
@@ -0,0 +1,33 @@
+package stack
+
+import (
+	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRemainingImports(t *testing.T) {
+	imports := []cloudformation.ResourceToImport{
+		{LogicalResourceId: "Uploads", ResourceType: "AWS::S3::Bucket"},
+		{LogicalResourceId: "Orders", ResourceType: "AWS::DynamoDB::Table"},
+	}
+
+	testCases := map[string]struct {
+		existing map[string]bool
+		expected []string
+	}{
+		"none imported": {existing: map[string]bool{"Queue": true}, expected: []string{"Uploads", "Orders"}},
+		"some imported": {existing: map[string]bool{"Uploads": true}, expected: []string{"Orders"}},
+		"all imported":  {existing: map[string]bool{"Uploads": true, "Orders": true}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var names []string
+			for _, r := range remainingImports(imports, tc.existing) {
+				names = append(names, r.LogicalResourceId)
+			}
+			require.Equal(t, tc.expected, names)
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package stack
+
+import (
+	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"path"
+)
+
+// ProtectedChanges returns the changes that need an approval: removals and replacements of
+// resources whose type is protected, and every replacement when AllowReplacement is false
+func (s *Stack) ProtectedChanges(changes []cloudformation.ChangeResource) []cloudformation.ChangeResource {
+	var out []cloudformation.ChangeResource
+	for _, c := range changes {
+		replacement := c.Replacement == "True" || c.Replacement == "Conditional"
+		protected := s.isProtectedType(c.Type)
+
+		switch {
+		case c.Action == "Remove" && protected:
+			out = append(out, c)
+		case replacement && protected:
+			out = append(out, c)
+		case replacement && s.AllowReplacement != nil && !*s.AllowReplacement:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (s *Stack) isProtectedType(resourceType string) bool {
+	for _, pattern := range s.ProtectedResourceTypes {
+		if ok, _ := path.Match(pattern, resourceType); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package stack
+
+import (
+	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestProtectedChanges(t *testing.T) {
+	changes := []cloudformation.ChangeResource{
+		{Name: "Queue", Type: "AWS::SQS::Queue", Action: "Remove"},
+		{Name: "Topic", Type: "AWS::SNS::Topic", Action: "Modify", Replacement: "Conditional"},
+		{Name: "Orders", Type: "AWS::DynamoDB::Table", Action: "Remove"},
+		{Name: "Database", Type: "AWS::RDS::DBInstance", Action: "Modify", Replacement: "True"},
+		{Name: "Replica", Type: "AWS::RDS::DBInstance", Action: "Modify", Replacement: "False"},
+	}
+	no := false
+
+	testCases := map[string]struct {
+		stack    Stack
+		expected []string
+	}{
+		"no settings": {
+			stack: Stack{},
+		},
+		"protected types": {
+			stack:    Stack{ProtectedResourceTypes: []string{"AWS::RDS::*", "AWS::DynamoDB::Table"}},
+			expected: []string{"Orders", "Database"},
+		},
+		"replacements not allowed": {
+			stack:    Stack{AllowReplacement: &no},
+			expected: []string{"Topic", "Database"},
+		},
+		"both": {
+			stack:    Stack{AllowReplacement: &no, ProtectedResourceTypes: []string{"AWS::SQS::Queue"}},
+			expected: []string{"Queue", "Topic", "Database"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var out []string
+			for _, c := range tc.stack.ProtectedChanges(changes) {
+				out = append(out, c.Name)
+			}
+			require.Equal(t, tc.expected, out)
+		})
+	}
+}
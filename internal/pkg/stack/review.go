@@ -0,0 +1,141 @@
+package stack
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/approval"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"github.com/CleverTap/cfstack/internal/pkg/templates"
+	"github.com/fatih/color"
+	"os"
+	"sort"
+)
+
+// Reviewer is asked before the changes of a stack are executed, s.Changes holds the changes
+type Reviewer interface {
+	Review(s Stack) (approval.Decision, error)
+}
+
+// Preview returns the changes a deploy of the stack would make without executing them.
+// New stacks don't get a change set, every resource of the template is added.
+func (s *Stack) Preview() (*cloudformation.Changes, error) {
+	if !s.templateReady() {
+		err := s.prepareTemplate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	stackExists, err := s.Deployer.StackExists(s.StackName)
+	if err != nil {
+		return nil, err
+	}
+
+	imports, err := s.pendingImports(stackExists)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes *cloudformation.Changes
+	if len(imports) > 0 {
+		opts, err := s.importChangeSetOpts(imports)
+		if err != nil {
+			return nil, err
+		}
+		changes, err = s.Deployer.GetStackChanges(opts)
+		if err != nil {
+			return nil, err
+		}
+	} else if stackExists {
+		stackPolicy, err := json.Marshal(s.StackPolicy)
+		if err != nil {
+			return nil, err
+		}
+		changes, err = s.Deployer.GetStackChanges(&cloudformation.GetStackChangesOpts{
+			StackName:     s.StackName,
+			TemplateUrl:   s.TemplateUrl,
+			TemplateBody:  s.TemplateBody,
+			StackPolicy:   string(stackPolicy),
+			Parameters:    s.Parameters,
+			ChangeSetName: s.getChangeSetName(),
+			Type:          "UPDATE",
+			RoleArn:       s.RoleArn,
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		changes, err = s.newStackChanges()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	changes.Status = DiffSuccessStatus
+	if !stackExists && len(imports) == 0 {
+		err = s.checkPolicies(nil)
+	} else {
+		err = s.checkPolicies(changes.Resources)
+	}
+	return changes, err
+}
+
+// newStackChanges lists every resource of the template as added
+func (s *Stack) newStackChanges() (*cloudformation.Changes, error) {
+	t, err := templates.LoadTemplate(s.templateFile())
+	if err != nil {
+		return nil, err
+	}
+
+	changes := &cloudformation.Changes{Resources: []cloudformation.ChangeResource{}}
+	for name := range t.Section("Resources") {
+		changes.Resources = append(changes.Resources, cloudformation.ChangeResource{
+			Name:   name,
+			Type:   t.ResourceType(name),
+			Action: "Add",
+		})
+	}
+	sort.Slice(changes.Resources, func(i, j int) bool {
+		return changes.Resources[i].Name < changes.Resources[j].Name
+	})
+	return changes, nil
+}
+
+// reviewDelete hands the resources an existing stack would lose to the reviewer, false
+// means the stack is kept
+func (s *Stack) reviewDelete() (bool, error) {
+	if s.Reviewer == nil {
+		return true, nil
+	}
+	changes, err := s.Deployer.DeleteStackChanges(s.StackName)
+	if err != nil {
+		return false, err
+	}
+	return s.review(changes)
+}
+
+// review hands the changes to the reviewer, false means the stack is skipped
+func (s *Stack) review(changes *cloudformation.Changes) (bool, error) {
+	if s.Reviewer == nil {
+		return true, nil
+	}
+
+	s.Changes = changes
+	s.Changes.Status = DiffSuccessStatus
+
+	decision, err := s.Reviewer.Review(*s)
+	if err != nil {
+		return false, err
+	}
+
+	switch decision {
+	case approval.Skip:
+		color.New(color.FgYellow).Fprintf(os.Stdout, "    Skipping stack %s in region %s\n", s.StackName, s.Region)
+		return false, nil
+	case approval.Abort:
+		return false, approval.ErrAborted
+	default:
+		fmt.Printf("    Applying changes to stack %s\n", s.StackName)
+		return true, nil
+	}
+}
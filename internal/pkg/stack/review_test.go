@@ -0,0 +1,61 @@
+package stack
+
+import (
+	"github.com/CleverTap/cfstack/internal/pkg/approval"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type fakeReviewer struct {
+	decision approval.Decision
+	reviewed []string
+}
+
+func (r *fakeReviewer) Review(s Stack) (approval.Decision, error) {
+	r.reviewed = append(r.reviewed, s.StackName)
+	return r.decision, nil
+}
+
+func TestReview(t *testing.T) {
+	testCases := map[string]struct {
+		reviewer *fakeReviewer
+		apply    bool
+		err      error
+	}{
+		"apply": {reviewer: &fakeReviewer{decision: approval.Apply}, apply: true},
+		"skip":  {reviewer: &fakeReviewer{decision: approval.Skip}},
+		"abort": {reviewer: &fakeReviewer{decision: approval.Abort}, err: approval.ErrAborted},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := Stack{StackName: "Data", Reviewer: tc.reviewer}
+			apply, err := s.review(&cloudformation.Changes{})
+
+			require.Equal(t, tc.err, err)
+			require.Equal(t, tc.apply, apply)
+			require.Equal(t, []string{"Data"}, tc.reviewer.reviewed)
+			require.Equal(t, DiffSuccessStatus, s.Changes.Status)
+		})
+	}
+
+	apply, err := (&Stack{}).review(&cloudformation.Changes{})
+	require.NoError(t, err)
+	require.True(t, apply)
+
+	apply, err = (&Stack{Action: "DELETE"}).reviewDelete()
+	require.NoError(t, err)
+	require.True(t, apply)
+}
+
+func TestNewStackChanges(t *testing.T) {
+	s := Stack{TemplatePath: "sample-bucket-template.json", TemplateRootPath: "../../../testdata"}
+	changes, err := s.newStackChanges()
+	require.NoError(t, err)
+	require.NotEmpty(t, changes.Resources)
+	for _, c := range changes.Resources {
+		require.Equal(t, "Add", c.Action)
+		require.NotEmpty(t, c.Type)
+	}
+}
@@ -0,0 +1,14 @@
+package stack
+
+// SetRole picks the service role CloudFormation uses for the stack: the RoleArn of the stack
+// in the manifest, then the role given with --role, then the role of the region bootstrap
+func (s *Stack) SetRole(role, bootstrapRole string) {
+	if s.RoleArn != "" {
+		return
+	}
+	if role != "" {
+		s.RoleArn = role
+		return
+	}
+	s.RoleArn = bootstrapRole
+}
@@ -0,0 +1,40 @@
+package stack
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSetRole(t *testing.T) {
+	testCases := map[string]struct {
+		stackRole     string
+		role          string
+		bootstrapRole string
+		expected      string
+	}{
+		"stack role wins": {
+			stackRole:     "arn:aws:iam::123456789012:role/stack",
+			role:          "arn:aws:iam::123456789012:role/flag",
+			bootstrapRole: "arn:aws:iam::123456789012:role/bootstrap",
+			expected:      "arn:aws:iam::123456789012:role/stack",
+		},
+		"flag over bootstrap": {
+			role:          "arn:aws:iam::123456789012:role/flag",
+			bootstrapRole: "arn:aws:iam::123456789012:role/bootstrap",
+			expected:      "arn:aws:iam::123456789012:role/flag",
+		},
+		"bootstrap by default": {
+			bootstrapRole: "arn:aws:iam::123456789012:role/bootstrap",
+			expected:      "arn:aws:iam::123456789012:role/bootstrap",
+		},
+		"no role": {},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := Stack{RoleArn: tc.stackRole}
+			s.SetRole(tc.role, tc.bootstrapRole)
+			require.Equal(t, tc.expected, s.RoleArn)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"compress/flate"
 	"fmt"
 	"github.com/CleverTap/cfstack/internal/pkg/aws/s3"
+	"github.com/CleverTap/cfstack/internal/pkg/templates"
 	"github.com/CleverTap/cfstack/internal/pkg/util"
 	"github.com/golang/glog"
 	"github.com/google/uuid"
@@ -15,11 +16,7 @@ import (
 )
 
 func (s *Stack) packageServerlessTemplate() error {
-	sourceBucket, err := s.Deployer.GetStackResourcePhysicalId("cfstack-Init", "SourceS3Bucket")
-
-	if err != nil {
-		return err
-	}
+	sourceBucket := s.SourceBucket
 
 	template, err := goformation.Open(s.AbsTemplatePath)
 
@@ -60,7 +57,7 @@ func (s *Stack) packageServerlessTemplate() error {
 		uploadOpts := s3.Opts{
 			Bucket:   sourceBucket,
 			Filepath: zipFilePath,
-			Key:      "lambda/" + uid.String(),
+			Key:      templates.PackagesPrefix + uid.String(),
 		}
 
 		err = s.Uploader.UploadToS3(&uploadOpts)
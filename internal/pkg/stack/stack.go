@@ -3,12 +3,17 @@ package stack
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/approval"
 	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
 	"github.com/CleverTap/cfstack/internal/pkg/aws/s3"
+	"github.com/CleverTap/cfstack/internal/pkg/policy"
+	"github.com/CleverTap/cfstack/internal/pkg/secrets"
 	"github.com/CleverTap/cfstack/internal/pkg/templates"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/fatih/color"
 	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -21,24 +26,47 @@ const (
 )
 
 type Stack struct {
-	StackName        string                   `validate:"required",json:"StackName"`
-	TemplatePath     string                   `validate:"required",json:"TemplatePath"`
-	TemplateRootPath string                   `json:"TemplateRootPath"`
-	AbsTemplatePath  string                   `json:"AbsTemplatePath"`
-	TemplateUrl      string                   `json:"TemplateUrl"`
-	Action           string                   `validate:"required",json:"Action"`
-	StackPolicy      templates.PolicyDocument `validate:"required",json:"StackPolicy"`
-	Region           string                   `json:"Region"`
-	UID              string                   `json:"UID,omitempty"`
-	Bucket           string                   `json:"Bucket,omitempty"`
-	Parameters       map[string]string        `validate:"required",json:"Parameters"`
-	DeploymentOrder  int                      `json:"DeploymentOrder"`
-	Changes          *cloudformation.Changes
+	StackName              string                            `validate:"required" json:"StackName"`
+	TemplatePath           string                            `validate:"required" json:"TemplatePath"`
+	TemplateRootPath       string                            `json:"TemplateRootPath"`
+	AbsTemplatePath        string                            `json:"AbsTemplatePath"`
+	TemplateUrl            string                            `json:"TemplateUrl"`
+	TemplateBody           string                            `json:"-"`
+	Action                 string                            `validate:"required" json:"Action"`
+	StackPolicy            templates.PolicyDocument          `validate:"required" json:"StackPolicy"`
+	Region                 string                            `json:"Region"`
+	UID                    string                            `json:"UID,omitempty"`
+	Bucket                 string                            `json:"Bucket,omitempty"`
+	SourceBucket           string                            `json:"-"`
+	S3UrlStyle             s3.URLStyle                       `json:"-"`
+	Parameters             map[string]string                 `validate:"required" json:"Parameters"`
+	DeploymentOrder        int                               `json:"DeploymentOrder"`
+	Tags                   map[string]string                 `json:"Tags,omitempty"`
+	DependsOn              []string                          `json:"DependsOn,omitempty"`
+	PolicyWaivers          []policy.Waiver                   `json:"PolicyWaivers,omitempty"`
+	AllowReplacement       *bool                             `json:"AllowReplacement,omitempty"`
+	ProtectedResourceTypes []string                          `json:"ProtectedResourceTypes,omitempty"`
+	ResourcesToImport      []cloudformation.ResourceToImport `json:"ResourcesToImport,omitempty"`
+	Changes                *cloudformation.Changes
 
 	SuppressMessages bool
 
 	serverless bool
-	RoleArn    string
+	// uploadedTemplate is the template uploaded under the run prefix, it is kept with the
+	// history of the deployment
+	uploadedTemplate []byte
+	// RoleArn is the service role CloudFormation assumes for the stack, see SetRole
+	RoleArn string `json:"RoleArn,omitempty"`
+
+	TemplateVersion string `json:"-"`
+	GitSha          string `json:"-"`
+	RollbackOf      string `json:"-"`
+
+	Policies   *policy.Set        `json:"-"`
+	AccountId  string             `json:"-"`
+	Violations []policy.Violation `json:"-"`
+	Approver   *approval.Approver `json:"-"`
+	Reviewer   Reviewer           `json:"-"`
 
 	Deployer cloudformation.CloudFormation
 	Uploader s3.S3
@@ -66,14 +94,14 @@ func (s *Stack) getChangeSetName() string {
 
 func (s *Stack) Deploy() error {
 
-	if len(s.TemplateUrl) == 0 {
-		err := s.uploadTemplate()
+	if !s.templateReady() {
+		err := s.prepareTemplate()
 		if err != nil {
 			return err
 		}
 	}
 
-	err := s.Deployer.ValidateTemplate(s.TemplateUrl)
+	err := s.Deployer.ValidateTemplate(s.TemplateUrl, s.TemplateBody)
 
 	if err != nil {
 		return err
@@ -86,14 +114,27 @@ func (s *Stack) Deploy() error {
 	}
 
 	if s.Action == "DELETE" {
-		if stackExists {
-			err = s.delete()
-			if err != nil {
-				return err
-			}
+		if !stackExists {
+			fmt.Printf("There is no stack %s in region %s to delete\n", s.StackName, s.Region)
+			return nil
+		}
+		apply, err := s.reviewDelete()
+		if err != nil || !apply {
+			return err
+		}
+		err = s.delete()
+		if err != nil {
+			return err
 		}
-		fmt.Printf("There is no stack %s in region %s to delete\n", s.StackName, s.Region)
 	} else {
+		imports, err := s.pendingImports(stackExists)
+		if err != nil {
+			return err
+		}
+		if len(imports) > 0 {
+			return s.importResources(imports)
+		}
+
 		if stackExists {
 			err = s.update()
 			if err != nil {
@@ -110,14 +151,14 @@ func (s *Stack) Deploy() error {
 }
 
 func (s *Stack) Diff() error {
-	if len(s.TemplateUrl) == 0 {
-		err := s.uploadTemplate()
+	if !s.templateReady() {
+		err := s.prepareTemplate()
 		if err != nil {
 			return err
 		}
 	}
 
-	err := s.Deployer.ValidateTemplate(s.TemplateUrl)
+	err := s.Deployer.ValidateTemplate(s.TemplateUrl, s.TemplateBody)
 
 	if err != nil {
 		glog.Warningf("Template validation error for stack %s", s.StackName)
@@ -138,6 +179,12 @@ func (s *Stack) Diff() error {
 		changeSetType = "CREATE"
 	}
 
+	imports, err := s.pendingImports(stackExists)
+
+	if err != nil {
+		return err
+	}
+
 	stackPolicy, err := json.Marshal(s.StackPolicy)
 
 	if err != nil {
@@ -147,6 +194,7 @@ func (s *Stack) Diff() error {
 	getStackChangesOpts := cloudformation.GetStackChangesOpts{
 		StackName:     s.StackName,
 		TemplateUrl:   s.TemplateUrl,
+		TemplateBody:  s.TemplateBody,
 		StackPolicy:   string(stackPolicy),
 		Parameters:    s.Parameters,
 		ChangeSetName: s.getChangeSetName(),
@@ -154,6 +202,11 @@ func (s *Stack) Diff() error {
 		RoleArn:       s.RoleArn,
 	}
 
+	if len(imports) > 0 {
+		getStackChangesOpts.Type = cloudformation.ChangeSetTypeImport
+		getStackChangesOpts.ResourcesToImport = imports
+	}
+
 	changes, err := s.Deployer.GetStackChanges(&getStackChangesOpts)
 
 	if err != nil {
@@ -162,7 +215,23 @@ func (s *Stack) Diff() error {
 
 	s.Changes = changes
 
-	return nil
+	if stackExists {
+		s.warnDrift()
+	}
+
+	return s.checkPolicies(changes.Resources)
+}
+
+// warnDrift tells when the last drift detection found the stack drifted, the update would
+// overwrite the changes made outside of cfstack
+func (s *Stack) warnDrift() {
+	status, checked, err := s.Deployer.LastDriftStatus(s.StackName)
+	if err != nil || status != cloudformation.DriftStatusDrifted {
+		return
+	}
+	s.Changes.Drifted = true
+	color.New(color.FgYellow).Fprintf(os.Stdout, "    Stack %s in region %s had drifted when drift was last detected on %s, see cfstack drift\n",
+		s.StackName, s.Region, checked.Format("2006-01-02 15:04"))
 }
 
 func (s *Stack) Delete() error {
@@ -184,22 +253,44 @@ func (s *Stack) create() error {
 		fmt.Printf("    Stack doesn't exist, creating a new one\n")
 	}
 
+	err := s.checkPolicies(nil)
+	if err != nil {
+		return err
+	}
+	err = policy.Error(s.StackName, s.Violations)
+	if err != nil {
+		return err
+	}
+
+	if s.Reviewer != nil {
+		changes, err := s.newStackChanges()
+		if err != nil {
+			return err
+		}
+		apply, err := s.review(changes)
+		if err != nil || !apply {
+			return err
+		}
+	}
+
 	stackPolicy, err := json.Marshal(s.StackPolicy)
 	if err != nil {
 		return err
 	}
 
 	err = s.Deployer.CreateNewStack(&cloudformation.CreateStackOpts{
-		StackName:   s.StackName,
-		TemplateUrl: s.TemplateUrl,
-		Parameters:  s.Parameters,
-		StackPolicy: string(stackPolicy),
-		Serverless:  s.serverless,
-		RoleArn:     s.RoleArn,
+		StackName:    s.StackName,
+		TemplateUrl:  s.TemplateUrl,
+		TemplateBody: s.TemplateBody,
+		Parameters:   s.Parameters,
+		StackPolicy:  string(stackPolicy),
+		Serverless:   s.serverless,
+		RoleArn:      s.RoleArn,
 	})
 	if err != nil {
 		return err
 	}
+	s.recordDeployment()
 	if !s.SuppressMessages {
 		color.New(color.FgGreen).Fprintf(os.Stdout, "    Stack create complete\n")
 	}
@@ -219,6 +310,7 @@ func (s *Stack) update() error {
 	changes, err := s.Deployer.GetStackChanges(&cloudformation.GetStackChangesOpts{
 		StackName:     s.StackName,
 		TemplateUrl:   s.TemplateUrl,
+		TemplateBody:  s.TemplateBody,
 		StackPolicy:   string(stackPolicy),
 		Parameters:    s.Parameters,
 		ChangeSetName: s.getChangeSetName(),
@@ -231,7 +323,7 @@ func (s *Stack) update() error {
 			switch aerr.Code() {
 			case "ValidationError":
 				if strings.Contains(aerr.Message(), "IN_PROGRESS") {
-					color.New(color.FgYellow).Fprintf(os.Stdout, "    %s\n", aerr.Message())
+					color.New(color.FgYellow).Fprintf(os.Stdout, "    %s\n", secrets.Redact(aerr.Message()))
 					return nil
 				} else {
 					return fmt.Errorf("Unhandled AWS ValidationError for stack %s\n%s", s.StackName, aerr.Message())
@@ -243,6 +335,27 @@ func (s *Stack) update() error {
 		return err
 	}
 
+	err = s.checkPolicies(changes.Resources)
+	if err != nil {
+		return err
+	}
+	err = policy.Error(s.StackName, s.Violations)
+	if err != nil {
+		return err
+	}
+
+	err = s.Approver.Approve(s.Region, s.StackName, s.ProtectedChanges(changes.Resources))
+	if err != nil {
+		return err
+	}
+
+	if changes.StackPolicyChange || changes.ForceStackUpdate || len(changes.Resources) > 0 {
+		apply, err := s.review(changes)
+		if err != nil || !apply {
+			return err
+		}
+	}
+
 	if changes.StackPolicyChange == true && string(stackPolicy) != "{}" {
 		if !s.SuppressMessages {
 			fmt.Printf("    Changes in %s stack policy detected, it will be updated first\n", s.StackName)
@@ -269,17 +382,19 @@ func (s *Stack) update() error {
 	}
 
 	err = s.Deployer.UpdateExistingStack(&cloudformation.CreateStackOpts{
-		StackName:   s.StackName,
-		TemplateUrl: s.TemplateUrl,
-		Parameters:  s.Parameters,
-		StackPolicy: string(stackPolicy),
-		Serverless:  s.serverless,
-		RoleArn:     s.RoleArn,
+		StackName:    s.StackName,
+		TemplateUrl:  s.TemplateUrl,
+		TemplateBody: s.TemplateBody,
+		Parameters:   s.Parameters,
+		StackPolicy:  string(stackPolicy),
+		Serverless:   s.serverless,
+		RoleArn:      s.RoleArn,
 	})
 
 	if err != nil {
 		return err
 	}
+	s.recordDeployment()
 	if !s.SuppressMessages {
 		color.New(color.FgGreen).Fprintf(os.Stdout, "    Stack update complete\n")
 	}
@@ -299,19 +414,60 @@ func (s *Stack) delete() error {
 	return nil
 }
 
-func (s *Stack) uploadTemplate() error {
-	s.AbsTemplatePath = s.TemplatePath
+// checkPolicies evaluates the policies against the template that is deployed, the
+// packaged one for serverless stacks. changes is nil when the stack is created.
+func (s *Stack) checkPolicies(changes []cloudformation.ChangeResource) error {
+	s.Violations = nil
+	if s.Policies.IsEmpty() {
+		return nil
+	}
 
-	s.TemplateUrl = "https://s3-" + s.Region + ".amazonaws.com/" + s.Bucket + "/" + s.UID + s.TemplatePath
+	t, err := templates.LoadTemplate(s.templateFile())
+	if err != nil {
+		return err
+	}
 
-	if !filepath.IsAbs(s.TemplatePath) {
-		s.AbsTemplatePath = filepath.Join(s.TemplateRootPath, s.TemplatePath)
+	params, err := s.Deployer.ResolveParameters(s.StackName, s.Parameters)
+	if err != nil {
+		return err
+	}
 
-		s.TemplateUrl = "https://s3-" + s.Region + ".amazonaws.com/" + s.Bucket + "/" + s.UID + "/" + s.TemplatePath
+	s.Violations = s.Policies.Evaluate(policy.Input{
+		Account:    s.AccountId,
+		Region:     s.Region,
+		StackName:  s.StackName,
+		Template:   t,
+		Parameters: params,
+		Changes:    changes,
+		Waivers:    s.PolicyWaivers,
+	})
+	return nil
+}
+
+// templateFile is the local template of the stack, the packaged one once uploaded
+func (s *Stack) templateFile() string {
+	if s.AbsTemplatePath != "" {
+		return s.AbsTemplatePath
+	}
+	if filepath.IsAbs(s.TemplatePath) {
+		return s.TemplatePath
 	}
+	return filepath.Join(s.TemplateRootPath, s.TemplatePath)
+}
 
-	if s.Region == "us-east-1" {
-		s.TemplateUrl = strings.Replace(s.TemplateUrl, "https://s3-", "https://s3.", -1)
+// templateReady reports whether the template was already passed inline or uploaded
+func (s *Stack) templateReady() bool {
+	return s.TemplateUrl != "" || s.TemplateBody != ""
+}
+
+// prepareTemplate packages serverless templates and checks the template against the
+// CloudFormation quotas. Templates small enough are passed inline as TemplateBody, the
+// others are uploaded to the templates bucket and passed by URL.
+func (s *Stack) prepareTemplate() error {
+	s.AbsTemplatePath = s.TemplatePath
+
+	if !filepath.IsAbs(s.TemplatePath) {
+		s.AbsTemplatePath = filepath.Join(s.TemplateRootPath, s.TemplatePath)
 	}
 
 	isServerLessStack, err := templates.IsServerlessTemplate(s.AbsTemplatePath)
@@ -330,18 +486,60 @@ func (s *Stack) uploadTemplate() error {
 		}
 	}
 
+	b, err := ioutil.ReadFile(s.AbsTemplatePath)
+
+	if err != nil {
+		return err
+	}
+
+	t, err := templates.ParseTemplate(b)
+
+	if err != nil {
+		return errors.Wrapf(err, "template of stack %s", s.StackName)
+	}
+
+	if problems := t.CheckLimits(); len(problems) > 0 {
+		return errors.Errorf("template of stack %s exceeds CloudFormation quotas: %s", s.StackName, strings.Join(problems, ", "))
+	}
+
+	body, err := templates.PrepareBody(b)
+
+	if err != nil {
+		return errors.Wrapf(err, "template of stack %s", s.StackName)
+	}
+
+	if body.Minified && !s.SuppressMessages {
+		fmt.Printf("    Minified template of stack %s to %d bytes to fit CloudFormation limits\n", s.StackName, len(body.Content))
+	}
+
+	if body.Inline {
+		s.TemplateBody = string(body.Content)
+		return nil
+	}
+
+	key := templates.RunsPrefix + s.UID + "/" + s.TemplatePath
+
+	templateUrl, err := s3.ObjectURL(s.Region, s.Bucket, key, s.S3UrlStyle)
+
+	if err != nil {
+		return err
+	}
+	s.TemplateUrl = templateUrl
+
 	uploaderOpts := s3.Opts{
 		Bucket:   s.Bucket,
 		Filepath: s.AbsTemplatePath,
-		Key:      s.UID + "/" + s.TemplatePath,
+		Key:      key,
+		Body:     body.Content,
 	}
 
-	err = s.Uploader.UploadToS3(&uploaderOpts)
+	s.TemplateVersion, err = s.Uploader.Upload(&uploaderOpts)
 
 	if err != nil {
 		glog.Errorf("template upload for stack %s failed", s.StackName)
 		return err
 	}
+	s.uploadedTemplate = body.Content
 
 	//if isServerLessStack {
 	//	err = os.Remove(s.AbsTemplatePath)
@@ -8,6 +8,7 @@ type PolicyDocument struct {
 
 type Statement struct {
 	Action    interface{} `json:"Action,omitempty"`
+	Condition interface{} `json:"Condition,omitempty"`
 	Effect    string      `json:"Effect,omitempty"`
 	Principal interface{} `json:"Principal,omitempty"`
 	Resource  interface{} `json:"Resource,omitempty"`
@@ -1,31 +1,150 @@
 package templates
 
 import (
+	"encoding/json"
 	"github.com/awslabs/goformation/v3/cloudformation"
 	"github.com/awslabs/goformation/v3/cloudformation/iam"
+	"github.com/awslabs/goformation/v3/cloudformation/kms"
 	"github.com/awslabs/goformation/v3/cloudformation/s3"
+	"github.com/pkg/errors"
+	"regexp"
+	"strconv"
 )
 
-func GenerateInitTemplate() (string, error) {
-	sTemplate := cloudformation.NewTemplate()
+// BootstrapVersion is the version of the cfstack-Init template, bump it whenever
+// GenerateInitTemplate changes so that regions are told to run cfstack init upgrade
+const BootstrapVersion = 6
 
-	sourceS3Bucket := &s3.Bucket{
-		AccessControl: "BucketOwnerFullControl",
-		VersioningConfiguration: &s3.Bucket_VersioningConfiguration{
-			Status: "Enabled",
-		},
+// BootstrapVersionOutput is the output of the cfstack-Init stack holding BootstrapVersion
+const BootstrapVersionOutput = "BootstrapVersion"
+
+// BootstrapOptionsOutput is the output holding the InitOptions the stack was deployed
+// with, upgrades start from them
+const BootstrapOptionsOutput = "BootstrapOptions"
+
+// ServiceRoleOutput is the output holding the ARN of the CloudFormation service role,
+// stacks without a role of their own are deployed with it
+const ServiceRoleOutput = "CloudFormationServiceRoleArn"
+
+// RunsPrefix is the key prefix of the templates uploaded by each run, they expire after
+// ExpirationDays. Deployed templates are copied next to their history entry, which is
+// never expired so that rollbacks keep working.
+const RunsPrefix = "runs/"
+
+// PackagesPrefix is the key prefix of the lambda packages in the SourceS3Bucket, they
+// expire after PackageExpirationDays
+const PackagesPrefix = "lambda/"
+
+// DefaultAllowedServices are the services the CloudFormation service role may manage
+// unless init is given another list
+var DefaultAllowedServices = []string{
+	"acm", "apigateway", "application-autoscaling", "autoscaling", "cloudformation", "cloudfront", "cloudwatch",
+	"dynamodb", "ec2", "ecr", "ecs", "elasticache", "elasticloadbalancing", "events", "iam", "kinesis", "kms",
+	"lambda", "logs", "rds", "route53", "s3", "secretsmanager", "sns", "sqs", "ssm", "states",
+}
+
+var servicePattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// InitOptions configure the buckets and the service role of the bootstrap template
+type InitOptions struct {
+	// KmsKeyArn encrypts the buckets with an existing key, CreateKmsKey with a key created
+	// by the stack. Without either the buckets use S3 managed keys.
+	KmsKeyArn         string `json:"KmsKeyArn,omitempty"`
+	CreateKmsKey      bool   `json:"CreateKmsKey,omitempty"`
+	BlockPublicAccess bool   `json:"BlockPublicAccess"`
+	EnforceTLS        bool   `json:"EnforceTLS"`
+	// ExpirationDays expires the objects under RunsPrefix that many days after upload,
+	// PackageExpirationDays the ones under PackagesPrefix and NoncurrentExpirationDays
+	// removes old versions. 0 keeps them forever.
+	ExpirationDays           int      `json:"ExpirationDays"`
+	PackageExpirationDays    int      `json:"PackageExpirationDays"`
+	NoncurrentExpirationDays int      `json:"NoncurrentExpirationDays"`
+	AllowedServices          []string `json:"AllowedServices"`
+}
+
+func DefaultInitOptions() InitOptions {
+	return InitOptions{
+		BlockPublicAccess:        true,
+		EnforceTLS:               true,
+		ExpirationDays:           90,
+		PackageExpirationDays:    365,
+		NoncurrentExpirationDays: 30,
+		AllowedServices:          append([]string{}, DefaultAllowedServices...),
 	}
+}
 
-	sTemplate.Resources["SourceS3Bucket"] = sourceS3Bucket
+// ParseInitOptions reads the options recorded in the outputs of cfstack-Init, stacks
+// deployed before options were recorded get the defaults. Their service role allowed
+// every service so it is kept that way, only --allowed-services narrows it.
+func ParseInitOptions(outputs map[string]string) (InitOptions, error) {
+	o := DefaultInitOptions()
+	recorded, ok := outputs[BootstrapOptionsOutput]
+	if !ok {
+		o.AllowedServices = []string{"*"}
+		return o, nil
+	}
+	err := json.Unmarshal([]byte(recorded), &o)
+	if err != nil {
+		return o, errors.Wrapf(err, "invalid %s output", BootstrapOptionsOutput)
+	}
+	return o, nil
+}
 
-	templatesS3Bucket := &s3.Bucket{
-		AccessControl: "BucketOwnerFullControl",
-		VersioningConfiguration: &s3.Bucket_VersioningConfiguration{
-			Status: "Enabled",
-		},
+func (o InitOptions) Validate() error {
+	if o.KmsKeyArn != "" && o.CreateKmsKey {
+		return errors.New("a KMS key can't be both provided and created")
+	}
+	if o.ExpirationDays < 0 || o.PackageExpirationDays < 0 || o.NoncurrentExpirationDays < 0 {
+		return errors.New("expiration days can't be negative")
+	}
+	if len(o.AllowedServices) == 0 {
+		return errors.New("the service role needs at least one allowed service")
+	}
+	for _, s := range o.AllowedServices {
+		if s != "*" && !servicePattern.MatchString(s) {
+			return errors.Errorf("invalid service %q, use the IAM prefix of the service such as s3 or lambda", s)
+		}
 	}
+	return nil
+}
 
-	sTemplate.Resources["TemplatesS3Bucket"] = templatesS3Bucket
+func GenerateInitTemplate(o InitOptions) (string, error) {
+	err := o.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	sTemplate := cloudformation.NewTemplate()
+
+	keyArn := o.KmsKeyArn
+	if o.CreateKmsKey {
+		sTemplate.Resources["BootstrapKmsKey"] = &kms.Key{
+			Description:       "Encrypts the cfstack buckets",
+			EnableKeyRotation: true,
+			KeyPolicy: PolicyDocument{
+				Version: "2012-10-17",
+				Statement: []Statement{
+					{
+						Sid:    "AllowAccountToManageKey",
+						Effect: "Allow",
+						Principal: map[string]interface{}{
+							"AWS": cloudformation.Sub("arn:${AWS::Partition}:iam::${AWS::AccountId}:root"),
+						},
+						Action:   "kms:*",
+						Resource: "*",
+					},
+				},
+			},
+		}
+		keyArn = cloudformation.GetAtt("BootstrapKmsKey", "Arn")
+	}
+
+	for _, name := range []string{"SourceS3Bucket", "TemplatesS3Bucket"} {
+		sTemplate.Resources[name] = o.bucket(keyArn)
+		if o.EnforceTLS {
+			sTemplate.Resources[name+"Policy"] = tlsOnlyPolicy(name)
+		}
+	}
 
 	cloudFormationServiceIamRole := &iam.Role{
 		AssumeRolePolicyDocument: PolicyDocument{
@@ -47,11 +166,9 @@ func GenerateInitTemplate() (string, error) {
 		PolicyDocument: PolicyDocument{
 			Statement: []Statement{
 				{
-					Sid:    "AllowInteractionWithEcsCluster",
-					Effect: "Allow",
-					Action: []string{
-						"*",
-					},
+					Sid:      "AllowManagingAllowedServices",
+					Effect:   "Allow",
+					Action:   serviceActions(o.AllowedServices),
 					Resource: "*",
 				},
 			},
@@ -70,11 +187,127 @@ func GenerateInitTemplate() (string, error) {
 
 	sTemplate.Resources["CloudFormationServiceIamPolicy"] = cloudFormationServiceIamPolicy
 
+	options, err := json.Marshal(o)
+	if err != nil {
+		return "", err
+	}
+
+	sTemplate.Outputs[BootstrapVersionOutput] = map[string]interface{}{
+		"Description": "Version of the cfstack bootstrap template",
+		"Value":       strconv.Itoa(BootstrapVersion),
+	}
+	sTemplate.Outputs[BootstrapOptionsOutput] = map[string]interface{}{
+		"Description": "Options cfstack init was run with",
+		"Value":       string(options),
+	}
+	sTemplate.Outputs[ServiceRoleOutput] = map[string]interface{}{
+		"Description": "Service role CloudFormation assumes to deploy cfstack stacks",
+		"Value":       cloudformation.GetAtt("CloudFormationServiceIamRole", "Arn"),
+	}
+	if keyArn != "" {
+		sTemplate.Outputs["KmsKeyArn"] = map[string]interface{}{
+			"Description": "Key encrypting the cfstack buckets",
+			"Value":       keyArn,
+		}
+	}
+
 	j, err := sTemplate.JSON()
 
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 
 	return string(j), nil
 }
+
+func (o InitOptions) bucket(keyArn string) *s3.Bucket {
+	encryption := &s3.Bucket_ServerSideEncryptionByDefault{SSEAlgorithm: "AES256"}
+	if keyArn != "" {
+		encryption = &s3.Bucket_ServerSideEncryptionByDefault{SSEAlgorithm: "aws:kms", KMSMasterKeyID: keyArn}
+	}
+
+	bucket := &s3.Bucket{
+		AccessControl: "BucketOwnerFullControl",
+		VersioningConfiguration: &s3.Bucket_VersioningConfiguration{
+			Status: "Enabled",
+		},
+		BucketEncryption: &s3.Bucket_BucketEncryption{
+			ServerSideEncryptionConfiguration: []s3.Bucket_ServerSideEncryptionRule{
+				{ServerSideEncryptionByDefault: encryption},
+			},
+		},
+		LifecycleConfiguration: &s3.Bucket_LifecycleConfiguration{
+			Rules: []s3.Bucket_Rule{
+				{
+					Id:                                "cfstack-noncurrent-expiration",
+					Status:                            "Enabled",
+					NoncurrentVersionExpirationInDays: o.NoncurrentExpirationDays,
+					AbortIncompleteMultipartUpload: &s3.Bucket_AbortIncompleteMultipartUpload{
+						DaysAfterInitiation: 7,
+					},
+				},
+			},
+		},
+	}
+
+	if o.ExpirationDays > 0 {
+		bucket.LifecycleConfiguration.Rules = append(bucket.LifecycleConfiguration.Rules, s3.Bucket_Rule{
+			Id:               "cfstack-expiration",
+			Status:           "Enabled",
+			Prefix:           RunsPrefix,
+			ExpirationInDays: o.ExpirationDays,
+		})
+	}
+	if o.PackageExpirationDays > 0 {
+		bucket.LifecycleConfiguration.Rules = append(bucket.LifecycleConfiguration.Rules, s3.Bucket_Rule{
+			Id:               "cfstack-package-expiration",
+			Status:           "Enabled",
+			Prefix:           PackagesPrefix,
+			ExpirationInDays: o.PackageExpirationDays,
+		})
+	}
+
+	if o.BlockPublicAccess {
+		bucket.PublicAccessBlockConfiguration = &s3.Bucket_PublicAccessBlockConfiguration{
+			BlockPublicAcls:       true,
+			BlockPublicPolicy:     true,
+			IgnorePublicAcls:      true,
+			RestrictPublicBuckets: true,
+		}
+	}
+	return bucket
+}
+
+// tlsOnlyPolicy denies every request to the bucket that isn't made over TLS
+func tlsOnlyPolicy(bucket string) *s3.BucketPolicy {
+	arn := cloudformation.GetAtt(bucket, "Arn")
+	return &s3.BucketPolicy{
+		Bucket: cloudformation.Ref(bucket),
+		PolicyDocument: PolicyDocument{
+			Version: "2012-10-17",
+			Statement: []Statement{
+				{
+					Sid:       "DenyInsecureTransport",
+					Effect:    "Deny",
+					Principal: "*",
+					Action:    "s3:*",
+					Resource:  []string{arn, cloudformation.Join("", []string{arn, "/*"})},
+					Condition: map[string]interface{}{
+						"Bool": map[string]string{"aws:SecureTransport": "false"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func serviceActions(services []string) []string {
+	actions := make([]string, 0, len(services))
+	for _, s := range services {
+		if s == "*" {
+			return []string{"*"}
+		}
+		actions = append(actions, s+":*")
+	}
+	return actions
+}
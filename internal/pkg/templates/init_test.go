@@ -0,0 +1,140 @@
+package templates
+
+import (
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+)
+
+func TestGenerateInitTemplate(t *testing.T) {
+	body, err := GenerateInitTemplate(DefaultInitOptions())
+	require.NoError(t, err)
+
+	tmpl, err := ParseTemplate([]byte(body))
+	require.NoError(t, err)
+
+	output, ok := tmpl.Section("Outputs")[BootstrapVersionOutput].(map[string]interface{})
+	require.True(t, ok)
+	require.Equal(t, strconv.Itoa(BootstrapVersion), output["Value"])
+
+	for _, bucket := range []string{"TemplatesS3Bucket", "SourceS3Bucket"} {
+		require.Equal(t, "AWS::S3::Bucket", tmpl.ResourceType(bucket))
+	}
+}
+
+func TestGenerateInitTemplateOptions(t *testing.T) {
+	testCases := map[string]struct {
+		options   func(o *InitOptions)
+		resources map[string]string
+		missing   []string
+		keyOutput bool
+		err       string
+	}{
+		"defaults": {
+			options: func(o *InitOptions) {},
+			resources: map[string]string{
+				"TemplatesS3BucketPolicy": "AWS::S3::BucketPolicy",
+				"SourceS3BucketPolicy":    "AWS::S3::BucketPolicy",
+			},
+			missing: []string{"BootstrapKmsKey"},
+		},
+		"created key": {
+			options:   func(o *InitOptions) { o.CreateKmsKey = true },
+			resources: map[string]string{"BootstrapKmsKey": "AWS::KMS::Key"},
+			keyOutput: true,
+		},
+		"provided key": {
+			options:   func(o *InitOptions) { o.KmsKeyArn = "arn:aws:kms:us-east-1:123456789012:key/abc" },
+			missing:   []string{"BootstrapKmsKey"},
+			keyOutput: true,
+		},
+		"without tls": {
+			options: func(o *InitOptions) { o.EnforceTLS = false },
+			missing: []string{"TemplatesS3BucketPolicy", "SourceS3BucketPolicy"},
+		},
+		"both keys": {
+			options: func(o *InitOptions) {
+				o.CreateKmsKey = true
+				o.KmsKeyArn = "arn:aws:kms:us-east-1:123456789012:key/abc"
+			},
+			err: "a KMS key can't be both provided and created",
+		},
+		"negative expiration": {
+			options: func(o *InitOptions) { o.ExpirationDays = -1 },
+			err:     "expiration days can't be negative",
+		},
+		"no services": {
+			options: func(o *InitOptions) { o.AllowedServices = nil },
+			err:     "the service role needs at least one allowed service",
+		},
+		"invalid service": {
+			options: func(o *InitOptions) { o.AllowedServices = []string{"s3:*"} },
+			err:     `invalid service "s3:*", use the IAM prefix of the service such as s3 or lambda`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			o := DefaultInitOptions()
+			tc.options(&o)
+
+			body, err := GenerateInitTemplate(o)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			tmpl, err := ParseTemplate([]byte(body))
+			require.NoError(t, err)
+
+			for id, resourceType := range tc.resources {
+				require.Equal(t, resourceType, tmpl.ResourceType(id))
+			}
+			for _, id := range tc.missing {
+				require.NotContains(t, tmpl.Section("Resources"), id)
+			}
+			_, ok := tmpl.Section("Outputs")["KmsKeyArn"]
+			require.Equal(t, tc.keyOutput, ok)
+
+			outputs := map[string]string{}
+			output := tmpl.Section("Outputs")[BootstrapOptionsOutput].(map[string]interface{})
+			outputs[BootstrapOptionsOutput] = output["Value"].(string)
+			parsed, err := ParseInitOptions(outputs)
+			require.NoError(t, err)
+			require.Equal(t, o, parsed)
+		})
+	}
+}
+
+func TestServiceActions(t *testing.T) {
+	require.Equal(t, []string{"s3:*", "lambda:*"}, serviceActions([]string{"s3", "lambda"}))
+	require.Equal(t, []string{"*"}, serviceActions([]string{"s3", "*"}))
+}
+
+func TestBucketLifecycle(t *testing.T) {
+	expiring := func(o InitOptions) map[string]int {
+		prefixes := map[string]int{}
+		for _, rule := range o.bucket("").LifecycleConfiguration.Rules {
+			if rule.ExpirationInDays > 0 {
+				prefixes[rule.Prefix] = rule.ExpirationInDays
+			}
+		}
+		return prefixes
+	}
+
+	o := DefaultInitOptions()
+	require.Equal(t, map[string]int{RunsPrefix: 90, PackagesPrefix: 365}, expiring(o))
+
+	o.ExpirationDays = 0
+	o.PackageExpirationDays = 0
+	require.Empty(t, expiring(o))
+	require.Len(t, o.bucket("").LifecycleConfiguration.Rules, 1)
+}
+
+func TestParseInitOptionsUnrecorded(t *testing.T) {
+	o, err := ParseInitOptions(map[string]string{BootstrapVersionOutput: "2"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"*"}, o.AllowedServices)
+	require.True(t, o.BlockPublicAccess)
+}
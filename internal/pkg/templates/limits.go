@@ -0,0 +1,74 @@
+package templates
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+)
+
+// Quotas CloudFormation enforces on a template
+const (
+	MaxTemplateBodyBytes = 51200
+	MaxTemplateUrlBytes  = 1024 * 1024
+	MaxResources         = 500
+	MaxParameters        = 200
+	MaxOutputs           = 200
+)
+
+// CheckLimits returns a message for every section of the template declaring more entries
+// than CloudFormation accepts
+func (t Template) CheckLimits() []string {
+	var problems []string
+	for _, l := range []struct {
+		section string
+		max     int
+	}{
+		{"Resources", MaxResources},
+		{"Parameters", MaxParameters},
+		{"Outputs", MaxOutputs},
+	} {
+		if n := len(t.Section(l.section)); n > l.max {
+			problems = append(problems, fmt.Sprintf("template declares %d %s, CloudFormation allows %d. Move some of them into a nested stack",
+				n, l.section, l.max))
+		}
+	}
+	return problems
+}
+
+// Body is a template body ready to be passed to CloudFormation
+type Body struct {
+	Content []byte
+	// Inline bodies fit TemplateBody, the others have to be uploaded and passed by URL
+	Inline   bool
+	Minified bool
+}
+
+// PrepareBody decides how a template is passed to CloudFormation. Bodies up to 51,200 bytes
+// are passed inline, larger ones through S3. JSON templates are minified when that brings
+// them under one of the limits, templates over 1 MB even then are rejected.
+func PrepareBody(b []byte) (*Body, error) {
+	if len(b) <= MaxTemplateBodyBytes {
+		return &Body{Content: b, Inline: true}, nil
+	}
+
+	size := len(b)
+	if IsJSON(b) {
+		compact := &bytes.Buffer{}
+		if json.Compact(compact, b) == nil {
+			switch {
+			case compact.Len() <= MaxTemplateBodyBytes:
+				return &Body{Content: compact.Bytes(), Inline: true, Minified: true}, nil
+			case len(b) > MaxTemplateUrlBytes && compact.Len() <= MaxTemplateUrlBytes:
+				return &Body{Content: compact.Bytes(), Minified: true}, nil
+			}
+			size = compact.Len()
+		}
+	}
+
+	if size > MaxTemplateUrlBytes {
+		return nil, errors.Errorf("template is %d bytes, over the %d bytes CloudFormation accepts from S3. Move resources into a nested stack",
+			size, MaxTemplateUrlBytes)
+	}
+	return &Body{Content: b}, nil
+}
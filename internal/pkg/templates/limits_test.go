@@ -0,0 +1,114 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+// jsonTemplate returns an indented JSON template with n resources
+func jsonTemplate(t *testing.T, n int) []byte {
+	resources := map[string]interface{}{}
+	for i := 0; i < n; i++ {
+		resources[fmt.Sprintf("Queue%d", i)] = map[string]interface{}{
+			"Type":       "AWS::SQS::Queue",
+			"Properties": map[string]interface{}{"QueueName": fmt.Sprintf("queue-%d", i)},
+		}
+	}
+	b, err := json.MarshalIndent(map[string]interface{}{"Resources": resources}, "", "                ")
+	require.NoError(t, err)
+	return b
+}
+
+func TestPrepareBody(t *testing.T) {
+	testCases := map[string]struct {
+		body     func(t *testing.T) []byte
+		inline   bool
+		minified bool
+		err      string
+	}{
+		"small template is inline": {
+			body:   func(t *testing.T) []byte { return jsonTemplate(t, 10) },
+			inline: true,
+		},
+		"minified to fit inline": {
+			body:     func(t *testing.T) []byte { return jsonTemplate(t, 400) },
+			inline:   true,
+			minified: true,
+		},
+		"large template goes through S3": {
+			body: func(t *testing.T) []byte { return jsonTemplate(t, 1500) },
+		},
+		"minified to fit S3": {
+			body:     func(t *testing.T) []byte { return jsonTemplate(t, 7000) },
+			minified: true,
+		},
+		"large YAML template goes through S3": {
+			body: func(t *testing.T) []byte {
+				return []byte("Resources:\n" + strings.Repeat("  # padding to push the template over the inline limit\n", 1000))
+			},
+		},
+		"too large even minified": {
+			body: func(t *testing.T) []byte {
+				return []byte(`{"Description": "` + strings.Repeat("x", MaxTemplateUrlBytes) + `"}`)
+			},
+			err: "template is 1048594 bytes, over the 1048576 bytes CloudFormation accepts from S3. Move resources into a nested stack",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			b := tc.body(t)
+			body, err := PrepareBody(b)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.inline, body.Inline)
+			require.Equal(t, tc.minified, body.Minified)
+			if !tc.minified {
+				require.Equal(t, b, body.Content)
+			}
+			if body.Inline {
+				require.True(t, len(body.Content) <= MaxTemplateBodyBytes)
+			}
+			require.True(t, len(body.Content) <= MaxTemplateUrlBytes)
+		})
+	}
+}
+
+func TestCheckLimits(t *testing.T) {
+	section := func(n int) map[string]interface{} {
+		m := map[string]interface{}{}
+		for i := 0; i < n; i++ {
+			m[fmt.Sprintf("Entry%d", i)] = map[string]interface{}{}
+		}
+		return m
+	}
+
+	testCases := map[string]struct {
+		template Template
+		expected []string
+	}{
+		"within limits": {
+			template: Template{"Resources": section(500), "Parameters": section(200), "Outputs": section(200)},
+		},
+		"over every limit": {
+			template: Template{"Resources": section(501), "Parameters": section(201), "Outputs": section(201)},
+			expected: []string{
+				"template declares 501 Resources, CloudFormation allows 500. Move some of them into a nested stack",
+				"template declares 201 Parameters, CloudFormation allows 200. Move some of them into a nested stack",
+				"template declares 201 Outputs, CloudFormation allows 200. Move some of them into a nested stack",
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.template.CheckLimits())
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package templates
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// Rule is a single lint check. Check returns findings without Rule and Severity set,
+// the engine fills them in from the rule and the config.
+type Rule struct {
+	ID          string
+	Description string
+	Severity    Severity
+	Check       func(t Template) []Finding
+}
+
+// Finding is a problem reported by a rule. Resource is set when the problem is in a
+// resource so that the resource Metadata can suppress it.
+type Finding struct {
+	Rule     string
+	Severity Severity
+	Path     string
+	Resource string
+	Message  string
+}
+
+// LintConfig enables, disables or changes the severity of rules by ID
+type LintConfig struct {
+	Rules map[string]RuleConfig `json:"Rules"`
+}
+
+type RuleConfig struct {
+	Enabled  *bool    `json:"Enabled,omitempty"`
+	Severity Severity `json:"Severity,omitempty"`
+}
+
+var rules []Rule
+
+// RegisterRule adds a rule to the lint engine
+func RegisterRule(r Rule) {
+	rules = append(rules, r)
+}
+
+// Rules returns every registered rule sorted by ID
+func Rules() []Rule {
+	out := append([]Rule{}, rules...)
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// LoadLintConfig reads a lint config file, unknown rules and severities are an error
+func LoadLintConfig(path string) (*LintConfig, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &LintConfig{}
+	err = json.Unmarshal(b, c)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid lint config %s", path)
+	}
+
+	known := map[string]bool{}
+	for _, r := range rules {
+		known[r.ID] = true
+	}
+	for id, rc := range c.Rules {
+		if !known[id] {
+			return nil, errors.Errorf("unknown lint rule %s in %s", id, path)
+		}
+		switch rc.Severity {
+		case "", SeverityError, SeverityWarning, SeverityInfo:
+		default:
+			return nil, errors.Errorf("invalid severity %s for lint rule %s in %s", rc.Severity, id, path)
+		}
+	}
+	return c, nil
+}
+
+// Lint runs every enabled rule against the template. Rules listed in Metadata.cfstack.lint.ignore
+// at the top of the template or in a resource are suppressed for the template or the resource.
+func (t Template) Lint(c *LintConfig) []Finding {
+	ignored := ignoredRules(t["Metadata"])
+
+	var findings []Finding
+	for _, r := range Rules() {
+		severity := r.Severity
+		if c != nil {
+			if rc, ok := c.Rules[r.ID]; ok {
+				if rc.Enabled != nil && !*rc.Enabled {
+					continue
+				}
+				if rc.Severity != "" {
+					severity = rc.Severity
+				}
+			}
+		}
+		if ignored[r.ID] {
+			continue
+		}
+
+		for _, f := range r.Check(t) {
+			if f.Resource != "" && ignoredRules(t.Resource(f.Resource)["Metadata"])[r.ID] {
+				continue
+			}
+			f.Rule = r.ID
+			f.Severity = severity
+			findings = append(findings, f)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Path != findings[j].Path {
+			return findings[i].Path < findings[j].Path
+		}
+		return findings[i].Rule < findings[j].Rule
+	})
+	return findings
+}
+
+// ignoredRules reads {"cfstack": {"lint": {"ignore": ["rule-id"]}}} from a Metadata section
+func ignoredRules(metadata interface{}) map[string]bool {
+	ignored := map[string]bool{}
+	m, _ := metadata.(map[string]interface{})
+	cfstack, _ := m["cfstack"].(map[string]interface{})
+	lint, _ := cfstack["lint"].(map[string]interface{})
+	ids, _ := lint["ignore"].([]interface{})
+	for _, id := range ids {
+		if s, ok := id.(string); ok {
+			ignored[strings.TrimSpace(s)] = true
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,408 @@
+package templates
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var pseudoParameters = map[string]bool{
+	"AWS::AccountId":        true,
+	"AWS::NotificationARNs": true,
+	"AWS::NoValue":          true,
+	"AWS::Partition":        true,
+	"AWS::Region":           true,
+	"AWS::StackId":          true,
+	"AWS::StackName":        true,
+	"AWS::URLSuffix":        true,
+}
+
+// statefulTypes hold data that is lost when the resource is deleted or replaced
+var statefulTypes = map[string]bool{
+	"AWS::DocDB::DBCluster":              true,
+	"AWS::DynamoDB::GlobalTable":         true,
+	"AWS::DynamoDB::Table":               true,
+	"AWS::EC2::Volume":                   true,
+	"AWS::EFS::FileSystem":               true,
+	"AWS::ElastiCache::ReplicationGroup": true,
+	"AWS::Elasticsearch::Domain":         true,
+	"AWS::Neptune::DBCluster":            true,
+	"AWS::OpenSearchService::Domain":     true,
+	"AWS::RDS::DBCluster":                true,
+	"AWS::RDS::DBInstance":               true,
+	"AWS::Redshift::Cluster":             true,
+	"AWS::S3::Bucket":                    true,
+	"AWS::Serverless::SimpleTable":       true,
+}
+
+var (
+	regionPattern  = regexp.MustCompile(`\b(us|eu|ap|sa|ca|me|af|il|mx|cn)(-gov|-iso|-isob)?-(north|south|east|west|central|northeast|northwest|southeast|southwest)-\d\b`)
+	accountPattern = regexp.MustCompile(`(^|[^0-9])[0-9]{12}([^0-9]|$)`)
+	subVariable    = regexp.MustCompile(`\$\{([^!}][^}]*)\}`)
+)
+
+func init() {
+	RegisterRule(Rule{
+		ID:          "missing-ref-target",
+		Description: "Ref and Fn::GetAtt in resources and conditions must point to a parameter or resource of the template",
+		Severity:    SeverityError,
+		Check: func(t Template) []Finding {
+			return missingTargets(t, "Resources", "Conditions")
+		},
+	})
+	RegisterRule(Rule{
+		ID:          "missing-output-target",
+		Description: "Outputs must reference resources and parameters of the template",
+		Severity:    SeverityError,
+		Check: func(t Template) []Finding {
+			return missingTargets(t, "Outputs")
+		},
+	})
+	RegisterRule(Rule{
+		ID:          "unused-parameter",
+		Description: "Parameters should be referenced somewhere in the template",
+		Severity:    SeverityWarning,
+		Check:       unusedParameters,
+	})
+	RegisterRule(Rule{
+		ID:          "unused-condition",
+		Description: "Conditions should be used by a resource, an output or another condition",
+		Severity:    SeverityWarning,
+		Check:       unusedConditions,
+	})
+	RegisterRule(Rule{
+		ID:          "circular-depends-on",
+		Description: "DependsOn chains must not loop back to the resource",
+		Severity:    SeverityError,
+		Check:       circularDependsOn,
+	})
+	RegisterRule(Rule{
+		ID:          "hardcoded-region",
+		Description: "Use AWS::Region instead of writing region names in resources and outputs",
+		Severity:    SeverityWarning,
+		Check: func(t Template) []Finding {
+			return hardcoded(t, regionPattern, "region")
+		},
+	})
+	RegisterRule(Rule{
+		ID:          "hardcoded-account",
+		Description: "Use AWS::AccountId instead of writing account IDs in resources and outputs",
+		Severity:    SeverityWarning,
+		Check: func(t Template) []Finding {
+			return hardcoded(t, accountPattern, "account ID")
+		},
+	})
+	RegisterRule(Rule{
+		ID:          "stateful-deletion-policy",
+		Description: "Stateful resources should set a DeletionPolicy so that data isn't lost when they are removed",
+		Severity:    SeverityWarning,
+		Check:       missingDeletionPolicy,
+	})
+}
+
+// reference is a Ref, Fn::GetAtt or Fn::Sub variable found in the template
+type reference struct {
+	path   string
+	target string
+	getAtt bool
+}
+
+// references collects every reference of a value, path is the position of v
+func references(v interface{}, path string) []reference {
+	var refs []reference
+
+	walk(v, path, func(p string, node interface{}) {
+		m, ok := node.(map[string]interface{})
+		if !ok || len(m) != 1 {
+			return
+		}
+		for fn, arg := range m {
+			switch fn {
+			case "Ref":
+				if s, ok := arg.(string); ok {
+					refs = append(refs, reference{path: p, target: s})
+				}
+			case "Fn::GetAtt":
+				if l, ok := arg.([]interface{}); ok && len(l) > 0 {
+					if s, ok := l[0].(string); ok {
+						refs = append(refs, reference{path: p, target: s, getAtt: true})
+					}
+				}
+			case "Fn::Sub":
+				body, vars := arg, map[string]interface{}{}
+				if l, ok := arg.([]interface{}); ok && len(l) > 0 {
+					body = l[0]
+					if len(l) > 1 {
+						vars, _ = l[1].(map[string]interface{})
+					}
+				}
+				s, _ := body.(string)
+				for _, match := range subVariable.FindAllStringSubmatch(s, -1) {
+					name := strings.TrimSpace(match[1])
+					getAtt := false
+					if i := strings.Index(name, "."); i >= 0 {
+						name, getAtt = name[:i], true
+					}
+					if _, local := vars[name]; local {
+						continue
+					}
+					refs = append(refs, reference{path: p, target: name, getAtt: getAtt})
+				}
+			}
+		}
+	})
+
+	return refs
+}
+
+// walk calls fn on v and every value nested in it, keys are sorted so that findings
+// come out in the same order on every run
+func walk(v interface{}, path string, fn func(path string, v interface{})) {
+	fn(path, v)
+	switch t := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			walk(t[k], path+"."+k, fn)
+		}
+	case []interface{}:
+		for i, item := range t {
+			walk(item, fmt.Sprintf("%s[%d]", path, i), fn)
+		}
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// resourceOf returns the logical ID of the resource a path points into
+func resourceOf(path string) string {
+	if !strings.HasPrefix(path, "Resources.") {
+		return ""
+	}
+	name := strings.TrimPrefix(path, "Resources.")
+	if i := strings.IndexAny(name, ".["); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// isServerless reports whether the template goes through the SAM transform, which adds
+// resources such as <Function>Role that the template can reference
+func (t Template) isServerless() bool {
+	switch tr := t["Transform"].(type) {
+	case string:
+		return strings.HasPrefix(tr, "AWS::Serverless")
+	case []interface{}:
+		for _, item := range tr {
+			if s, ok := item.(string); ok && strings.HasPrefix(s, "AWS::Serverless") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (t Template) generatedBySam(name string) bool {
+	if !t.isServerless() {
+		return false
+	}
+	if strings.HasPrefix(name, "Serverless") {
+		return true
+	}
+	for resource := range t.Section("Resources") {
+		if strings.HasPrefix(t.ResourceType(resource), "AWS::Serverless::") && strings.HasPrefix(name, resource) {
+			return true
+		}
+	}
+	return false
+}
+
+func missingTargets(t Template, sections ...string) []Finding {
+	var findings []Finding
+
+	resources := t.Section("Resources")
+	parameters := t.Section("Parameters")
+
+	for _, section := range sections {
+		for _, ref := range references(t[section], section) {
+			_, isResource := resources[ref.target]
+			_, isParameter := parameters[ref.target]
+
+			switch {
+			case ref.getAtt && !isResource && !t.generatedBySam(ref.target):
+				findings = append(findings, Finding{
+					Path:     ref.path,
+					Resource: resourceOf(ref.path),
+					Message:  fmt.Sprintf("Fn::GetAtt targets resource %s which is not defined", ref.target),
+				})
+			case !ref.getAtt && !isResource && !isParameter && !pseudoParameters[ref.target] && !t.generatedBySam(ref.target):
+				findings = append(findings, Finding{
+					Path:     ref.path,
+					Resource: resourceOf(ref.path),
+					Message:  fmt.Sprintf("Ref targets %s which is neither a parameter nor a resource", ref.target),
+				})
+			}
+		}
+	}
+
+	return findings
+}
+
+func unusedParameters(t Template) []Finding {
+	used := map[string]bool{}
+	for _, section := range []string{"Resources", "Outputs", "Conditions", "Rules", "Metadata", "Mappings"} {
+		for _, ref := range references(t[section], section) {
+			used[ref.target] = true
+		}
+	}
+
+	var findings []Finding
+	for _, name := range sortedKeys(t.Section("Parameters")) {
+		if !used[name] {
+			findings = append(findings, Finding{
+				Path:    "Parameters." + name,
+				Message: fmt.Sprintf("parameter %s is never used", name),
+			})
+		}
+	}
+	return findings
+}
+
+func unusedConditions(t Template) []Finding {
+	used := map[string]bool{}
+
+	for _, section := range []string{"Resources", "Outputs", "Conditions"} {
+		walk(t[section], section, func(path string, v interface{}) {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return
+			}
+			if c, ok := m["Condition"].(string); ok {
+				used[c] = true
+			}
+			if l, ok := m["Fn::If"].([]interface{}); ok && len(l) > 0 {
+				if c, ok := l[0].(string); ok {
+					used[c] = true
+				}
+			}
+		})
+	}
+
+	var findings []Finding
+	for _, name := range sortedKeys(t.Section("Conditions")) {
+		if !used[name] {
+			findings = append(findings, Finding{
+				Path:    "Conditions." + name,
+				Message: fmt.Sprintf("condition %s is never used", name),
+			})
+		}
+	}
+	return findings
+}
+
+func circularDependsOn(t Template) []Finding {
+	dependsOn := map[string][]string{}
+	for name := range t.Section("Resources") {
+		switch d := t.Resource(name)["DependsOn"].(type) {
+		case string:
+			dependsOn[name] = []string{d}
+		case []interface{}:
+			for _, item := range d {
+				if s, ok := item.(string); ok {
+					dependsOn[name] = append(dependsOn[name], s)
+				}
+			}
+		}
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+
+	var findings []Finding
+	var visit func(name string, chain []string)
+	visit = func(name string, chain []string) {
+		switch state[name] {
+		case done:
+			return
+		case visiting:
+			for i, c := range chain {
+				if c == name {
+					cycle := append(append([]string{}, chain[i:]...), name)
+					findings = append(findings, Finding{
+						Path:     "Resources." + name + ".DependsOn",
+						Resource: name,
+						Message:  fmt.Sprintf("circular DependsOn %s", strings.Join(cycle, " -> ")),
+					})
+				}
+			}
+			return
+		}
+
+		state[name] = visiting
+		for _, dep := range dependsOn[name] {
+			visit(dep, append(chain, name))
+		}
+		state[name] = done
+	}
+
+	for _, name := range sortedKeys(t.Section("Resources")) {
+		visit(name, nil)
+	}
+	return findings
+}
+
+func hardcoded(t Template, pattern *regexp.Regexp, what string) []Finding {
+	var findings []Finding
+	for _, section := range []string{"Resources", "Outputs"} {
+		walk(t[section], section, func(path string, v interface{}) {
+			s, ok := v.(string)
+			if !ok {
+				return
+			}
+			match := pattern.FindString(s)
+			if match == "" {
+				return
+			}
+			findings = append(findings, Finding{
+				Path:     path,
+				Resource: resourceOf(path),
+				Message:  fmt.Sprintf("hard-coded %s %s", what, strings.Trim(match, ":/ -.\"'")),
+			})
+		})
+	}
+	return findings
+}
+
+func missingDeletionPolicy(t Template) []Finding {
+	var findings []Finding
+	for _, name := range sortedKeys(t.Section("Resources")) {
+		resourceType := t.ResourceType(name)
+		if !statefulTypes[resourceType] {
+			continue
+		}
+		if _, ok := t.Resource(name)["DeletionPolicy"]; ok {
+			continue
+		}
+		findings = append(findings, Finding{
+			Path:     "Resources." + name,
+			Resource: name,
+			Message:  fmt.Sprintf("%s %s has no DeletionPolicy", resourceType, name),
+		})
+	}
+	return findings
+}
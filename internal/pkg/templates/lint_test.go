@@ -0,0 +1,172 @@
+package templates
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	testCases := map[string]struct {
+		template string
+		config   *LintConfig
+		expected []string
+	}{
+		"clean template": {
+			template: `
+Parameters:
+  Env: {Type: String}
+Conditions:
+  IsProd: !Equals [!Ref Env, prod]
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    DeletionPolicy: Retain
+    Condition: IsProd
+    Properties:
+      BucketName: !Sub "${AWS::StackName}-${Env}-${AWS::Region}"
+Outputs:
+  Arn:
+    Value: !GetAtt Bucket.Arn
+`,
+		},
+		"missing targets": {
+			template: `
+Resources:
+  Topic:
+    Type: AWS::SNS::Topic
+    Properties:
+      TopicName: !Ref Missing
+      DisplayName: !Sub "${Other.Name}"
+Outputs:
+  Arn:
+    Value: !GetAtt Queue.Arn
+`,
+			expected: []string{
+				"error missing-output-target Outputs.Arn.Value: Fn::GetAtt targets resource Queue which is not defined",
+				"error missing-ref-target Resources.Topic.Properties.DisplayName: Fn::GetAtt targets resource Other which is not defined",
+				"error missing-ref-target Resources.Topic.Properties.TopicName: Ref targets Missing which is neither a parameter nor a resource",
+			},
+		},
+		"unused parameters and conditions": {
+			template: `
+Parameters:
+  Env: {Type: String}
+Conditions:
+  IsProd: !Equals [prod, prod]
+Resources:
+  Topic: {Type: AWS::SNS::Topic}
+`,
+			expected: []string{
+				"warning unused-condition Conditions.IsProd: condition IsProd is never used",
+				"warning unused-parameter Parameters.Env: parameter Env is never used",
+			},
+		},
+		"circular depends on": {
+			template: `
+Resources:
+  A: {Type: AWS::SNS::Topic, DependsOn: B}
+  B: {Type: AWS::SNS::Topic, DependsOn: [C]}
+  C: {Type: AWS::SNS::Topic, DependsOn: A}
+`,
+			expected: []string{"error circular-depends-on Resources.A.DependsOn: circular DependsOn A -> B -> C -> A"},
+		},
+		"hard-coded region and account": {
+			template: `
+Resources:
+  Topic:
+    Type: AWS::SNS::Topic
+    Properties:
+      KmsMasterKeyId: arn:aws:kms:eu-west-1:123456789012:key/abc
+`,
+			expected: []string{
+				"warning hardcoded-account Resources.Topic.Properties.KmsMasterKeyId: hard-coded account ID 123456789012",
+				"warning hardcoded-region Resources.Topic.Properties.KmsMasterKeyId: hard-coded region eu-west-1",
+			},
+		},
+		"config disables rules and changes severity": {
+			template: `
+Parameters:
+  Env: {Type: String}
+Resources:
+  Table: {Type: AWS::DynamoDB::Table}
+`,
+			config: &LintConfig{Rules: map[string]RuleConfig{
+				"stateful-deletion-policy": {Enabled: new(bool)},
+				"unused-parameter":         {Severity: SeverityError},
+			}},
+			expected: []string{"error unused-parameter Parameters.Env: parameter Env is never used"},
+		},
+		"metadata suppression": {
+			template: `
+Metadata:
+  cfstack:
+    lint:
+      ignore: [unused-parameter]
+Parameters:
+  Env: {Type: String}
+Resources:
+  Table:
+    Type: AWS::DynamoDB::Table
+    Metadata:
+      cfstack:
+        lint:
+          ignore: [stateful-deletion-policy]
+  Bucket:
+    Type: AWS::S3::Bucket
+`,
+			expected: []string{"warning stateful-deletion-policy Resources.Bucket: AWS::S3::Bucket Bucket has no DeletionPolicy"},
+		},
+		"resources generated by the SAM transform": {
+			template: `
+Transform: AWS::Serverless-2016-10-31
+Resources:
+  Fn:
+    Type: AWS::Serverless::Function
+    Properties:
+      CodeUri: ./src
+Outputs:
+  Role:
+    Value: !GetAtt FnRole.Arn
+  Api:
+    Value: !Ref ServerlessRestApi
+`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tmpl, err := ParseTemplate([]byte(tc.template))
+			require.NoError(t, err)
+
+			var out []string
+			for _, f := range tmpl.Lint(tc.config) {
+				out = append(out, string(f.Severity)+" "+f.Rule+" "+f.Path+": "+f.Message)
+			}
+			require.Equal(t, tc.expected, out)
+		})
+	}
+}
+
+func TestLoadLintConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cfstack-lint")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, ".cfstack-lint.json")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"Rules": {"hardcoded-region": {"Enabled": false}}}`), 0644))
+	c, err := LoadLintConfig(path)
+	require.NoError(t, err)
+	require.False(t, *c.Rules["hardcoded-region"].Enabled)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"Rules": {"no-such-rule": {"Enabled": false}}}`), 0644))
+	_, err = LoadLintConfig(path)
+	require.EqualError(t, err, "unknown lint rule no-such-rule in "+path)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"Rules": {"hardcoded-region": {"Severity": "fatal"}}}`), 0644))
+	_, err = LoadLintConfig(path)
+	require.EqualError(t, err, "invalid severity fatal for lint rule hardcoded-region in "+path)
+}
@@ -0,0 +1,198 @@
+package templates
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// Template is a generic representation of a CloudFormation template. YAML short form
+// intrinsic functions such as !Ref or !GetAtt are expanded to their long JSON form.
+type Template map[string]interface{}
+
+var shortFormTags = []string{"Ref", "Condition", "GetAtt", "Base64", "Cidr", "FindInMap", "GetAZs",
+	"ImportValue", "Join", "Select", "Split", "Sub", "Transform", "And", "Equals", "If", "Not", "Or",
+}
+
+func LoadTemplate(path string) (Template, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTemplate(b)
+}
+
+// ParseTemplate parses a JSON or YAML template body
+func ParseTemplate(b []byte) (Template, error) {
+	body := b
+	if !IsJSON(b) {
+		var doc yaml.Node
+		err := yaml.Unmarshal(b, &doc)
+		if err != nil {
+			return nil, errors.Errorf("invalid YAML template: %v", err)
+		}
+
+		v, err := fromYAML(&doc)
+		if err != nil {
+			return nil, errors.Errorf("invalid YAML template: %v", err)
+		}
+
+		body, err = json.Marshal(v)
+		if err != nil {
+			return nil, errors.Errorf("invalid YAML template: %v", err)
+		}
+	}
+
+	t := Template{}
+	err := json.Unmarshal(body, &t)
+	if err != nil {
+		return nil, errors.Errorf("invalid JSON template: %v", err)
+	}
+
+	expandGetAtt(map[string]interface{}(t))
+
+	return t, nil
+}
+
+// fromYAML converts a YAML node to the values encoding/json would produce, short form
+// intrinsic function tags are expanded to their long form
+func fromYAML(n *yaml.Node) (interface{}, error) {
+	var v interface{}
+
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return map[string]interface{}{}, nil
+		}
+		return fromYAML(n.Content[0])
+	case yaml.AliasNode:
+		return fromYAML(n.Alias)
+	case yaml.MappingNode:
+		m := make(map[string]interface{}, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			item, err := fromYAML(n.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			m[n.Content[i].Value] = item
+		}
+		v = m
+	case yaml.SequenceNode:
+		l := make([]interface{}, 0, len(n.Content))
+		for _, c := range n.Content {
+			item, err := fromYAML(c)
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, item)
+		}
+		v = l
+	case yaml.ScalarNode:
+		if strings.HasPrefix(n.Tag, "!!") {
+			var scalar interface{}
+			if err := n.Decode(&scalar); err != nil {
+				return nil, err
+			}
+			v = scalar
+		} else {
+			v = n.Value
+		}
+	}
+
+	tag := strings.TrimPrefix(n.Tag, "!")
+	if strings.HasPrefix(n.Tag, "!!") || !isShortFormTag(tag) {
+		return v, nil
+	}
+	if tag != "Ref" && tag != "Condition" {
+		tag = "Fn::" + tag
+	}
+	return map[string]interface{}{tag: v}, nil
+}
+
+func isShortFormTag(tag string) bool {
+	for _, t := range shortFormTags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// IsJSON reports whether a template body is JSON rather than YAML
+func IsJSON(b []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(b), []byte("{"))
+}
+
+// Section returns a top level section of the template such as Resources or Parameters
+func (t Template) Section(name string) map[string]interface{} {
+	if section, ok := t[name].(map[string]interface{}); ok {
+		return section
+	}
+	return map[string]interface{}{}
+}
+
+// Resource returns the definition of a single resource
+func (t Template) Resource(name string) map[string]interface{} {
+	if resource, ok := t.Section("Resources")[name].(map[string]interface{}); ok {
+		return resource
+	}
+	return map[string]interface{}{}
+}
+
+// ResourceType returns the Type of a resource
+func (t Template) ResourceType(name string) string {
+	resourceType, _ := t.Resource(name)["Type"].(string)
+	return resourceType
+}
+
+// Property returns a top level property of a resource
+func (t Template) Property(resource string, property string) interface{} {
+	properties, _ := t.Resource(resource)["Properties"].(map[string]interface{})
+	return properties[property]
+}
+
+// LocalPaths returns the string values of a property on every resource of the given
+// type that point to a local file rather than to S3 or http
+func (t Template) LocalPaths(resourceType string, property string) []string {
+	var paths []string
+	for name := range t.Section("Resources") {
+		if t.ResourceType(name) != resourceType {
+			continue
+		}
+		v, ok := t.Property(name, property).(string)
+		if !ok || v == "" || strings.HasPrefix(v, "s3://") || strings.HasPrefix(v, "http://") || strings.HasPrefix(v, "https://") {
+			continue
+		}
+		paths = append(paths, v)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// expandGetAtt turns the short "Resource.Attribute" form of GetAtt into a list so that
+// templates written in YAML and JSON look the same
+func expandGetAtt(v interface{}) {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		for k, child := range node {
+			if s, ok := child.(string); ok && k == "Fn::GetAtt" {
+				parts := strings.SplitN(s, ".", 2)
+				list := make([]interface{}, 0, len(parts))
+				for _, p := range parts {
+					list = append(list, p)
+				}
+				node[k] = list
+				continue
+			}
+			expandGetAtt(child)
+		}
+	case []interface{}:
+		for _, child := range node {
+			expandGetAtt(child)
+		}
+	}
+}
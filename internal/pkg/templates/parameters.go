@@ -0,0 +1,190 @@
+package templates
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Parameter is a template parameter with the constraints CloudFormation enforces on it
+type Parameter struct {
+	Name           string
+	Type           string
+	HasDefault     bool
+	AllowedValues  []string
+	AllowedPattern string
+	MinLength      *int
+	MaxLength      *int
+	MinValue       *float64
+	MaxValue       *float64
+}
+
+// Parameters returns the parameters declared by the template sorted by name
+func (t Template) Parameters() []Parameter {
+	section := t.Section("Parameters")
+
+	names := make([]string, 0, len(section))
+	for name := range section {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	params := make([]Parameter, 0, len(names))
+	for _, name := range names {
+		def, _ := section[name].(map[string]interface{})
+		p := Parameter{Name: name}
+		p.Type, _ = def["Type"].(string)
+		_, p.HasDefault = def["Default"]
+		p.AllowedPattern, _ = def["AllowedPattern"].(string)
+		if values, ok := def["AllowedValues"].([]interface{}); ok {
+			for _, v := range values {
+				p.AllowedValues = append(p.AllowedValues, scalar(v))
+			}
+		}
+		p.MinLength = intValue(def["MinLength"])
+		p.MaxLength = intValue(def["MaxLength"])
+		p.MinValue = floatValue(def["MinValue"])
+		p.MaxValue = floatValue(def["MaxValue"])
+		params = append(params, p)
+	}
+	return params
+}
+
+// CheckParameters compares the parameters passed to a stack with the ones declared by
+// the template. supplied holds every parameter name passed, resolved the values that
+// are known locally. Every problem found is returned.
+func (t Template) CheckParameters(supplied []string, resolved map[string]string) []string {
+	var problems []string
+
+	declared := map[string]bool{}
+	passed := map[string]bool{}
+	for _, name := range supplied {
+		passed[name] = true
+	}
+
+	for _, p := range t.Parameters() {
+		declared[p.Name] = true
+		if !passed[p.Name] {
+			if !p.HasDefault {
+				problems = append(problems, fmt.Sprintf("required parameter %s is missing", p.Name))
+			}
+			continue
+		}
+		if value, ok := resolved[p.Name]; ok {
+			problems = append(problems, p.Check(value)...)
+		}
+	}
+
+	sorted := append([]string{}, supplied...)
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		if !declared[name] {
+			problems = append(problems, fmt.Sprintf("parameter %s is not declared in the template", name))
+		}
+	}
+
+	return problems
+}
+
+// Check validates a value against the constraints of the parameter
+func (p Parameter) Check(value string) []string {
+	// Values of SSM parameter types are names of parameters, not the values themselves
+	if strings.HasPrefix(p.Type, "AWS::SSM::Parameter::") {
+		return nil
+	}
+
+	items := []string{value}
+	if p.Type == "CommaDelimitedList" || strings.HasPrefix(p.Type, "List<") {
+		items = strings.Split(value, ",")
+		for i := range items {
+			items[i] = strings.TrimSpace(items[i])
+		}
+	}
+
+	var problems []string
+	for _, item := range items {
+		problems = append(problems, p.checkItem(item)...)
+	}
+	return problems
+}
+
+func (p Parameter) checkItem(value string) []string {
+	var problems []string
+
+	if len(p.AllowedValues) > 0 && !contains(p.AllowedValues, value) {
+		problems = append(problems, fmt.Sprintf("parameter %s: %q is not one of the allowed values %s", p.Name, value, strings.Join(p.AllowedValues, ", ")))
+	}
+
+	if p.AllowedPattern != "" {
+		// Patterns CloudFormation accepts but Go doesn't are left for CloudFormation to check
+		re, err := regexp.Compile("^(?:" + p.AllowedPattern + ")$")
+		if err == nil && !re.MatchString(value) {
+			problems = append(problems, fmt.Sprintf("parameter %s: %q does not match the allowed pattern %s", p.Name, value, p.AllowedPattern))
+		}
+	}
+
+	if p.MinLength != nil && len(value) < *p.MinLength {
+		problems = append(problems, fmt.Sprintf("parameter %s: %q is shorter than the minimum length %d", p.Name, value, *p.MinLength))
+	}
+	if p.MaxLength != nil && len(value) > *p.MaxLength {
+		problems = append(problems, fmt.Sprintf("parameter %s: %q is longer than the maximum length %d", p.Name, value, *p.MaxLength))
+	}
+
+	if p.Type == "Number" || p.Type == "List<Number>" {
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return append(problems, fmt.Sprintf("parameter %s: %q is not a number", p.Name, value))
+		}
+		if p.MinValue != nil && n < *p.MinValue {
+			problems = append(problems, fmt.Sprintf("parameter %s: %s is less than the minimum value %s", p.Name, value, scalar(*p.MinValue)))
+		}
+		if p.MaxValue != nil && n > *p.MaxValue {
+			problems = append(problems, fmt.Sprintf("parameter %s: %s is greater than the maximum value %s", p.Name, value, scalar(*p.MaxValue)))
+		}
+	}
+
+	return problems
+}
+
+func scalar(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+func floatValue(v interface{}) *float64 {
+	switch t := v.(type) {
+	case float64:
+		return &t
+	case string:
+		if f, err := strconv.ParseFloat(t, 64); err == nil {
+			return &f
+		}
+	}
+	return nil
+}
+
+func intValue(v interface{}) *int {
+	f := floatValue(v)
+	if f == nil {
+		return nil
+	}
+	i := int(*f)
+	return &i
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package templates
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCheckParameters(t *testing.T) {
+	tmpl, err := ParseTemplate([]byte(`
+Parameters:
+  Env:
+    Type: String
+    AllowedValues: [dev, prod]
+  Name:
+    Type: String
+    AllowedPattern: "[a-z-]+"
+    MinLength: 3
+    MaxLength: 8
+  Replicas:
+    Type: Number
+    MinValue: 1
+    MaxValue: 10
+    Default: 2
+  Zones:
+    Type: CommaDelimitedList
+    AllowedValues: [a, b, c]
+    Default: a
+  Image:
+    Type: AWS::SSM::Parameter::Value<String>
+    Default: /images/latest
+Resources: {}
+`))
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		params   map[string]string
+		deferred []string
+		expected []string
+	}{
+		"valid": {
+			params: map[string]string{"Env": "prod", "Name": "api", "Replicas": "3", "Zones": "a, c", "Image": "/images/v2"},
+		},
+		"deferred values are only checked for presence": {
+			params:   map[string]string{"Env": "prod"},
+			deferred: []string{"Name"},
+		},
+		"missing and unknown": {
+			params:   map[string]string{"Env": "dev", "Nmae": "api"},
+			expected: []string{"required parameter Name is missing", "parameter Nmae is not declared in the template"},
+		},
+		"constraints": {
+			params: map[string]string{"Env": "qa", "Name": "API_SERVER", "Replicas": "12", "Zones": "a,d"},
+			expected: []string{
+				`parameter Env: "qa" is not one of the allowed values dev, prod`,
+				`parameter Name: "API_SERVER" does not match the allowed pattern [a-z-]+`,
+				`parameter Name: "API_SERVER" is longer than the maximum length 8`,
+				"parameter Replicas: 12 is greater than the maximum value 10",
+				`parameter Zones: "d" is not one of the allowed values a, b, c`,
+			},
+		},
+		"not a number": {
+			params:   map[string]string{"Env": "dev", "Name": "ab", "Replicas": "two"},
+			expected: []string{`parameter Name: "ab" is shorter than the minimum length 3`, `parameter Replicas: "two" is not a number`},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			supplied := append([]string{}, tc.deferred...)
+			for k := range tc.params {
+				supplied = append(supplied, k)
+			}
+			require.Equal(t, tc.expected, tmpl.CheckParameters(supplied, tc.params))
+		})
+	}
+}
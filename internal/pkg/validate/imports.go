@@ -0,0 +1,66 @@
+package validate
+
+import (
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/manifest"
+	"github.com/CleverTap/cfstack/internal/pkg/stack"
+	"github.com/CleverTap/cfstack/internal/pkg/templates"
+)
+
+// Imports checks the ResourcesToImport of every stack against its template. CloudFormation
+// only imports resources that are declared with the same type and a DeletionPolicy.
+func Imports(m *manifest.Manifest, templatesRoot string) []Problem {
+	var problems []Problem
+
+	for _, region := range m.Regions {
+		for _, s := range region.Stacks {
+			if s.Action == "DELETE" || len(s.ResourcesToImport) == 0 {
+				continue
+			}
+			for _, msg := range stackImports(s, templatesRoot) {
+				problems = append(problems, Problem{Region: region.Name, Stack: s.StackName, Message: msg})
+			}
+		}
+	}
+
+	return problems
+}
+
+func stackImports(s stack.Stack, templatesRoot string) []string {
+	t, err := templates.LoadTemplate(TemplatePath(templatesRoot, s))
+	if err != nil {
+		return []string{fmt.Sprintf("template %s could not be loaded: %s", s.TemplatePath, err)}
+	}
+
+	var problems []string
+	seen := map[string]bool{}
+
+	for i, r := range s.ResourcesToImport {
+		if r.LogicalResourceId == "" {
+			problems = append(problems, fmt.Sprintf("resource to import %d is missing a LogicalResourceId", i))
+			continue
+		}
+		if seen[r.LogicalResourceId] {
+			problems = append(problems, fmt.Sprintf("resource %s is imported more than once", r.LogicalResourceId))
+			continue
+		}
+		seen[r.LogicalResourceId] = true
+
+		if len(r.ResourceIdentifier) == 0 {
+			problems = append(problems, fmt.Sprintf("resource to import %s has no ResourceIdentifier", r.LogicalResourceId))
+		}
+
+		if _, ok := t.Section("Resources")[r.LogicalResourceId]; !ok {
+			problems = append(problems, fmt.Sprintf("resource to import %s is not declared in the template", r.LogicalResourceId))
+			continue
+		}
+		if declared := t.ResourceType(r.LogicalResourceId); r.ResourceType != declared {
+			problems = append(problems, fmt.Sprintf("resource to import %s has type %q, the template declares %s", r.LogicalResourceId, r.ResourceType, declared))
+		}
+		if _, ok := t.Resource(r.LogicalResourceId)["DeletionPolicy"]; !ok {
+			problems = append(problems, fmt.Sprintf("resource to import %s must have a DeletionPolicy in the template", r.LogicalResourceId))
+		}
+	}
+
+	return problems
+}
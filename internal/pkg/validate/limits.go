@@ -0,0 +1,50 @@
+package validate
+
+import (
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/manifest"
+	"github.com/CleverTap/cfstack/internal/pkg/stack"
+	"github.com/CleverTap/cfstack/internal/pkg/templates"
+	"io/ioutil"
+)
+
+// Limits checks the template of every stack against the size, resource, parameter and
+// output quotas of CloudFormation. Templates that can't be loaded are reported by
+// Parameters.
+func Limits(m *manifest.Manifest, templatesRoot string) []Problem {
+	var problems []Problem
+
+	for _, region := range m.Regions {
+		for _, s := range region.Stacks {
+			if s.Action == "DELETE" {
+				continue
+			}
+			for _, msg := range stackLimits(s, templatesRoot) {
+				problems = append(problems, Problem{Region: region.Name, Stack: s.StackName, Message: msg})
+			}
+		}
+	}
+
+	return problems
+}
+
+func stackLimits(s stack.Stack, templatesRoot string) []string {
+	b, err := ioutil.ReadFile(TemplatePath(templatesRoot, s))
+	if err != nil {
+		return nil
+	}
+	t, err := templates.ParseTemplate(b)
+	if err != nil {
+		return nil
+	}
+
+	var problems []string
+	_, err = templates.PrepareBody(b)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("%s: %s", s.TemplatePath, err))
+	}
+	for _, msg := range t.CheckLimits() {
+		problems = append(problems, fmt.Sprintf("%s: %s", s.TemplatePath, msg))
+	}
+	return problems
+}
@@ -0,0 +1,87 @@
+package validate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// lineAt returns the 1 based line of a byte offset
+func lineAt(b []byte, offset int64) int {
+	if offset > int64(len(b)) {
+		offset = int64(len(b))
+	}
+	return bytes.Count(b[:offset], []byte("\n")) + 1
+}
+
+// nextToken skips whitespace and separators from offset
+func nextToken(b []byte, offset int64) int64 {
+	for offset < int64(len(b)) && bytes.IndexByte([]byte(" \t\r\n,:"), b[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// syntaxError turns a JSON decoding error into a message and the line it happened on
+func syntaxError(b []byte, err error) (string, int) {
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		return fmt.Sprintf("invalid JSON: %s", e), lineAt(b, e.Offset)
+	case *json.UnmarshalTypeError:
+		return fmt.Sprintf("%s must be a %s, found a %s", e.Field, e.Type, e.Value), lineAt(b, e.Offset)
+	default:
+		return err.Error(), 0
+	}
+}
+
+// jsonLines maps the path of every object key and array element of a JSON document,
+// e.g. Regions[0].Stacks[1].Action, to the line it starts on
+func jsonLines(b []byte) map[string]int {
+	lines := map[string]int{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	walkJSON(dec, b, "", lines)
+	return lines
+}
+
+func walkJSON(dec *json.Decoder, b []byte, path string, lines map[string]int) bool {
+	tok, err := dec.Token()
+	if err != nil {
+		return false
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return true
+	}
+
+	switch delim {
+	case '{':
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return false
+			}
+			key := fmt.Sprint(keyTok)
+			child := key
+			if path != "" {
+				child = path + "." + key
+			}
+			lines[child] = lineAt(b, dec.InputOffset())
+			if !walkJSON(dec, b, child, lines) {
+				return false
+			}
+		}
+	case '[':
+		for i := 0; dec.More(); i++ {
+			child := fmt.Sprintf("%s[%d]", path, i)
+			lines[child] = lineAt(b, nextToken(b, dec.InputOffset()))
+			if !walkJSON(dec, b, child, lines) {
+				return false
+			}
+		}
+	}
+
+	// Closing delimiter
+	_, err = dec.Token()
+	return err == nil
+}
@@ -0,0 +1,392 @@
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/manifest"
+	"github.com/CleverTap/cfstack/internal/pkg/stack"
+	"github.com/CleverTap/cfstack/internal/pkg/templates"
+	"github.com/CleverTap/cfstack/internal/pkg/util"
+	"github.com/CleverTap/cfstack/internal/pkg/values"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// Actions lists the values accepted for the Action of a stack
+var Actions = []string{"CREATE", "UPDATE", "DELETE"}
+
+var stackPolicyActions = []string{"Update:*", "Update:Modify", "Update:Replace", "Update:Delete"}
+
+type Opts struct {
+	ManifestFile string
+	ValuesFiles  []string
+	Params       []string
+}
+
+type manifestValidator struct {
+	opts          *Opts
+	templatesRoot string
+	file          string
+	lines         map[string]int
+	problems      []Problem
+}
+
+// Run validates a manifest, its values files and templates without calling AWS
+func Run(opts *Opts) []Problem {
+	v := &manifestValidator{
+		opts:          opts,
+		templatesRoot: filepath.Dir(opts.ManifestFile),
+		file:          display(opts.ManifestFile),
+	}
+
+	m, ok := v.parseManifest()
+	if !ok {
+		return v.problems
+	}
+
+	vals, ok := v.loadValues()
+
+	loaded := v.checkRegions(m)
+
+	if ok {
+		for _, p := range Parameters(loaded, vals, v.templatesRoot) {
+			p.File = v.file
+			p.Line = v.stackLine(m, p.Region, p.Stack, "Parameters")
+			v.problems = append(v.problems, p)
+		}
+	}
+
+	for _, p := range Imports(loaded, v.templatesRoot) {
+		p.File = v.file
+		p.Line = v.stackLine(m, p.Region, p.Stack, "ResourcesToImport")
+		v.problems = append(v.problems, p)
+	}
+
+	for _, p := range Limits(loaded, v.templatesRoot) {
+		p.File = v.file
+		p.Line = v.stackLine(m, p.Region, p.Stack, "TemplatePath")
+		v.problems = append(v.problems, p)
+	}
+
+	return v.problems
+}
+
+func (v *manifestValidator) add(line int, region string, stackName string, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{
+		File:    v.file,
+		Line:    line,
+		Region:  region,
+		Stack:   stackName,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *manifestValidator) parseManifest() (*manifest.Manifest, bool) {
+	b, err := ioutil.ReadFile(v.opts.ManifestFile)
+	if err != nil {
+		v.add(0, "", "", "%s", err)
+		return nil, false
+	}
+
+	m := &manifest.Manifest{}
+	err = json.Unmarshal(b, m)
+	if err != nil {
+		msg, line := syntaxError(b, err)
+		v.add(line, "", "", "%s", msg)
+		return nil, false
+	}
+
+	v.lines = jsonLines(b)
+
+	if len(m.Regions) == 0 {
+		v.add(0, "", "", "No Regions found")
+		return nil, false
+	}
+	return m, true
+}
+
+func (v *manifestValidator) loadValues() (*values.Values, bool) {
+	ok := true
+	for _, path := range v.opts.ValuesFiles {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			v.problems = append(v.problems, Problem{File: display(path), Message: err.Error()})
+			ok = false
+			continue
+		}
+		var data interface{}
+		err = json.Unmarshal(b, &data)
+		if err != nil {
+			msg, line := syntaxError(b, err)
+			v.problems = append(v.problems, Problem{File: display(path), Line: line, Message: msg})
+			ok = false
+			continue
+		}
+		if _, isObject := data.(map[string]interface{}); !isObject {
+			v.problems = append(v.problems, Problem{File: display(path), Line: 1, Message: "values must be an object of regions"})
+			ok = false
+		}
+	}
+	if !ok {
+		return nil, false
+	}
+
+	vals, err := values.Load(v.opts.ValuesFiles)
+	if err != nil {
+		v.add(0, "", "", "%s", err)
+		return nil, false
+	}
+
+	err = vals.SetOverrides(v.opts.Params)
+	if err != nil {
+		v.problems = append(v.problems, Problem{Message: err.Error()})
+		return nil, false
+	}
+
+	return vals, true
+}
+
+// checkRegions validates every region and stack and returns the manifest reduced to the
+// stacks whose template could be loaded
+func (v *manifestValidator) checkRegions(m *manifest.Manifest) *manifest.Manifest {
+	loaded := &manifest.Manifest{}
+
+	for i, region := range m.Regions {
+		path := fmt.Sprintf("Regions[%d]", i)
+
+		if region.Name == "" {
+			v.add(v.lines[path], "", "", "Region name is missing for %d element", i)
+			continue
+		}
+		if !validRegion(region.Name) {
+			v.add(v.lines[path+".Name"], "", "", "%s is not a valid region", region.Name)
+		}
+
+		if len(region.Stacks) == 0 {
+			v.add(v.lines[path], "", "", "No stacks found in region %s", region.Name)
+			continue
+		}
+
+		out := manifest.Region{Name: region.Name}
+		seen := map[string]bool{}
+
+		for j, s := range region.Stacks {
+			stackPath := fmt.Sprintf("%s.Stacks[%d]", path, j)
+
+			if s.StackName == "" {
+				v.add(v.lines[stackPath], region.Name, "", "Stack name is missing for %d element in region %s", j, region.Name)
+				continue
+			}
+			if seen[s.StackName] {
+				v.add(v.lines[stackPath+".StackName"], region.Name, s.StackName, "stack is defined more than once in the region")
+				continue
+			}
+			seen[s.StackName] = true
+
+			if v.checkStack(region, s, stackPath) {
+				out.Stacks = append(out.Stacks, s)
+			}
+		}
+
+		if len(out.Stacks) > 0 {
+			loaded.Regions = append(loaded.Regions, out)
+		}
+	}
+
+	return loaded
+}
+
+// checkStack reports the problems of a single stack, it returns true when its template
+// could be loaded
+func (v *manifestValidator) checkStack(region manifest.Region, s stack.Stack, path string) bool {
+	line := func(field string) int {
+		if l, ok := v.lines[path+"."+field]; ok {
+			return l
+		}
+		return v.lines[path]
+	}
+
+	if s.Action == "" {
+		v.add(line("StackName"), region.Name, s.StackName, "Action is missing")
+	} else if !contains(Actions, s.Action) {
+		v.add(line("Action"), region.Name, s.StackName, "invalid Action %s, must be one of %s", s.Action, strings.Join(Actions, ", "))
+	}
+
+	for _, dep := range s.DependsOn {
+		if dep == s.StackName {
+			v.add(line("DependsOn"), region.Name, s.StackName, "stack depends on itself")
+		} else if !hasStack(region, dep) {
+			v.add(line("DependsOn"), region.Name, s.StackName, "depends on %s which is not defined in the region", dep)
+		}
+	}
+
+	for k, st := range s.StackPolicy.Statement {
+		for _, msg := range statementProblems(st) {
+			v.add(line(fmt.Sprintf("StackPolicy.Statement[%d]", k)), region.Name, s.StackName, "stack policy statement %d: %s", k, msg)
+		}
+	}
+
+	if s.TemplatePath == "" {
+		v.add(line("StackName"), region.Name, s.StackName, "TemplatePath is missing")
+		return false
+	}
+
+	if s.Action == "DELETE" {
+		return false
+	}
+
+	templatePath := TemplatePath(v.templatesRoot, s)
+	if !util.FileExists(templatePath) {
+		v.add(line("TemplatePath"), region.Name, s.StackName, "template %s does not exist", s.TemplatePath)
+		return false
+	}
+
+	b, err := ioutil.ReadFile(templatePath)
+	if err != nil {
+		v.add(line("TemplatePath"), region.Name, s.StackName, "%s", err)
+		return false
+	}
+
+	t, err := templates.ParseTemplate(b)
+	if err != nil {
+		msg, l := err.Error(), 0
+		if templates.IsJSON(b) {
+			var doc interface{}
+			if jsonErr := json.Unmarshal(b, &doc); jsonErr != nil {
+				msg, l = syntaxError(b, jsonErr)
+			}
+		}
+		v.problems = append(v.problems, Problem{File: display(templatePath), Line: l, Region: region.Name, Stack: s.StackName, Message: msg})
+		return false
+	}
+
+	base := filepath.Dir(templatePath)
+	code := append(t.LocalPaths("AWS::Serverless::Function", "CodeUri"), t.LocalPaths("AWS::Serverless::LayerVersion", "ContentUri")...)
+	for _, uri := range code {
+		if !util.FileExists(util.ResolvePath(base, uri)) {
+			v.problems = append(v.problems, Problem{File: display(templatePath), Region: region.Name, Stack: s.StackName, Message: fmt.Sprintf("code path %s does not exist", uri)})
+		}
+	}
+
+	return true
+}
+
+func (v *manifestValidator) stackLine(m *manifest.Manifest, region string, stackName string, field string) int {
+	for i, r := range m.Regions {
+		if r.Name != region {
+			continue
+		}
+		for j, s := range r.Stacks {
+			if s.StackName != stackName {
+				continue
+			}
+			path := fmt.Sprintf("Regions[%d].Stacks[%d]", i, j)
+			if l, ok := v.lines[path+"."+field]; ok {
+				return l
+			}
+			return v.lines[path]
+		}
+	}
+	return 0
+}
+
+// statementProblems checks a stack policy statement against what CloudFormation accepts
+func statementProblems(st templates.Statement) []string {
+	var problems []string
+
+	if st.Effect != "Allow" && st.Effect != "Deny" {
+		problems = append(problems, fmt.Sprintf("Effect must be Allow or Deny, found %q", st.Effect))
+	}
+
+	actions, ok := stringList(st.Action)
+	if !ok || len(actions) == 0 {
+		problems = append(problems, "Action is missing")
+	}
+	for _, a := range actions {
+		if !contains(stackPolicyActions, a) {
+			problems = append(problems, fmt.Sprintf("invalid Action %s, must be one of %s", a, strings.Join(stackPolicyActions, ", ")))
+		}
+	}
+
+	if principal, _ := st.Principal.(string); principal != "*" {
+		problems = append(problems, `Principal must be "*"`)
+	}
+
+	resources, ok := stringList(st.Resource)
+	if !ok || len(resources) == 0 {
+		problems = append(problems, "Resource is missing")
+	}
+	for _, r := range resources {
+		if r != "*" && !strings.HasPrefix(r, "LogicalResourceId/") {
+			problems = append(problems, fmt.Sprintf("invalid Resource %s, must be * or LogicalResourceId/<name>", r))
+		}
+	}
+
+	return problems
+}
+
+func stringList(v interface{}) ([]string, bool) {
+	switch t := v.(type) {
+	case string:
+		return []string{t}, true
+	case []interface{}:
+		out := make([]string, 0, len(t))
+		for _, item := range t {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			out = append(out, s)
+		}
+		return out, true
+	default:
+		return nil, false
+	}
+}
+
+func validRegion(name string) bool {
+	partitions := endpoints.DefaultResolver().(endpoints.EnumPartitions).Partitions()
+	for _, p := range partitions {
+		if _, ok := p.Regions()[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func hasStack(region manifest.Region, name string) bool {
+	for _, s := range region.Stacks {
+		if s.StackName == name {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// display shortens a path relative to the working directory when possible
+func display(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	wd, err := filepath.Abs(".")
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return abs
+	}
+	return rel
+}
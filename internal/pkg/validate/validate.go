@@ -0,0 +1,114 @@
+package validate
+
+import (
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/manifest"
+	"github.com/CleverTap/cfstack/internal/pkg/resolver"
+	"github.com/CleverTap/cfstack/internal/pkg/stack"
+	"github.com/CleverTap/cfstack/internal/pkg/templates"
+	"github.com/CleverTap/cfstack/internal/pkg/values"
+	"path/filepath"
+	"strings"
+)
+
+// Problem is a single validation failure, Region and Stack are empty for problems that
+// are not specific to a stack. File and Line point to where the problem is when known.
+type Problem struct {
+	File    string
+	Line    int
+	Region  string
+	Stack   string
+	Message string
+}
+
+func (p Problem) String() string {
+	var b strings.Builder
+	if p.File != "" {
+		b.WriteString(p.File)
+		if p.Line > 0 {
+			fmt.Fprintf(&b, ":%d", p.Line)
+		}
+		b.WriteString(": ")
+	}
+	if p.Stack != "" {
+		fmt.Fprintf(&b, "%s/%s: ", p.Region, p.Stack)
+	}
+	b.WriteString(p.Message)
+	return b.String()
+}
+
+// Error reports every problem found in one pass
+type Error struct {
+	Problems []Problem
+}
+
+func (e *Error) Error() string {
+	lines := make([]string, 0, len(e.Problems))
+	for _, p := range e.Problems {
+		lines = append(lines, p.String())
+	}
+	return fmt.Sprintf("validation failed with %d problem(s):\n    %s", len(e.Problems), strings.Join(lines, "\n    "))
+}
+
+// Check returns an *Error holding problems or nil when there are none
+func Check(problems []Problem) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return &Error{Problems: problems}
+}
+
+// Parameters checks the parameters of every stack in the manifest against the Parameters
+// section of its template. Only values that can be resolved without AWS are checked.
+func Parameters(m *manifest.Manifest, v *values.Values, templatesRoot string) []Problem {
+	var problems []Problem
+
+	for _, region := range m.Regions {
+		for _, s := range region.Stacks {
+			if s.Action == "DELETE" {
+				continue
+			}
+			for _, msg := range stackParameters(region.Name, s, v, templatesRoot) {
+				problems = append(problems, Problem{Region: region.Name, Stack: s.StackName, Message: msg})
+			}
+		}
+	}
+
+	return problems
+}
+
+func stackParameters(region string, s stack.Stack, v *values.Values, templatesRoot string) []string {
+	var problems []string
+
+	params := v.Parameters(region, s.StackName, s.Parameters)
+	supplied := make([]string, 0, len(params))
+	for k := range params {
+		supplied = append(supplied, k)
+	}
+
+	resolved, _, err := resolver.New(region, s.StackName, v, nil).ResolveOffline(s.Parameters)
+	if err != nil {
+		resolveErr, ok := err.(*resolver.Error)
+		if !ok {
+			return append(problems, err.Error())
+		}
+		for _, u := range resolveErr.Unresolved {
+			problems = append(problems, fmt.Sprintf("parameter %s: %s", u.Parameter, u.Err))
+		}
+	}
+
+	t, err := templates.LoadTemplate(TemplatePath(templatesRoot, s))
+	if err != nil {
+		return append(problems, fmt.Sprintf("template %s could not be loaded: %s", s.TemplatePath, err))
+	}
+
+	return append(problems, t.CheckParameters(supplied, resolved)...)
+}
+
+// TemplatePath returns the path of the template of a stack as deploy resolves it
+func TemplatePath(templatesRoot string, s stack.Stack) string {
+	if filepath.IsAbs(s.TemplatePath) {
+		return s.TemplatePath
+	}
+	return filepath.Join(templatesRoot, s.TemplatePath)
+}
@@ -0,0 +1,227 @@
+package validate
+
+import (
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"github.com/CleverTap/cfstack/internal/pkg/manifest"
+	"github.com/CleverTap/cfstack/internal/pkg/stack"
+	"github.com/CleverTap/cfstack/internal/pkg/templates"
+	"github.com/CleverTap/cfstack/internal/pkg/values"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParameters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cfstack-validate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	template := `{"Parameters": {"Env": {"Type": "String", "AllowedValues": ["dev", "prod"]}, "Password": {"Type": "String", "MinLength": 20}}, "Resources": {}}`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "api.json"), []byte(template), 0644))
+
+	m := &manifest.Manifest{Regions: []manifest.Region{
+		{
+			Name: "eu-west-1",
+			Stacks: []stack.Stack{
+				{StackName: "Api", TemplatePath: "api.json", Parameters: map[string]string{"Env": "prod", "Password": "{{ ssm:/api/password }}"}},
+				{StackName: "Typo", TemplatePath: "api.json", Parameters: map[string]string{"Env": "{{ Missing }}", "Pasword": "x"}},
+				{StackName: "NoTemplate", TemplatePath: "missing.json", Parameters: map[string]string{}},
+				{StackName: "Old", TemplatePath: "missing.json", Action: "DELETE"},
+			},
+		},
+	}}
+
+	v := values.New()
+	require.NoError(t, v.SetOverrides([]string{"eu-west-1/Api.Env=qa"}))
+
+	var messages []string
+	for _, p := range Parameters(m, v, dir) {
+		messages = append(messages, p.String())
+	}
+
+	require.Len(t, messages, 5)
+	require.Equal(t, `eu-west-1/Api: parameter Env: "qa" is not one of the allowed values dev, prod`, messages[0])
+	require.Equal(t, "eu-west-1/Typo: parameter Env: Value Missing not found in values for stack Typo in region eu-west-1", messages[1])
+	require.Equal(t, "eu-west-1/Typo: required parameter Password is missing", messages[2])
+	require.Equal(t, "eu-west-1/Typo: parameter Pasword is not declared in the template", messages[3])
+	require.Contains(t, messages[4], "eu-west-1/NoTemplate: template missing.json could not be loaded")
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cfstack-validate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(name string, body string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, []byte(body), 0644))
+		return path
+	}
+
+	manifestFile := write("manifest.json", `{
+  "Regions": [
+    {
+      "Name": "eu-west-1",
+      "Stacks": [
+        {
+          "StackName": "Api",
+          "Action": "CREAT",
+          "StackPolicy": {
+            "Statement": [
+              { "Effect": "Allow", "Action": "Update:All", "Principal": "*", "Resource": "*" }
+            ]
+          },
+          "Parameters": { "Name": "{{ Name }}" },
+          "TemplatePath": "api.yaml"
+        },
+        {
+          "StackName": "Api",
+          "Action": "CREATE",
+          "TemplatePath": "api.yaml"
+        },
+        {
+          "StackName": "Worker",
+          "Action": "CREATE",
+          "TemplatePath": "missing.json"
+        }
+      ]
+    },
+    {
+      "Name": "eu-west-9",
+      "Stacks": [{ "StackName": "Api", "Action": "DELETE", "TemplatePath": "api.yaml" }]
+    }
+  ]
+}`)
+	write("api.yaml", "Parameters:\n  Name:\n    Type: String\n    MaxLength: 3\nResources:\n  Fn:\n    Type: AWS::Serverless::Function\n    Properties:\n      CodeUri: ./src\n")
+	valuesFile := write("values.json", `{"*": {"*": {"Name": "too-long"}}}`)
+
+	var messages []string
+	for _, p := range Run(&Opts{ManifestFile: manifestFile, ValuesFiles: []string{valuesFile}}) {
+		p.File = filepath.Base(p.File)
+		messages = append(messages, p.String())
+	}
+
+	require.Equal(t, []string{
+		"manifest.json:8: eu-west-1/Api: invalid Action CREAT, must be one of CREATE, UPDATE, DELETE",
+		"manifest.json:11: eu-west-1/Api: stack policy statement 0: invalid Action Update:All, must be one of Update:*, Update:Modify, Update:Replace, Update:Delete",
+		"api.yaml: eu-west-1/Api: code path ./src does not exist",
+		"manifest.json:18: eu-west-1/Api: stack is defined more than once in the region",
+		"manifest.json:25: eu-west-1/Worker: template missing.json does not exist",
+		"manifest.json:30: eu-west-9 is not a valid region",
+		`manifest.json:14: eu-west-1/Api: parameter Name: "too-long" is longer than the maximum length 3`,
+	}, messages)
+
+	t.Run("syntax errors point to their line", func(t *testing.T) {
+		broken := write("broken.json", "{\n  \"Regions\": [\n    {\"Name\": \"eu-west-1\",}\n  ]\n}")
+		problems := Run(&Opts{ManifestFile: broken})
+		require.Len(t, problems, 1)
+		require.Equal(t, 3, problems[0].Line)
+	})
+}
+
+func TestImports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cfstack-validate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	template := `{"Resources": {
+		"Uploads": {"Type": "AWS::S3::Bucket", "DeletionPolicy": "Retain"},
+		"Queue": {"Type": "AWS::SQS::Queue"}
+	}}`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "data.json"), []byte(template), 0644))
+
+	bucket := map[string]string{"BucketName": "acme-uploads"}
+
+	testCases := map[string]struct {
+		imports  []cloudformation.ResourceToImport
+		expected []string
+	}{
+		"valid": {
+			imports: []cloudformation.ResourceToImport{
+				{LogicalResourceId: "Uploads", ResourceType: "AWS::S3::Bucket", ResourceIdentifier: bucket},
+			},
+		},
+		"invalid": {
+			imports: []cloudformation.ResourceToImport{
+				{ResourceType: "AWS::S3::Bucket", ResourceIdentifier: bucket},
+				{LogicalResourceId: "Uploads", ResourceType: "AWS::S3::Bucket"},
+				{LogicalResourceId: "Uploads", ResourceType: "AWS::S3::Bucket", ResourceIdentifier: bucket},
+				{LogicalResourceId: "Queue", ResourceType: "AWS::SNS::Topic", ResourceIdentifier: map[string]string{"QueueUrl": "x"}},
+				{LogicalResourceId: "Missing", ResourceType: "AWS::S3::Bucket", ResourceIdentifier: bucket},
+			},
+			expected: []string{
+				"eu-west-1/Data: resource to import 0 is missing a LogicalResourceId",
+				"eu-west-1/Data: resource to import Uploads has no ResourceIdentifier",
+				"eu-west-1/Data: resource Uploads is imported more than once",
+				`eu-west-1/Data: resource to import Queue has type "AWS::SNS::Topic", the template declares AWS::SQS::Queue`,
+				"eu-west-1/Data: resource to import Queue must have a DeletionPolicy in the template",
+				"eu-west-1/Data: resource to import Missing is not declared in the template",
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			m := &manifest.Manifest{Regions: []manifest.Region{
+				{
+					Name: "eu-west-1",
+					Stacks: []stack.Stack{
+						{StackName: "Data", TemplatePath: "data.json", ResourcesToImport: tc.imports},
+						{StackName: "Old", TemplatePath: "missing.json", Action: "DELETE", ResourcesToImport: tc.imports},
+					},
+				},
+			}}
+
+			var messages []string
+			for _, p := range Imports(m, dir) {
+				messages = append(messages, p.String())
+			}
+			require.Equal(t, tc.expected, messages)
+		})
+	}
+}
+
+func TestLimits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cfstack-validate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	resources := make([]string, 0, 501)
+	for i := 0; i < 501; i++ {
+		resources = append(resources, fmt.Sprintf(`"Queue%d": {"Type": "AWS::SQS::Queue"}`, i))
+	}
+	files := map[string]string{
+		"small.json": `{"Resources": {"Queue": {"Type": "AWS::SQS::Queue"}}}`,
+		"many.json":  `{"Resources": {` + strings.Join(resources, ",") + `}}`,
+		"huge.json":  `{"Description": "` + strings.Repeat("x", templates.MaxTemplateUrlBytes) + `"}`,
+	}
+	for name, body := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(body), 0644))
+	}
+
+	m := &manifest.Manifest{Regions: []manifest.Region{
+		{
+			Name: "eu-west-1",
+			Stacks: []stack.Stack{
+				{StackName: "Small", TemplatePath: "small.json"},
+				{StackName: "Many", TemplatePath: "many.json"},
+				{StackName: "Huge", TemplatePath: "huge.json"},
+				{StackName: "Missing", TemplatePath: "missing.json"},
+				{StackName: "Old", TemplatePath: "many.json", Action: "DELETE"},
+			},
+		},
+	}}
+
+	var messages []string
+	for _, p := range Limits(m, dir) {
+		messages = append(messages, p.String())
+	}
+	require.Equal(t, []string{
+		"eu-west-1/Many: many.json: template declares 501 Resources, CloudFormation allows 500. Move some of them into a nested stack",
+		"eu-west-1/Huge: huge.json: template is 1048594 bytes, over the 1048576 bytes CloudFormation accepts from S3. Move resources into a nested stack",
+	}, messages)
+}
@@ -0,0 +1,105 @@
+package values
+
+import (
+	"github.com/pkg/errors"
+	"os"
+	"strings"
+)
+
+// EnvPrefix marks a placeholder that is resolved from an environment variable
+const EnvPrefix = "env:"
+
+// Override replaces a stack parameter for a single run. An empty Region applies
+// the override to the stack in every region.
+type Override struct {
+	Region    string
+	Stack     string
+	Parameter string
+	Value     string
+}
+
+// ParseOverride parses Stack.Param=value or region/Stack.Param=value
+func ParseOverride(s string) (Override, error) {
+	o := Override{}
+
+	kv := strings.SplitN(s, "=", 2)
+	if len(kv) != 2 {
+		return o, errors.Errorf("invalid parameter override %s, expected [region/]Stack.Param=value", s)
+	}
+	o.Value = kv[1]
+
+	target := kv[0]
+	if i := strings.Index(target, "/"); i >= 0 {
+		o.Region, target = target[:i], target[i+1:]
+	}
+
+	i := strings.LastIndex(target, ".")
+	if i <= 0 || i == len(target)-1 || (o.Region == "" && strings.Contains(kv[0], "/")) {
+		return o, errors.Errorf("invalid parameter override %s, expected [region/]Stack.Param=value", s)
+	}
+	o.Stack, o.Parameter = target[:i], target[i+1:]
+
+	return o, nil
+}
+
+// SetOverrides parses and stores parameter overrides given on the command line
+func (v *Values) SetOverrides(params []string) error {
+	v.overrides = nil
+	for _, p := range params {
+		o, err := ParseOverride(p)
+		if err != nil {
+			return err
+		}
+		v.overrides = append(v.overrides, o)
+	}
+	return nil
+}
+
+// AllOverrides returns the overrides in the order they were given
+func (v *Values) AllOverrides() []Override {
+	if v == nil {
+		return nil
+	}
+	return v.overrides
+}
+
+// Parameters returns the parameters of a stack with the overrides applied. Overrides
+// for the region win over overrides for the stack in every region.
+func (v *Values) Parameters(region string, stack string, params map[string]string) map[string]string {
+	out := make(map[string]string, len(params))
+	for k, p := range params {
+		out[k] = p
+	}
+
+	if v == nil {
+		return out
+	}
+
+	for _, o := range v.overrides {
+		if o.Stack == stack && o.Region == "" {
+			out[o.Parameter] = o.Value
+		}
+	}
+	for _, o := range v.overrides {
+		if o.Stack == stack && o.Region == region {
+			out[o.Parameter] = o.Value
+		}
+	}
+
+	return out
+}
+
+// LookupEnv resolves an env:NAME reference, unset variables are an error rather than
+// an empty value
+func LookupEnv(ref string) (string, error) {
+	name := strings.TrimPrefix(ref, EnvPrefix)
+	if name == "" {
+		return "", errors.New("env reference is missing the variable name")
+	}
+
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", errors.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
@@ -0,0 +1,140 @@
+package values
+
+import (
+	"github.com/CleverTap/cfstack/internal/pkg/util"
+	"github.com/Jeffail/gabs"
+	"sort"
+)
+
+// Wildcard can be used instead of a region or a stack name in a values file to
+// define defaults shared by all regions or all stacks
+const Wildcard = "*"
+
+const (
+	StackScope       = "stack"
+	StackGlobalScope = "stack in all regions"
+	RegionScope      = "region default"
+	GlobalScope      = "global default"
+)
+
+// File is a single parsed values file
+type File struct {
+	Path string
+	Data *gabs.Container
+}
+
+// Values is a stack of values files where later files override earlier ones
+type Values struct {
+	files     []File
+	overrides []Override
+}
+
+// Source tells where a resolved value came from
+type Source struct {
+	File  string
+	Scope string
+}
+
+type scope struct {
+	name   string
+	region string
+	stack  string
+}
+
+func New(files ...File) *Values {
+	return &Values{files: files}
+}
+
+// Load parses values files in the order they are given
+func Load(paths []string) (*Values, error) {
+	v := &Values{}
+	for _, path := range paths {
+		data, err := util.ParseJsonFile(path)
+		if err != nil {
+			return nil, err
+		}
+		v.files = append(v.files, File{Path: path, Data: data})
+	}
+	return v, nil
+}
+
+func (v *Values) Files() []string {
+	var paths []string
+	if v == nil {
+		return paths
+	}
+	for _, f := range v.files {
+		paths = append(paths, f.Path)
+	}
+	return paths
+}
+
+// Lookup resolves a value for a stack in a region. The most specific scope wins:
+// region and stack, then the stack in all regions, then the region default and finally
+// the global default. Within a scope the last file defining the value wins.
+func (v *Values) Lookup(region string, stack string, name string) (interface{}, Source, bool) {
+	if v == nil {
+		return nil, Source{}, false
+	}
+
+	for _, sc := range scopes(region, stack) {
+		for i := len(v.files) - 1; i >= 0; i-- {
+			f := v.files[i]
+			if f.Data != nil && f.Data.Exists(sc.region, sc.stack, name) {
+				return f.Data.Search(sc.region, sc.stack, name).Data(), Source{File: f.Path, Scope: sc.name}, true
+			}
+		}
+	}
+
+	return nil, Source{}, false
+}
+
+// Names returns the sorted names of every value visible to a stack in a region
+func (v *Values) Names(region string, stack string) []string {
+	var names []string
+	if v == nil {
+		return names
+	}
+
+	seen := map[string]bool{}
+	for _, sc := range scopes(region, stack) {
+		for _, f := range v.files {
+			if f.Data == nil {
+				continue
+			}
+			children, err := f.Data.Search(sc.region, sc.stack).ChildrenMap()
+			if err != nil {
+				continue
+			}
+			for name := range children {
+				if !seen[name] {
+					seen[name] = true
+					names = append(names, name)
+				}
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Resolved returns every value visible to a stack in a region after scopes and files
+// have been merged
+func (v *Values) Resolved(region string, stack string) map[string]interface{} {
+	out := map[string]interface{}{}
+	for _, name := range v.Names(region, stack) {
+		value, _, _ := v.Lookup(region, stack, name)
+		out[name] = value
+	}
+	return out
+}
+
+func scopes(region string, stack string) []scope {
+	return []scope{
+		{name: StackScope, region: region, stack: stack},
+		{name: StackGlobalScope, region: Wildcard, stack: stack},
+		{name: RegionScope, region: region, stack: Wildcard},
+		{name: GlobalScope, region: Wildcard, stack: Wildcard},
+	}
+}
@@ -0,0 +1,167 @@
+package values
+
+import (
+	"github.com/Jeffail/gabs"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	parse := func(path string, body string) File {
+		data, err := gabs.ParseJSON([]byte(body))
+		require.NoError(t, err)
+		return File{Path: path, Data: data}
+	}
+
+	v := New(
+		parse("common.json", `{
+			"*": {"*": {"LogLevel": "info", "Owner": "platform"}, "Api": {"Memory": "512"}},
+			"eu-west-1": {"*": {"LogLevel": "warn"}, "Api": {"Replicas": "2"}}
+		}`),
+		parse("prod.json", `{
+			"*": {"*": {"Owner": "sre"}},
+			"eu-west-1": {"Api": {"Replicas": "4"}}
+		}`),
+	)
+
+	testCases := map[string]struct {
+		region        string
+		name          string
+		expected      interface{}
+		expectedFrom  Source
+		expectMissing bool
+	}{
+		"global default": {
+			region:       "us-east-1",
+			name:         "LogLevel",
+			expected:     "info",
+			expectedFrom: Source{File: "common.json", Scope: GlobalScope},
+		},
+		"region default beats global default": {
+			region:       "eu-west-1",
+			name:         "LogLevel",
+			expected:     "warn",
+			expectedFrom: Source{File: "common.json", Scope: RegionScope},
+		},
+		"stack in all regions": {
+			region:       "us-east-1",
+			name:         "Memory",
+			expected:     "512",
+			expectedFrom: Source{File: "common.json", Scope: StackGlobalScope},
+		},
+		"later file wins within a scope": {
+			region:       "eu-west-1",
+			name:         "Replicas",
+			expected:     "4",
+			expectedFrom: Source{File: "prod.json", Scope: StackScope},
+		},
+		"later file overrides global default": {
+			region:       "eu-west-1",
+			name:         "Owner",
+			expected:     "sre",
+			expectedFrom: Source{File: "prod.json", Scope: GlobalScope},
+		},
+		"missing value": {
+			region:        "eu-west-1",
+			name:          "Missing",
+			expectMissing: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			value, source, found := v.Lookup(tc.region, "Api", tc.name)
+
+			require.Equal(t, !tc.expectMissing, found)
+			require.Equal(t, tc.expected, value)
+			if !tc.expectMissing {
+				require.Equal(t, tc.expectedFrom, source)
+			}
+		})
+	}
+
+	require.Equal(t, []string{"LogLevel", "Memory", "Owner", "Replicas"}, v.Names("eu-west-1", "Api"))
+}
+
+func TestParameters(t *testing.T) {
+	v := New()
+	err := v.SetOverrides([]string{
+		"Api.ImageTag=v2",
+		"eu-west-1/Api.ImageTag=v3",
+		"Api.Url=https://example.com/?a=b",
+	})
+	require.NoError(t, err)
+
+	params := map[string]string{"ImageTag": "{{ env:IMAGE_TAG }}", "Memory": "512"}
+
+	testCases := map[string]struct {
+		region   string
+		stack    string
+		expected map[string]string
+	}{
+		"stack override in every region": {
+			region:   "us-east-1",
+			stack:    "Api",
+			expected: map[string]string{"ImageTag": "v2", "Memory": "512", "Url": "https://example.com/?a=b"},
+		},
+		"region override wins": {
+			region:   "eu-west-1",
+			stack:    "Api",
+			expected: map[string]string{"ImageTag": "v3", "Memory": "512", "Url": "https://example.com/?a=b"},
+		},
+		"other stack is untouched": {
+			region:   "eu-west-1",
+			stack:    "Worker",
+			expected: params,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.expected, v.Parameters(tc.region, tc.stack, params))
+		})
+	}
+
+	require.Equal(t, "{{ env:IMAGE_TAG }}", params["ImageTag"])
+}
+
+func TestParseOverride(t *testing.T) {
+	testCases := map[string]struct {
+		param    string
+		expected Override
+		invalid  bool
+	}{
+		"stack":            {param: "Api.ImageTag=v2", expected: Override{Stack: "Api", Parameter: "ImageTag", Value: "v2"}},
+		"region and stack": {param: "eu-west-1/Api.ImageTag=v2", expected: Override{Region: "eu-west-1", Stack: "Api", Parameter: "ImageTag", Value: "v2"}},
+		"empty value":      {param: "Api.ImageTag=", expected: Override{Stack: "Api", Parameter: "ImageTag"}},
+		"missing value":    {param: "Api.ImageTag", invalid: true},
+		"missing stack":    {param: ".ImageTag=v2", invalid: true},
+		"missing param":    {param: "Api.=v2", invalid: true},
+		"empty region":     {param: "/Api.ImageTag=v2", invalid: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			o, err := ParseOverride(tc.param)
+			if tc.invalid {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, o)
+		})
+	}
+}
+
+func TestLookupEnv(t *testing.T) {
+	os.Setenv("CFSTACK_TEST_IMAGE_TAG", "abc123")
+	defer os.Unsetenv("CFSTACK_TEST_IMAGE_TAG")
+
+	value, err := LookupEnv("env:CFSTACK_TEST_IMAGE_TAG")
+	require.NoError(t, err)
+	require.Equal(t, "abc123", value)
+
+	_, err = LookupEnv("env:CFSTACK_TEST_MISSING")
+	require.EqualError(t, err, "environment variable CFSTACK_TEST_MISSING is not set")
+}
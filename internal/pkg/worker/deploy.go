@@ -2,11 +2,15 @@ package worker
 
 import (
 	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/approval"
 	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
 	"github.com/CleverTap/cfstack/internal/pkg/aws/s3"
 	"github.com/CleverTap/cfstack/internal/pkg/aws/session"
+	"github.com/CleverTap/cfstack/internal/pkg/bootstrap"
+	"github.com/CleverTap/cfstack/internal/pkg/policy"
+	"github.com/CleverTap/cfstack/internal/pkg/secrets"
 	"github.com/CleverTap/cfstack/internal/pkg/stack"
-	"github.com/Jeffail/gabs"
+	"github.com/CleverTap/cfstack/internal/pkg/values"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/fatih/color"
 	"github.com/golang/glog"
@@ -21,6 +25,8 @@ const (
 	rocket = "🚀"
 	check  = "✅"
 	cross  = "❌"
+
+	magnifier = "🔍"
 )
 
 type RegionDeployWorkerJob struct {
@@ -30,9 +36,13 @@ type RegionDeployWorkerJob struct {
 	StackDeployWorkers int
 	Uid                string
 	TemplatesRoot      string
-	Values             *gabs.Container
+	Values             *values.Values
 	Role               string
+	Bootstrap          bootstrap.Config
 	ParallelMode       bool
+	Policies           *policy.Set
+	Approver           *approval.Approver
+	Reviewer           stack.Reviewer
 }
 
 type RegionDeployWorkerResult struct {
@@ -61,6 +71,9 @@ func RegionDeployWorker(id int, wg *sync.WaitGroup, regionWorkerJobs <-chan Regi
 		templateRoot := regionWorkerJob.TemplatesRoot
 		values := regionWorkerJob.Values
 		role := regionWorkerJob.Role
+		policies := regionWorkerJob.Policies
+		approver := regionWorkerJob.Approver
+		reviewer := regionWorkerJob.Reviewer
 		uid := regionWorkerJob.Uid
 		parallelMode := regionWorkerJob.ParallelMode
 
@@ -81,13 +94,25 @@ func RegionDeployWorker(id int, wg *sync.WaitGroup, regionWorkerJobs <-chan Regi
 		uploader := s3.New(sess)
 		deployer := cloudformation.New(sess, values)
 
-		bucket, err := deployer.GetStackResourcePhysicalId("cfstack-Init", "TemplatesS3Bucket")
+		resources, err := regionWorkerJob.Bootstrap.Resolve(deployer)
+		if err != nil {
+			regionWorkerResults <- &RegionDeployWorkerResult{
+				Region: region,
+				Err:    err,
+			}
+			wg.Done()
+			continue
+		}
+		bucket := resources.TemplatesBucket
+
+		account, err := policies.Account(sess)
 		if err != nil {
 			regionWorkerResults <- &RegionDeployWorkerResult{
 				Region: region,
 				Err:    err,
 			}
 			wg.Done()
+			continue
 		}
 
 		stackDeployWorkerJobs := make(chan stackDeployWorkerJob, len(stacks))
@@ -112,14 +137,19 @@ func RegionDeployWorker(id int, wg *sync.WaitGroup, regionWorkerJobs <-chan Regi
 			s.SetRegion(region)
 			s.SetUuid(uid)
 			s.SetBucket(bucket)
+			s.SourceBucket = resources.SourceBucket
 			s.SetDeploymentOrder(i)
 			s.TemplateRootPath = templateRoot
 
 			s.Uploader = uploader
 			s.Deployer = deployer
 
-			s.RoleArn = role
+			s.SetRole(role, resources.RoleArn)
 			s.SuppressMessages = parallelMode
+			s.Policies = policies
+			s.AccountId = account
+			s.Approver = approver
+			s.Reviewer = reviewer
 
 			stackDeployWorkerJobs <- stackDeployWorkerJob{
 				region:       region,
@@ -144,6 +174,7 @@ func RegionDeployWorker(id int, wg *sync.WaitGroup, regionWorkerJobs <-chan Regi
 				s := deployWorkerResult.Stack
 
 				if err != nil {
+					err = secrets.RedactError(err)
 					if parallelMode {
 						fmt.Printf("==> %s  Deployment completed for stack %s in region %s\n%v\n", cross, s.StackName, s.Region, err)
 					} else {
@@ -233,13 +264,13 @@ func stackDeployWorker(i int, waitGroup *sync.WaitGroup, stackDeployWorkerJobs c
 								continue
 							}
 						case "RequestError":
-							glog.Warningf("AWS request error for stack %s -  %s, retrying..", s.StackName, aerr.Message())
+							glog.Warningf("AWS request error for stack %s -  %s, retrying..", s.StackName, secrets.Redact(aerr.Message()))
 							continue
 						case "ChangeSetNotFound":
 							glog.Warningf("Looks like changeset not found for stack %s, retrying..", s.StackName)
 							continue
 						default:
-							glog.Errorf("unhandled AWS error for stack %s\n%s : %s", s.StackName, aerr.Code(), aerr.Message())
+							glog.Errorf("unhandled AWS error for stack %s\n%s : %s", s.StackName, aerr.Code(), secrets.Redact(aerr.Message()))
 						}
 					}
 				}
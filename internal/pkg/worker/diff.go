@@ -5,8 +5,11 @@ import (
 	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
 	"github.com/CleverTap/cfstack/internal/pkg/aws/s3"
 	"github.com/CleverTap/cfstack/internal/pkg/aws/session"
+	"github.com/CleverTap/cfstack/internal/pkg/bootstrap"
+	"github.com/CleverTap/cfstack/internal/pkg/policy"
+	"github.com/CleverTap/cfstack/internal/pkg/secrets"
 	"github.com/CleverTap/cfstack/internal/pkg/stack"
-	"github.com/Jeffail/gabs"
+	"github.com/CleverTap/cfstack/internal/pkg/values"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/fatih/color"
 	"github.com/golang/glog"
@@ -23,6 +26,14 @@ var (
 	MinWorker = 1
 )
 
+// Exit codes returned by diff when --detailed-exitcode is set
+const (
+	DiffExitNoChanges = 0
+	DiffExitError     = 1
+	DiffExitChanges   = 2
+	DiffExitUnknown   = 3
+)
+
 type RegionDiffWorkerJob struct {
 	Region          string
 	Stacks          []stack.Stack
@@ -30,8 +41,10 @@ type RegionDiffWorkerJob struct {
 	StackDiffWorker int
 	Uid             string
 	TemplatesRoot   string
-	Values          *gabs.Container
+	Values          *values.Values
 	Role            string
+	Bootstrap       bootstrap.Config
+	Policies        *policy.Set
 }
 
 type RegionDiffWorkerResult struct {
@@ -55,14 +68,15 @@ type stackDiffWorkerResult struct {
 }
 
 func RegionDiffWorker(id int, wg *sync.WaitGroup, regionWorkerJobs <-chan RegionDiffWorkerJob, regionWorkerResults chan<- *RegionDiffWorkerResult) {
-	var errResult error
 	for regionWorkerJob := range regionWorkerJobs {
+		var errResult error
 		//glog.Infof("region-worker-%d: starting diff for stacks in in region %s\n", id, regionWorkerJob.Region)
 		region := regionWorkerJob.Region
 		profile := regionWorkerJob.Profile
 		templateRoot := regionWorkerJob.TemplatesRoot
 		values := regionWorkerJob.Values
 		role := regionWorkerJob.Role
+		policies := regionWorkerJob.Policies
 
 		stacks := regionWorkerJob.Stacks
 
@@ -80,11 +94,24 @@ func RegionDiffWorker(id int, wg *sync.WaitGroup, regionWorkerJobs <-chan Region
 				Err:    err,
 			}
 			wg.Done()
+			continue
 		}
 		uploader := s3.New(sess)
 		deployer := cloudformation.New(sess, values)
 
-		bucket, err := deployer.GetStackResourcePhysicalId("cfstack-Init", "TemplatesS3Bucket")
+		resources, err := regionWorkerJob.Bootstrap.Resolve(deployer)
+		if err != nil {
+			regionWorkerResults <- &RegionDiffWorkerResult{
+				Region: region,
+				Stacks: out,
+				Err:    err,
+			}
+			wg.Done()
+			continue
+		}
+		bucket := resources.TemplatesBucket
+
+		account, err := policies.Account(sess)
 		if err != nil {
 			regionWorkerResults <- &RegionDiffWorkerResult{
 				Region: region,
@@ -92,6 +119,7 @@ func RegionDiffWorker(id int, wg *sync.WaitGroup, regionWorkerJobs <-chan Region
 				Err:    err,
 			}
 			wg.Done()
+			continue
 		}
 
 		stackDiffWorkerJobs := make(chan stackDiffWorkerJob, len(stacks))
@@ -115,6 +143,7 @@ func RegionDiffWorker(id int, wg *sync.WaitGroup, regionWorkerJobs <-chan Region
 			s.SetRegion(regionWorkerJob.Region)
 			s.SetUuid(regionWorkerJob.Uid)
 			s.SetBucket(bucket)
+			s.SourceBucket = resources.SourceBucket
 			s.SetDeploymentOrder(i)
 			s.TemplateRootPath = templateRoot
 
@@ -128,7 +157,9 @@ func RegionDiffWorker(id int, wg *sync.WaitGroup, regionWorkerJobs <-chan Region
 			s.Uploader = uploader
 			s.Deployer = deployer
 
-			s.RoleArn = role
+			s.SetRole(role, resources.RoleArn)
+			s.Policies = policies
+			s.AccountId = account
 
 			stackDiffWorkerJobs <- stackDiffWorkerJob{
 				region: region,
@@ -161,7 +192,7 @@ func RegionDiffWorker(id int, wg *sync.WaitGroup, regionWorkerJobs <-chan Region
 						switch aerr.Code() {
 						case "ValidationError":
 							if strings.Contains(aerr.Message(), "IN_PROGRESS") {
-								color.New(color.FgYellow).Fprintf(os.Stdout, "    %s\n", aerr.Message())
+								color.New(color.FgYellow).Fprintf(os.Stdout, "    %s\n", secrets.Redact(aerr.Message()))
 								s.Changes.Status = stack.DiffUnknownStatus
 								s.Changes.StatusReason = aerr.Message()
 							} else {
@@ -171,6 +202,8 @@ func RegionDiffWorker(id int, wg *sync.WaitGroup, regionWorkerJobs <-chan Region
 						}
 					}
 
+					s.Changes.StatusReason = secrets.Redact(s.Changes.StatusReason)
+
 					if s.Changes.Status == stack.DiffFailStatus {
 						color.New(color.FgRed).Fprintf(os.Stdout, "    diff failed for stack %s : %v\n", s.StackName, s.Changes.StatusReason)
 						errResult = fmt.Errorf("diff for few stacks in %s region has failed", region)
@@ -180,9 +213,13 @@ func RegionDiffWorker(id int, wg *sync.WaitGroup, regionWorkerJobs <-chan Region
 					continue
 				}
 
+				printViolations(s)
+
 				if s.Changes.StackPolicyChange || s.Changes.ForceStackUpdate || len(s.Changes.Resources) > 0 {
 					s.Changes.Status = stack.DiffSuccessStatus
 					out = append(out, s)
+				} else if len(s.Violations) > 0 {
+					out = append(out, s)
 				}
 			}
 		}
@@ -201,6 +238,53 @@ func RegionDiffWorker(id int, wg *sync.WaitGroup, regionWorkerJobs <-chan Region
 	}
 }
 
+// printViolations lists the policy violations of a stack, waived ones in yellow
+func printViolations(s stack.Stack) {
+	for _, v := range s.Violations {
+		c := color.New(color.FgRed)
+		if v.Waived {
+			c = color.New(color.FgYellow)
+		}
+		c.Fprintf(os.Stdout, "    policy violation in stack %s in region %s: %s\n", s.StackName, s.Region, v)
+	}
+}
+
+// DiffExitCode aggregates the results of all regions into a single exit code.
+// Errors take precedence over unknown diffs, which take precedence over changes.
+func DiffExitCode(results []*RegionDiffWorkerResult) int {
+	var failed, unknown, changed bool
+
+	for _, result := range results {
+		if result.Err != nil {
+			failed = true
+		}
+		for _, s := range result.Stacks {
+			if s.Changes == nil {
+				continue
+			}
+			switch s.Changes.Status {
+			case stack.DiffFailStatus:
+				failed = true
+			case stack.DiffUnknownStatus:
+				unknown = true
+			case stack.DiffSuccessStatus:
+				changed = true
+			}
+		}
+	}
+
+	switch {
+	case failed:
+		return DiffExitError
+	case unknown:
+		return DiffExitUnknown
+	case changed:
+		return DiffExitChanges
+	default:
+		return DiffExitNoChanges
+	}
+}
+
 func stackDiffWorker(id int, waitGroup *sync.WaitGroup, stackDiffWorkerJobs <-chan stackDiffWorkerJob, stackDiffWorkerResults chan<- *stackDiffWorkerResult) {
 	for diffWorkerJob := range stackDiffWorkerJobs {
 		s := diffWorkerJob.stack
@@ -230,13 +314,13 @@ func stackDiffWorker(id int, waitGroup *sync.WaitGroup, stackDiffWorkerJobs <-ch
 								continue
 							}
 						case "RequestError":
-							glog.Warningf("AWS request error for stack %s -  %s, retrying..", s.StackName, aerr.Message())
+							glog.Warningf("AWS request error for stack %s -  %s, retrying..", s.StackName, secrets.Redact(aerr.Message()))
 							continue
 						case "ChangeSetNotFound":
 							glog.Warningf("Looks like changeset not found for stack %s, retrying..", s.StackName)
 							continue
 						default:
-							glog.Errorf("unhandled AWS error for stack %s\n%s : %s", s.StackName, aerr.Code(), aerr.Message())
+							glog.Errorf("unhandled AWS error for stack %s\n%s : %s", s.StackName, aerr.Code(), secrets.Redact(aerr.Message()))
 						}
 					}
 				}
@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"github.com/CleverTap/cfstack/internal/pkg/policy"
+	"github.com/CleverTap/cfstack/internal/pkg/stack"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func diffStack(name string, status string) stack.Stack {
+	return stack.Stack{StackName: name, Changes: &cloudformation.Changes{Status: status}}
+}
+
+func TestDiffExitCode(t *testing.T) {
+	violation := diffStack("Guarded", "")
+	violation.Violations = []policy.Violation{{Rule: "no-public-buckets", Resource: "Bucket", ResourceType: "AWS::S3::Bucket"}}
+
+	testCases := map[string]struct {
+		results  []*RegionDiffWorkerResult
+		expected int
+	}{
+		"no regions": {
+			expected: DiffExitNoChanges,
+		},
+		"no changes": {
+			results: []*RegionDiffWorkerResult{
+				{Region: "eu-west-1", Stacks: []stack.Stack{}},
+				{Region: "us-east-1", Stacks: []stack.Stack{{StackName: "Users"}}},
+			},
+			expected: DiffExitNoChanges,
+		},
+		"only policy violations": {
+			results: []*RegionDiffWorkerResult{
+				{Region: "eu-west-1", Stacks: []stack.Stack{violation}},
+			},
+			expected: DiffExitNoChanges,
+		},
+		"changes": {
+			results: []*RegionDiffWorkerResult{
+				{Region: "eu-west-1", Stacks: []stack.Stack{violation, diffStack("Users", stack.DiffSuccessStatus)}},
+				{Region: "us-east-1"},
+			},
+			expected: DiffExitChanges,
+		},
+		"unknown over changes": {
+			results: []*RegionDiffWorkerResult{
+				{Region: "eu-west-1", Stacks: []stack.Stack{diffStack("Users", stack.DiffSuccessStatus)}},
+				{Region: "us-east-1", Stacks: []stack.Stack{diffStack("Api", stack.DiffUnknownStatus)}},
+			},
+			expected: DiffExitUnknown,
+		},
+		"failed stack over unknown": {
+			results: []*RegionDiffWorkerResult{
+				{Region: "eu-west-1", Stacks: []stack.Stack{diffStack("Users", stack.DiffFailStatus), diffStack("Api", stack.DiffUnknownStatus)}},
+			},
+			expected: DiffExitError,
+		},
+		"region error without stacks": {
+			results: []*RegionDiffWorkerResult{
+				{Region: "eu-west-1", Stacks: []stack.Stack{diffStack("Users", stack.DiffUnknownStatus), diffStack("Api", stack.DiffSuccessStatus)}},
+				{Region: "us-east-1", Err: errors.New("cfstack-Init not found in region us-east-1")},
+			},
+			expected: DiffExitError,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.expected, DiffExitCode(tc.results))
+		})
+	}
+}
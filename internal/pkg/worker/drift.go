@@ -0,0 +1,172 @@
+package worker
+
+import (
+	"fmt"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"github.com/CleverTap/cfstack/internal/pkg/aws/session"
+	"github.com/CleverTap/cfstack/internal/pkg/secrets"
+	"github.com/CleverTap/cfstack/internal/pkg/stack"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/fatih/color"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type RegionDriftWorkerJob struct {
+	Region           string
+	Stacks           []stack.Stack
+	Profile          string
+	StackDriftWorker int
+}
+
+type RegionDriftWorkerResult struct {
+	Region string
+	Stacks []cloudformation.StackDrift
+	Err    error
+}
+
+type stackDriftWorkerResult struct {
+	order int
+	drift *cloudformation.StackDrift
+	err   error
+}
+
+// RegionDriftWorker detects drift of the stacks of a region, stacks that don't exist and
+// stacks the manifest deletes are skipped
+func RegionDriftWorker(id int, wg *sync.WaitGroup, regionWorkerJobs <-chan RegionDriftWorkerJob, regionWorkerResults chan<- *RegionDriftWorkerResult) {
+	for regionWorkerJob := range regionWorkerJobs {
+		region := regionWorkerJob.Region
+
+		sess, err := session.NewSession(&session.Opts{
+			Profile: regionWorkerJob.Profile,
+			Region:  region,
+		})
+		if err != nil {
+			regionWorkerResults <- &RegionDriftWorkerResult{Region: region, Err: err}
+			wg.Done()
+			continue
+		}
+		deployer := cloudformation.NewWithoutValues(sess)
+
+		var stacks []stack.Stack
+		for _, s := range regionWorkerJob.Stacks {
+			if s.Action != "DELETE" {
+				stacks = append(stacks, s)
+			}
+		}
+
+		jobs := make(chan int, len(stacks))
+		results := make(chan stackDriftWorkerResult, len(stacks))
+
+		workers := regionWorkerJob.StackDriftWorker
+		if len(stacks) < workers {
+			workers = len(stacks)
+		}
+
+		stackWaitGroup := sync.WaitGroup{}
+		for i := 1; i <= workers; i++ {
+			stackWaitGroup.Add(1)
+			go func() {
+				defer stackWaitGroup.Done()
+				for order := range jobs {
+					drift, err := detectStackDrift(deployer, stacks[order].StackName)
+					results <- stackDriftWorkerResult{order: order, drift: drift, err: err}
+				}
+			}()
+		}
+
+		for i, s := range stacks {
+			fmt.Printf("==> %s  Detecting drift of stack %s in region %s\n", magnifier, s.StackName, region)
+			jobs <- i
+		}
+		close(jobs)
+		stackWaitGroup.Wait()
+		close(results)
+
+		var collected []stackDriftWorkerResult
+		var errStacks []string
+		for r := range results {
+			if r.err != nil {
+				color.New(color.FgRed).Fprintf(os.Stdout, "    drift detection failed for stack %s : %v\n", stacks[r.order].StackName, secrets.RedactError(r.err))
+				errStacks = append(errStacks, stacks[r.order].StackName)
+				continue
+			}
+			if r.drift != nil {
+				collected = append(collected, r)
+			}
+		}
+
+		sort.Slice(collected, func(i, j int) bool {
+			return collected[i].order < collected[j].order
+		})
+		out := make([]cloudformation.StackDrift, 0, len(collected))
+		for _, r := range collected {
+			out = append(out, *r.drift)
+		}
+
+		var errResult error
+		if len(errStacks) > 0 {
+			sort.Strings(errStacks)
+			errResult = errors.Errorf("Drift detection failed for stack(s) %s in region %s", strings.Join(errStacks, ", "), region)
+		}
+
+		regionWorkerResults <- &RegionDriftWorkerResult{
+			Region: region,
+			Stacks: out,
+			Err:    errResult,
+		}
+		wg.Done()
+	}
+}
+
+// detectStackDrift retries on throttling, a nil drift means the stack doesn't exist
+func detectStackDrift(deployer cloudformation.CloudFormation, stackName string) (*cloudformation.StackDrift, error) {
+	stackExists, err := deployer.StackExists(stackName)
+	if err != nil || !stackExists {
+		return nil, err
+	}
+
+	timeout := time.After(1 * time.Hour)
+	ticker := time.Tick(5 * time.Second)
+
+	for {
+		select {
+		case <-timeout:
+			return nil, errors.New("too many AWS API calls. Try again later")
+		case <-ticker:
+			drift, err := deployer.DetectStackDrift(stackName)
+			if err != nil {
+				if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "Throttling" {
+					glog.Warningf("AWS rate limit error for stack %s, retrying..", stackName)
+					continue
+				}
+				return nil, err
+			}
+			return drift, nil
+		}
+	}
+}
+
+// DriftExitCode is DiffExitChanges when a stack drifted, DiffExitError when a region failed
+func DriftExitCode(results []*RegionDriftWorkerResult) int {
+	drifted := false
+	for _, result := range results {
+		if result.Err != nil {
+			return DiffExitError
+		}
+		for _, s := range result.Stacks {
+			if s.Status == cloudformation.DriftStatusDrifted {
+				drifted = true
+			}
+		}
+	}
+	if drifted {
+		return DiffExitChanges
+	}
+	return DiffExitNoChanges
+}
@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"github.com/CleverTap/cfstack/internal/pkg/aws/cloudformation"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDriftExitCode(t *testing.T) {
+	inSync := cloudformation.StackDrift{StackName: "Users", Status: cloudformation.DriftStatusInSync}
+	drifted := cloudformation.StackDrift{StackName: "Api", Status: cloudformation.DriftStatusDrifted}
+
+	testCases := map[string]struct {
+		results  []*RegionDriftWorkerResult
+		expected int
+	}{
+		"no regions": {
+			expected: DiffExitNoChanges,
+		},
+		"in sync": {
+			results: []*RegionDriftWorkerResult{
+				{Region: "eu-west-1", Stacks: []cloudformation.StackDrift{inSync}},
+				{Region: "us-east-1"},
+			},
+			expected: DiffExitNoChanges,
+		},
+		"drifted": {
+			results: []*RegionDriftWorkerResult{
+				{Region: "eu-west-1", Stacks: []cloudformation.StackDrift{inSync}},
+				{Region: "us-east-1", Stacks: []cloudformation.StackDrift{drifted}},
+			},
+			expected: DiffExitChanges,
+		},
+		"region error over drift": {
+			results: []*RegionDriftWorkerResult{
+				{Region: "eu-west-1", Stacks: []cloudformation.StackDrift{drifted}},
+				{Region: "us-east-1", Err: errors.New("drift detection failed for stack Users")},
+			},
+			expected: DiffExitError,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.expected, DriftExitCode(tc.results))
+		})
+	}
+}